@@ -0,0 +1,111 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/format"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+)
+
+type printAction struct {
+	writer io.Writer
+}
+
+// NewPrintAction returns an action which prints a line describing each alert to the given writer.
+func NewPrintAction(w io.Writer) Action {
+	return &printAction{writer: w}
+}
+
+func (pa *printAction) Fire(_ context.Context, alert Alert) error {
+	_, err := fmt.Fprintf(pa.writer, "%s rule %s matched %d time(s): %s\n",
+		format.Bold(format.Red("ALERT")), format.Bold(alert.Rule), alert.Matches, alert.Record.Message)
+	return err
+}
+
+const (
+	// How long a command may run before it is killed.
+	defaultExecTimeout = 30 * time.Second
+
+	// How long to wait for the output of a killed command, which its own children may hold open.
+	execWaitDelay = time.Second
+)
+
+type execAction struct {
+	command string
+	timeout time.Duration
+}
+
+// NewExecAction returns an action which runs the given command line using the platform shell. The alert is
+// passed to the command as JSON on standard input and is also described by SIL_* environment variables. The
+// command is killed if it runs for longer than 30 seconds or the action is cancelled.
+func NewExecAction(command string) Action {
+	return &execAction{command: command, timeout: defaultExecTimeout}
+}
+
+func (ea *execAction) Fire(ctx context.Context, alert Alert) error {
+	input, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, ea.timeout)
+	defer cancel()
+	cmd := shellCommand(ctx, ea.command)
+	cmd.Stdin = bytes.NewReader(input)
+	cmd.Env = append(os.Environ(), alertEnvironment(alert)...)
+	cmd.WaitDelay = execWaitDelay
+	output, err := cmd.CombinedOutput()
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("timed out after %s", ea.timeout)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %s", err, bytes.TrimSpace(output))
+	}
+	return nil
+}
+
+func shellCommand(ctx context.Context, command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.CommandContext(ctx, "cmd", "/C", command)
+	}
+	return exec.CommandContext(ctx, "/bin/sh", "-c", command)
+}
+
+func alertEnvironment(alert Alert) []string {
+	return []string{
+		"SIL_RULE=" + alert.Rule,
+		"SIL_PATTERN=" + alert.Pattern,
+		"SIL_MATCHES=" + strconv.Itoa(alert.Matches),
+		"SIL_TIMESTAMP=" + alert.Record.Timestamp.Format(logclient.LogTimestampFormat),
+		"SIL_SOURCE_TYPE=" + alert.Record.SourceType,
+		"SIL_SOURCE_INSTANCE=" + alert.Record.SourceInstance,
+		"SIL_MESSAGE_TYPE=" + alert.Record.MessageType,
+		"SIL_MESSAGE=" + alert.Record.Message,
+	}
+}
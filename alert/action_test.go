@@ -0,0 +1,121 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package alert_test
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/alert"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+)
+
+var _ = Describe("Actions", func() {
+	var testAlert alert.Alert
+
+	BeforeEach(func() {
+		testAlert = alert.Alert{
+			Rule:    "disk",
+			Pattern: "No space",
+			Matches: 5,
+			Record: logclient.LogRecord{
+				Timestamp:      time.Now(),
+				SourceType:     "ST",
+				SourceInstance: "SI",
+				MessageType:    "ERR",
+				Message:        "No space left on device",
+			},
+		}
+	})
+
+	Describe("PrintAction", func() {
+		It("should describe the alert", func() {
+			output := gbytes.NewBuffer()
+			Expect(alert.NewPrintAction(output).Fire(context.Background(), testAlert)).To(Succeed())
+			Expect(output).To(gbytes.Say("ALERT"))
+			Expect(output).To(gbytes.Say("disk"))
+			Expect(output).To(gbytes.Say("matched 5 time\\(s\\): No space left on device"))
+		})
+	})
+
+	Describe("ExecAction", func() {
+		var dir string
+
+		BeforeEach(func() {
+			if runtime.GOOS == "windows" {
+				Skip("shell commands in this test assume a POSIX shell")
+			}
+			dir = GinkgoT().TempDir()
+		})
+
+		It("should pass the alert as JSON on standard input", func() {
+			path := filepath.Join(dir, "alert.json")
+			Expect(alert.NewExecAction("cat > "+path).Fire(context.Background(), testAlert)).To(Succeed())
+
+			contents, err := os.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			var received alert.Alert
+			Expect(json.Unmarshal(contents, &received)).To(Succeed())
+			Expect(received.Rule).To(Equal("disk"))
+			Expect(received.Matches).To(Equal(5))
+			Expect(received.Record.Message).To(Equal("No space left on device"))
+		})
+
+		It("should describe the alert in environment variables", func() {
+			path := filepath.Join(dir, "env.txt")
+			Expect(alert.NewExecAction("echo \"$SIL_RULE $SIL_MATCHES $SIL_SOURCE_INSTANCE $SIL_MESSAGE\" > "+path).Fire(context.Background(), testAlert)).To(Succeed())
+
+			contents, err := os.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal("disk 5 SI No space left on device\n"))
+		})
+
+		Context("when the command fails", func() {
+			It("should return an error including its output", func() {
+				err := alert.NewExecAction("echo oops; exit 3").Fire(context.Background(), testAlert)
+				Expect(err).To(MatchError("exit status 3: oops"))
+			})
+		})
+
+		Context("when the command runs for too long", func() {
+			It("should kill it and return a suitable error", func() {
+				action := alert.NewExecAction("sleep 10")
+				alert.SetExecTimeout(action, 50*time.Millisecond)
+				start := time.Now()
+				Expect(action.Fire(context.Background(), testAlert)).To(MatchError("timed out after 50ms"))
+				Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+			})
+		})
+
+		Context("when the action is cancelled", func() {
+			It("should kill the command", func() {
+				ctx, cancel := context.WithCancel(context.Background())
+				time.AfterFunc(50*time.Millisecond, cancel)
+				start := time.Now()
+				Expect(alert.NewExecAction("sleep 10").Fire(ctx, testAlert)).NotTo(Succeed())
+				Expect(time.Since(start)).To(BeNumerically("<", 5*time.Second))
+			})
+		})
+	})
+})
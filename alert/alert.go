@@ -0,0 +1,234 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package alert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/sink"
+)
+
+// Alert is raised when a rule fires. Record is the log record which caused the rule to fire.
+type Alert struct {
+	Rule    string              `json:"rule"`
+	Pattern string              `json:"pattern"`
+	Matches int                 `json:"matches"`
+	Record  logclient.LogRecord `json:"record"`
+}
+
+// Action is performed each time a rule fires. Fire should return promptly once the context is cancelled.
+type Action interface {
+	Fire(ctx context.Context, alert Alert) error
+}
+
+type ruleState struct {
+	rule      Rule
+	matches   []time.Time
+	lastFired time.Time
+	fired     bool
+}
+
+const (
+	// Number of alerts which may be waiting for the action before further alerts are dropped.
+	alertQueueLength = 64
+
+	// How long Close waits for outstanding actions before cancelling them.
+	defaultCloseTimeout = 5 * time.Second
+)
+
+// errClosed is returned when a record is written to a sink which has been closed.
+var errClosed = errors.New("Alert sink is closed")
+
+type evaluator struct {
+	mutex        sync.Mutex
+	states       []*ruleState
+	closed       bool
+	dropped      int
+	enqueuing    sync.WaitGroup
+	action       Action
+	errWriter    io.Writer
+	closeTimeout time.Duration
+	ctx          context.Context
+	cancel       context.CancelFunc
+	queue        chan Alert
+	done         chan struct{}
+	closeOnce    sync.Once
+}
+
+// NewSink returns a sink which evaluates the given rules against each record written to it and performs the
+// given action whenever a rule fires. Actions are performed in order on a separate goroutine so that a slow
+// action does not hold up the log stream. If the action falls so far behind that alerts cannot be queued, they
+// are dropped. Failures of the action and dropped alerts are reported to errWriter rather than interrupting the
+// log stream. Closing the sink waits briefly for outstanding actions to complete and then cancels them.
+//
+// Thresholds and cooldowns are measured using record timestamps, so rules behave the same way whether records
+// arrive live or in a batch.
+func NewSink(rules []Rule, action Action, errWriter io.Writer) sink.Sink {
+	states := []*ruleState{}
+	for _, rule := range rules {
+		states = append(states, &ruleState{rule: rule})
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	e := &evaluator{
+		states:       states,
+		action:       action,
+		errWriter:    errWriter,
+		closeTimeout: defaultCloseTimeout,
+		ctx:          ctx,
+		cancel:       cancel,
+		queue:        make(chan Alert, alertQueueLength),
+		done:         make(chan struct{}),
+	}
+	go e.performActions()
+	return e
+}
+
+func (e *evaluator) Write(record logclient.LogRecord) error {
+	e.mutex.Lock()
+	if e.closed {
+		e.mutex.Unlock()
+		return errClosed
+	}
+	var alerts []Alert
+	for _, state := range e.states {
+		if matches, fire := state.evaluate(record); fire {
+			alerts = append(alerts, Alert{
+				Rule:    state.rule.Name,
+				Pattern: state.rule.Pattern.String(),
+				Matches: matches,
+				Record:  record,
+			})
+		}
+	}
+	// Close waits for the alerts to be enqueued, so that the queue is not closed beneath them.
+	e.enqueuing.Add(1)
+	e.mutex.Unlock()
+
+	defer e.enqueuing.Done()
+	for _, alert := range alerts {
+		select {
+		case e.queue <- alert:
+		default:
+			e.drop()
+		}
+	}
+	return nil
+}
+
+func (e *evaluator) Flush() error {
+	return nil
+}
+
+func (e *evaluator) Close() error {
+	e.mutex.Lock()
+	e.closed = true
+	e.mutex.Unlock()
+
+	e.closeOnce.Do(func() {
+		e.enqueuing.Wait()
+		close(e.queue)
+		select {
+		case <-e.done:
+		case <-time.After(e.closeTimeout):
+			e.cancel()
+			<-e.done
+		}
+		e.cancel()
+
+		if dropped := e.droppedCount(); dropped > 0 {
+			fmt.Fprintf(e.errWriter, "Dropped %d alert(s) because the alert action did not keep up\n", dropped)
+		}
+	})
+	<-e.done
+	return nil
+}
+
+func (e *evaluator) performActions() {
+	defer close(e.done)
+	for alert := range e.queue {
+		if e.ctx.Err() != nil {
+			e.drop()
+			continue
+		}
+		err := e.action.Fire(e.ctx, alert)
+		if e.ctx.Err() != nil {
+			e.drop()
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(e.errWriter, "Alert action for rule %q failed: %s\n", alert.Rule, err)
+		}
+	}
+}
+
+// drop counts an alert which was not performed, warning when the first alert is dropped while the sink is open.
+func (e *evaluator) drop() {
+	e.mutex.Lock()
+	e.dropped++
+	warn := e.dropped == 1 && !e.closed
+	e.mutex.Unlock()
+
+	if warn {
+		fmt.Fprintf(e.errWriter, "Alert action is not keeping up, so alerts are being dropped\n")
+	}
+}
+
+func (e *evaluator) droppedCount() int {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+	return e.dropped
+}
+
+// evaluate records a match of the rule, if any, and reports the number of matches in the current window and
+// whether the rule should fire.
+func (s *ruleState) evaluate(record logclient.LogRecord) (int, bool) {
+	if !s.rule.Pattern.MatchString(record.Message) {
+		return 0, false
+	}
+
+	now := record.Timestamp
+	if s.rule.Window > 0 {
+		cutoff := now.Add(-s.rule.Window)
+		live := s.matches[:0]
+		for _, t := range s.matches {
+			if t.After(cutoff) {
+				live = append(live, t)
+			}
+		}
+		s.matches = live
+	}
+	s.matches = append(s.matches, now)
+
+	if len(s.matches) < s.rule.Count {
+		return len(s.matches), false
+	}
+	if s.fired && now.Sub(s.lastFired) < s.rule.Cooldown {
+		return len(s.matches), false
+	}
+
+	matches := len(s.matches)
+	s.matches = nil
+	s.fired = true
+	s.lastFired = now
+	return matches, true
+}
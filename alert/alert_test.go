@@ -0,0 +1,212 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package alert_test
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/alert"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/sink"
+)
+
+type recordingAction struct {
+	mutex  sync.Mutex
+	alerts []alert.Alert
+	err    error
+}
+
+func (ra *recordingAction) Fire(_ context.Context, a alert.Alert) error {
+	ra.mutex.Lock()
+	defer ra.mutex.Unlock()
+	ra.alerts = append(ra.alerts, a)
+	return ra.err
+}
+
+// blockingAction waits to be released, or cancelled, before recording each alert.
+type blockingAction struct {
+	recordingAction
+	started chan struct{}
+	release chan struct{}
+}
+
+func (ba *blockingAction) Fire(ctx context.Context, a alert.Alert) error {
+	ba.started <- struct{}{}
+	select {
+	case <-ba.release:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	return ba.recordingAction.Fire(ctx, a)
+}
+
+var _ = Describe("Alert sink", func() {
+	var (
+		rule      alert.Rule
+		action    *recordingAction
+		errOutput *gbytes.Buffer
+		s         sink.Sink
+		start     time.Time
+	)
+
+	write := func(offset time.Duration, message string) {
+		Expect(s.Write(logclient.LogRecord{Timestamp: start.Add(offset), Message: message})).To(Succeed())
+	}
+
+	fired := func() []alert.Alert {
+		Expect(s.Close()).To(Succeed())
+		return action.alerts
+	}
+
+	BeforeEach(func() {
+		rule = alert.Rule{Name: "broken", Pattern: regexp.MustCompile("replication broken"), Count: 1}
+		action = &recordingAction{}
+		errOutput = gbytes.NewBuffer()
+		start = time.Now()
+	})
+
+	JustBeforeEach(func() {
+		s = alert.NewSink([]alert.Rule{rule}, action, errOutput)
+	})
+
+	It("should fire on a matching record", func() {
+		write(0, "all is well")
+		write(time.Second, "replication broken on node 2")
+
+		alerts := fired()
+		Expect(alerts).To(HaveLen(1))
+		Expect(alerts[0].Rule).To(Equal("broken"))
+		Expect(alerts[0].Pattern).To(Equal("replication broken"))
+		Expect(alerts[0].Matches).To(Equal(1))
+		Expect(alerts[0].Record.Message).To(Equal("replication broken on node 2"))
+	})
+
+	Context("when the rule has a threshold", func() {
+		BeforeEach(func() {
+			rule.Count = 3
+			rule.Window = time.Minute
+		})
+
+		It("should fire only when enough matches arrive within the window", func() {
+			write(0, "replication broken")
+			write(10*time.Second, "replication broken")
+			write(65*time.Second, "replication broken") // first match has left the window
+			write(69*time.Second, "replication broken")
+
+			alerts := fired()
+			Expect(alerts).To(HaveLen(1))
+			Expect(alerts[0].Matches).To(Equal(3))
+			Expect(alerts[0].Record.Timestamp).To(Equal(start.Add(69 * time.Second)))
+		})
+	})
+
+	Context("when the rule has a cooldown", func() {
+		BeforeEach(func() {
+			rule.Cooldown = time.Minute
+		})
+
+		It("should not fire again until the cooldown has elapsed", func() {
+			write(0, "replication broken")
+			write(30*time.Second, "replication broken")
+			write(61*time.Second, "replication broken")
+
+			alerts := fired()
+			Expect(alerts).To(HaveLen(2))
+			Expect(alerts[1].Record.Timestamp).To(Equal(start.Add(61 * time.Second)))
+		})
+	})
+
+	Context("when the action fails", func() {
+		BeforeEach(func() {
+			action.err = errors.New("no dice")
+		})
+
+		It("should report the failure without failing the write", func() {
+			write(0, "replication broken")
+			Expect(fired()).To(HaveLen(1))
+			Expect(errOutput).To(gbytes.Say(`Alert action for rule "broken" failed: no dice`))
+		})
+	})
+
+	Context("when the sink has been closed", func() {
+		It("should fail the write without firing", func() {
+			Expect(s.Close()).To(Succeed())
+			Expect(s.Write(logclient.LogRecord{Timestamp: start, Message: "replication broken"})).To(MatchError("Alert sink is closed"))
+			Expect(action.alerts).To(BeEmpty())
+		})
+	})
+
+	Context("when the action does not keep up", func() {
+		var blocking *blockingAction
+
+		JustBeforeEach(func() {
+			blocking = &blockingAction{started: make(chan struct{}, 100), release: make(chan struct{})}
+			s = alert.NewSink([]alert.Rule{rule}, blocking, errOutput)
+		})
+
+		It("should drop alerts which cannot be queued without holding up writes", func() {
+			write(0, "replication broken")
+			Eventually(blocking.started).Should(Receive())
+
+			// The action holds the first alert and the queue holds the next 64, so the last 5 are dropped.
+			for i := 1; i < 70; i++ {
+				write(time.Duration(i)*time.Second, "replication broken")
+			}
+			Expect(errOutput).To(gbytes.Say("Alert action is not keeping up, so alerts are being dropped"))
+
+			close(blocking.release)
+			Expect(s.Close()).To(Succeed())
+			Expect(blocking.alerts).To(HaveLen(65))
+			Expect(errOutput).To(gbytes.Say(`Dropped 5 alert\(s\) because the alert action did not keep up`))
+		})
+
+		It("should cancel outstanding actions when closing takes too long", func() {
+			alert.SetCloseTimeout(s, 10*time.Millisecond)
+			write(0, "replication broken")
+			write(time.Second, "replication broken")
+			Eventually(blocking.started).Should(Receive())
+
+			Expect(s.Close()).To(Succeed())
+			Expect(blocking.alerts).To(BeEmpty())
+			Expect(errOutput).To(gbytes.Say(`Dropped 2 alert\(s\) because the alert action did not keep up`))
+		})
+	})
+
+	Context("when the sink is closed while alerts are being written", func() {
+		It("should not fail", func() {
+			written := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(written)
+				for i := 0; i < 1000; i++ {
+					if s.Write(logclient.LogRecord{Timestamp: start.Add(time.Duration(i) * time.Second), Message: "replication broken"}) != nil {
+						return
+					}
+				}
+			}()
+			Expect(s.Close()).To(Succeed())
+			Eventually(written).Should(BeClosed())
+		})
+	})
+})
@@ -0,0 +1,137 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package alert
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rule describes log messages which should raise an alert. A rule fires when at least Count messages matching
+// Pattern arrive within Window and the rule has not fired within the previous Cooldown.
+type Rule struct {
+	Name     string
+	Pattern  *regexp.Regexp
+	Count    int
+	Window   time.Duration
+	Cooldown time.Duration
+}
+
+type rulesFile struct {
+	Rules []ruleStructure `json:"rules"`
+}
+
+type ruleStructure struct {
+	Name      string `json:"name"`
+	Pattern   string `json:"pattern"`
+	Threshold string `json:"threshold"`
+	Cooldown  string `json:"cooldown"`
+}
+
+// LoadRules reads alerting rules from the JSON file at the given path, for example:
+//
+//	{"rules": [{"name": "disk", "pattern": "No space left", "threshold": "5 in 1m", "cooldown": "10m"}]}
+//
+// Threshold and cooldown are optional. Without a threshold, a rule fires on every matching message.
+func LoadRules(path string) ([]Rule, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot read rules file: %s", err)
+	}
+
+	var file rulesFile
+	if err := json.Unmarshal(contents, &file); err != nil {
+		return nil, fmt.Errorf("Rules file %s contained invalid JSON: %s", path, err)
+	}
+
+	rules := []Rule{}
+	for i, rs := range file.Rules {
+		rule, err := parseRule(rs)
+		if err != nil {
+			return nil, fmt.Errorf("Rules file %s contained an invalid rule at index %d: %s", path, i, err)
+		}
+		rules = append(rules, rule)
+	}
+
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("Rules file %s did not contain any rules", path)
+	}
+	return rules, nil
+}
+
+func parseRule(rs ruleStructure) (Rule, error) {
+	if rs.Pattern == "" {
+		return Rule{}, fmt.Errorf("pattern not specified")
+	}
+	pattern, err := regexp.Compile(rs.Pattern)
+	if err != nil {
+		return Rule{}, fmt.Errorf("invalid pattern: %s", err)
+	}
+
+	name := rs.Name
+	if name == "" {
+		name = rs.Pattern
+	}
+
+	rule := Rule{Name: name, Pattern: pattern, Count: 1}
+
+	if rs.Threshold != "" {
+		rule.Count, rule.Window, err = parseThreshold(rs.Threshold)
+		if err != nil {
+			return Rule{}, err
+		}
+	}
+
+	if rs.Cooldown != "" {
+		rule.Cooldown, err = time.ParseDuration(rs.Cooldown)
+		if err != nil || rule.Cooldown < 0 {
+			return Rule{}, fmt.Errorf("invalid cooldown %q", rs.Cooldown)
+		}
+	}
+
+	return rule, nil
+}
+
+// parseThreshold parses a threshold of the form "5 in 1m" or "5 matches in 1m".
+func parseThreshold(threshold string) (int, time.Duration, error) {
+	invalid := fmt.Errorf("invalid threshold %q: expected a form such as \"5 matches in 1m\"", threshold)
+
+	fields := strings.Fields(threshold)
+	if len(fields) == 4 && (fields[1] == "matches" || fields[1] == "match") {
+		fields = append(fields[:1], fields[2:]...)
+	}
+	if len(fields) != 3 || fields[1] != "in" {
+		return 0, 0, invalid
+	}
+
+	count, err := strconv.Atoi(fields[0])
+	if err != nil || count < 1 {
+		return 0, 0, invalid
+	}
+
+	window, err := time.ParseDuration(fields[2])
+	if err != nil || window <= 0 {
+		return 0, 0, invalid
+	}
+
+	return count, window, nil
+}
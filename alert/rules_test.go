@@ -0,0 +1,138 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package alert_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/alert"
+)
+
+var _ = Describe("LoadRules", func() {
+	var (
+		contents string
+		path     string
+		rules    []alert.Rule
+		err      error
+	)
+
+	JustBeforeEach(func() {
+		path = filepath.Join(GinkgoT().TempDir(), "rules.json")
+		Expect(os.WriteFile(path, []byte(contents), 0644)).To(Succeed())
+		rules, err = alert.LoadRules(path)
+	})
+
+	Context("when the rules are valid", func() {
+		BeforeEach(func() {
+			contents = `{"rules": [
+				{"name": "disk", "pattern": "No space left", "threshold": "5 matches in 1m", "cooldown": "10m"},
+				{"pattern": "replication (broken|stopped)", "threshold": "2 in 30s"},
+				{"pattern": "FATAL"}
+			]}`
+		})
+
+		It("should parse them", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(rules).To(HaveLen(3))
+
+			Expect(rules[0].Name).To(Equal("disk"))
+			Expect(rules[0].Pattern.String()).To(Equal("No space left"))
+			Expect(rules[0].Count).To(Equal(5))
+			Expect(rules[0].Window).To(Equal(time.Minute))
+			Expect(rules[0].Cooldown).To(Equal(10 * time.Minute))
+
+			Expect(rules[1].Name).To(Equal("replication (broken|stopped)"))
+			Expect(rules[1].Count).To(Equal(2))
+			Expect(rules[1].Window).To(Equal(30 * time.Second))
+
+			Expect(rules[2].Count).To(Equal(1))
+			Expect(rules[2].Window).To(BeZero())
+			Expect(rules[2].Cooldown).To(BeZero())
+		})
+	})
+
+	Context("when the file is not valid JSON", func() {
+		BeforeEach(func() {
+			contents = "{"
+		})
+
+		It("should return a suitable error", func() {
+			Expect(err).To(MatchError(ContainSubstring("contained invalid JSON")))
+		})
+	})
+
+	Context("when the file contains no rules", func() {
+		BeforeEach(func() {
+			contents = `{"rules": []}`
+		})
+
+		It("should return a suitable error", func() {
+			Expect(err).To(MatchError(ContainSubstring("did not contain any rules")))
+		})
+	})
+
+	Context("when a rule has no pattern", func() {
+		BeforeEach(func() {
+			contents = `{"rules": [{"name": "empty"}]}`
+		})
+
+		It("should return a suitable error", func() {
+			Expect(err).To(MatchError(ContainSubstring("invalid rule at index 0: pattern not specified")))
+		})
+	})
+
+	Context("when a rule has an invalid pattern", func() {
+		BeforeEach(func() {
+			contents = `{"rules": [{"pattern": "("}]}`
+		})
+
+		It("should return a suitable error", func() {
+			Expect(err).To(MatchError(ContainSubstring("invalid pattern")))
+		})
+	})
+
+	Context("when a rule has an invalid threshold", func() {
+		BeforeEach(func() {
+			contents = `{"rules": [{"pattern": "x", "threshold": "lots in 1m"}]}`
+		})
+
+		It("should return a suitable error", func() {
+			Expect(err).To(MatchError(ContainSubstring(`invalid threshold "lots in 1m"`)))
+		})
+	})
+
+	Context("when a rule has an invalid cooldown", func() {
+		BeforeEach(func() {
+			contents = `{"rules": [{"pattern": "x", "cooldown": "soon"}]}`
+		})
+
+		It("should return a suitable error", func() {
+			Expect(err).To(MatchError(ContainSubstring(`invalid cooldown "soon"`)))
+		})
+	})
+
+	Context("when the file does not exist", func() {
+		It("should return a suitable error", func() {
+			_, err := alert.LoadRules(filepath.Join(GinkgoT().TempDir(), "missing.json"))
+			Expect(err).To(MatchError(HavePrefix("Cannot read rules file: ")))
+		})
+	})
+})
@@ -0,0 +1,16 @@
+package alert
+
+import (
+	"time"
+
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/sink"
+)
+
+// Allow timeouts to be shortened, but only in tests (since the name of this file ends in "...test.go").
+func SetCloseTimeout(s sink.Sink, timeout time.Duration) {
+	s.(*evaluator).closeTimeout = timeout
+}
+
+func SetExecTimeout(action Action, timeout time.Duration) {
+	action.(*execAction).timeout = timeout
+}
@@ -0,0 +1,190 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/cfutil"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+)
+
+// DefaultTTL is how long a discovered logs endpoint is trusted before it is discovered again.
+const DefaultTTL = 24 * time.Hour
+
+// Entry records the outcome of discovering a service instance: its GUID, service plan, service offering and the
+// logs endpoint advertised by the offering. Info, once it has been obtained, describes the capabilities of the logs
+// service at that endpoint.
+type Entry struct {
+	Name            string             `json:"name"`
+	Guid            string             `json:"guid"`
+	ServicePlanGuid string             `json:"service_plan_guid"`
+	ServiceGuid     string             `json:"service_guid"`
+	LogsEndpoint    string             `json:"logs_endpoint"`
+	SharedFrom      *cfutil.SharedFrom `json:"shared_from,omitempty"`
+	Info            *logclient.Info    `json:"info,omitempty"`
+	Stored          time.Time          `json:"stored"`
+}
+
+// Cache stores discovery results, keyed by how the service instance was identified, so that later invocations
+// of the plugin can skip the Cloud Controller requests.
+type Cache interface {
+	// Get returns the unexpired entry with the given key, if there is one.
+	Get(key string) (Entry, bool)
+	Put(key string, entry Entry)
+	Delete(key string)
+	// Save persists the cache.
+	Save() error
+}
+
+type fileCache struct {
+	mutex   sync.Mutex
+	path    string
+	api     string
+	ttl     time.Duration
+	now     func() time.Time
+	entries map[string]Entry
+}
+
+type cacheFile struct {
+	Api     string           `json:"api"`
+	Entries map[string]Entry `json:"entries"`
+}
+
+// Open returns a cache of the discovery results for the given Cloud Controller API endpoint, backed by a file in
+// the given directory. A missing or unreadable file results in an empty cache, since the entries can always be
+// discovered again.
+func Open(dir string, apiEndpoint string, ttl time.Duration) Cache {
+	sum := sha256.Sum256([]byte(apiEndpoint))
+	c := &fileCache{
+		path:    filepath.Join(dir, hex.EncodeToString(sum[:8])+".json"),
+		api:     apiEndpoint,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: map[string]Entry{},
+	}
+
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return c
+	}
+	var file cacheFile
+	if json.Unmarshal(data, &file) == nil && file.Api == apiEndpoint && file.Entries != nil {
+		c.entries = file.Entries
+	}
+	return c
+}
+
+func (c *fileCache) Get(key string) (Entry, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	entry, ok := c.entries[key]
+	if !ok || c.now().Sub(entry.Stored) >= c.ttl {
+		return Entry{}, false
+	}
+	return entry, true
+}
+
+func (c *fileCache) Put(key string, entry Entry) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	entry.Stored = c.now()
+	c.entries[key] = entry
+}
+
+func (c *fileCache) Delete(key string) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	delete(c.entries, key)
+}
+
+// Save writes the unexpired entries to a temporary file which then replaces the cache file, so that concurrent
+// invocations never observe a partially written cache.
+func (c *fileCache) Save() error {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for key, entry := range c.entries {
+		if c.now().Sub(entry.Stored) >= c.ttl {
+			delete(c.entries, key)
+		}
+	}
+
+	data, err := json.Marshal(cacheFile{Api: c.api, Entries: c.entries})
+	if err != nil {
+		return fmt.Errorf("Cannot encode cache: %s", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return fmt.Errorf("Cannot create cache directory: %s", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return fmt.Errorf("Cannot write cache: %s", err)
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("Cannot write cache: %s", err)
+	}
+	return nil
+}
+
+type refreshingCache struct {
+	Cache
+}
+
+// Refreshing returns a cache which ignores the existing entries of the given cache but stores new ones in it.
+func Refreshing(c Cache) Cache {
+	return refreshingCache{c}
+}
+
+func (refreshingCache) Get(string) (Entry, bool) {
+	return Entry{}, false
+}
+
+type noCache struct{}
+
+// None returns a cache which stores nothing.
+func None() Cache {
+	return noCache{}
+}
+
+func (noCache) Get(string) (Entry, bool) {
+	return Entry{}, false
+}
+
+func (noCache) Put(string, Entry) {}
+
+func (noCache) Delete(string) {}
+
+func (noCache) Save() error {
+	return nil
+}
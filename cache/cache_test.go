@@ -0,0 +1,153 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cache_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/cache"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/cfutil"
+)
+
+var _ = Describe("Cache", func() {
+	const api = "https://api.example.com"
+
+	var (
+		dir   string
+		now   time.Time
+		entry cache.Entry
+	)
+
+	open := func(apiEndpoint string) cache.Cache {
+		c := cache.Open(dir, apiEndpoint, time.Hour)
+		c.(cache.ClockSetter).SetClock(func() time.Time { return now })
+		return c
+	}
+
+	BeforeEach(func() {
+		dir = filepath.Join(GinkgoT().TempDir(), "cache")
+		now = time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+		entry = cache.Entry{
+			Name:            "my-service",
+			Guid:            "si-guid",
+			ServicePlanGuid: "plan-guid",
+			ServiceGuid:     "service-guid",
+			LogsEndpoint:    "https://logs.example.com/logs/",
+			SharedFrom:      &cfutil.SharedFrom{SpaceGuid: "space-guid", SpaceName: "platform", OrgName: "platform-org"},
+		}
+	})
+
+	It("should start empty when there is no cache file", func() {
+		_, ok := open(api).Get("key")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should return stored entries", func() {
+		c := open(api)
+		c.Put("key", entry)
+		got, ok := c.Get("key")
+		Expect(ok).To(BeTrue())
+		Expect(got.LogsEndpoint).To(Equal(entry.LogsEndpoint))
+		Expect(got.Stored).To(Equal(now))
+	})
+
+	It("should persist entries across invocations", func() {
+		c := open(api)
+		c.Put("key", entry)
+		Expect(c.Save()).To(Succeed())
+
+		got, ok := open(api).Get("key")
+		Expect(ok).To(BeTrue())
+		entry.Stored = now
+		Expect(got).To(Equal(entry))
+	})
+
+	It("should keep the entries of each API separate", func() {
+		c := open(api)
+		c.Put("key", entry)
+		Expect(c.Save()).To(Succeed())
+
+		_, ok := open("https://api.other.example.com").Get("key")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should expire entries after the TTL", func() {
+		c := open(api)
+		c.Put("key", entry)
+		now = now.Add(59 * time.Minute)
+		_, ok := c.Get("key")
+		Expect(ok).To(BeTrue())
+
+		now = now.Add(time.Minute)
+		_, ok = c.Get("key")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should forget deleted entries", func() {
+		c := open(api)
+		c.Put("key", entry)
+		c.Delete("key")
+		Expect(c.Save()).To(Succeed())
+
+		_, ok := open(api).Get("key")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should ignore a corrupt cache file", func() {
+		c := open(api)
+		c.Put("key", entry)
+		Expect(c.Save()).To(Succeed())
+		files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(files).To(HaveLen(1))
+		Expect(os.WriteFile(files[0], []byte("{"), 0600)).To(Succeed())
+
+		_, ok := open(api).Get("key")
+		Expect(ok).To(BeFalse())
+	})
+
+	Describe("Refreshing", func() {
+		It("should ignore existing entries but store new ones", func() {
+			c := open(api)
+			c.Put("key", entry)
+			refreshing := cache.Refreshing(c)
+
+			_, ok := refreshing.Get("key")
+			Expect(ok).To(BeFalse())
+
+			entry.LogsEndpoint = "https://new.example.com/logs/"
+			refreshing.Put("key", entry)
+			got, ok := c.Get("key")
+			Expect(ok).To(BeTrue())
+			Expect(got.LogsEndpoint).To(Equal("https://new.example.com/logs/"))
+		})
+	})
+
+	Describe("None", func() {
+		It("should store nothing", func() {
+			c := cache.None()
+			c.Put("key", entry)
+			_, ok := c.Get("key")
+			Expect(ok).To(BeFalse())
+			Expect(c.Save()).To(Succeed())
+		})
+	})
+})
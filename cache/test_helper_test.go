@@ -0,0 +1,12 @@
+package cache
+
+import "time"
+
+// Allow the cache's clock to be replaced, but only in tests (since the name of this file ends in "...test.go").
+func (c *fileCache) SetClock(now func() time.Time) {
+	c.now = now
+}
+
+type ClockSetter interface {
+	SetClock(now func() time.Time)
+}
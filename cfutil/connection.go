@@ -0,0 +1,30 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cfutil
+
+import "code.cloudfoundry.org/cli/plugin/models"
+
+// Connection is the part of plugin.CliConnection used to look up service instances and to authenticate to their
+// logs endpoints. It allows service instances to be looked up without the cf CLI, in which case there is no
+// targeted org or space.
+type Connection interface {
+	CliCommandWithoutTerminalOutput(args ...string) ([]string, error)
+	AccessToken() (string, error)
+	GetCurrentOrg() (plugin_models.Organization, error)
+	GetCurrentSpace() (plugin_models.Space, error)
+	GetService(name string) (plugin_models.GetService_Model, error)
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cfutil
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ccError is the body of a Cloud Controller V2 error response, which 'cf curl' returns as normal output.
+type ccError struct {
+	Code        int    `json:"code"`
+	Description string `json:"description"`
+	ErrorCode   string `json:"error_code"`
+}
+
+// CCError is an error response from the Cloud Controller.
+type CCError struct {
+	Path        string
+	Code        int
+	Description string
+	ErrorCode   string
+}
+
+func (e *CCError) Error() string {
+	return fmt.Sprintf("%s failed: %s (%s)", e.Path, e.Description, e.ErrorCode)
+}
+
+// notAuthorizedCode is the Cloud Controller error code returned when the user lacks permission for a resource.
+const notAuthorizedCode = 10003
+
+// IsNotAuthorized reports whether the error is returned because the user lacks permission for the resource.
+func (e *CCError) IsNotAuthorized() bool {
+	return e.Code == notAuthorizedCode
+}
+
+// ErrNoContent is returned by Curl when the Cloud Controller responds without a body.
+var ErrNoContent = errors.New("no content")
+
+// Curl issues a GET request for the given Cloud Controller path and decodes the JSON response into result.
+func Curl(cliConnection Connection, path string, result interface{}) error {
+	output, err := cliConnection.CliCommandWithoutTerminalOutput("curl", path)
+	if err != nil {
+		return fmt.Errorf("%s failed: %w", path, err)
+	}
+	body := []byte(strings.Join(output, "\n"))
+	if strings.TrimSpace(string(body)) == "" {
+		return ErrNoContent
+	}
+
+	var ccErr ccError
+	if json.Unmarshal(body, &ccErr) == nil && ccErr.ErrorCode != "" {
+		return &CCError{Path: path, Code: ccErr.Code, Description: ccErr.Description, ErrorCode: ccErr.ErrorCode}
+	}
+
+	if err := json.Unmarshal(body, result); err != nil {
+		return fmt.Errorf("%s returned invalid JSON: %s", path, err)
+	}
+	return nil
+}
+
+// Resource is an entry in a Cloud Controller V2 list response.
+type Resource struct {
+	Metadata struct {
+		Guid string `json:"guid"`
+	} `json:"metadata"`
+	Entity json.RawMessage `json:"entity"`
+}
+
+type resourcePage struct {
+	NextUrl   string     `json:"next_url"`
+	Resources []Resource `json:"resources"`
+}
+
+// CurlResources issues GET requests for every page of the given Cloud Controller V2 list path and returns
+// the resources from all the pages.
+func CurlResources(cliConnection Connection, path string) ([]Resource, error) {
+	resources := []Resource{}
+	for path != "" {
+		var page resourcePage
+		if err := Curl(cliConnection, path, &page); err != nil {
+			return nil, err
+		}
+		resources = append(resources, page.Resources...)
+		path = page.NextUrl
+	}
+	return resources, nil
+}
@@ -0,0 +1,131 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cfutil_test
+
+import (
+	"errors"
+
+	"code.cloudfoundry.org/cli/plugin/pluginfakes"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/cfutil"
+)
+
+var _ = Describe("Curl", func() {
+	var (
+		fakeCliConnection *pluginfakes.FakeCliConnection
+		output            []string
+		curlErr           error
+	)
+
+	BeforeEach(func() {
+		fakeCliConnection = &pluginfakes.FakeCliConnection{}
+		output = []string{`{`, `"name": "some-name"`, `}`}
+		curlErr = nil
+		fakeCliConnection.CliCommandWithoutTerminalOutputStub = func(args ...string) ([]string, error) {
+			return output, curlErr
+		}
+	})
+
+	Describe("Curl", func() {
+		var (
+			result struct {
+				Name string `json:"name"`
+			}
+			err error
+		)
+
+		JustBeforeEach(func() {
+			err = cfutil.Curl(fakeCliConnection, "/v2/things/guid", &result)
+		})
+
+		It("should curl the given path", func() {
+			Expect(fakeCliConnection.CliCommandWithoutTerminalOutputArgsForCall(0)).To(Equal([]string{"curl", "/v2/things/guid"}))
+		})
+
+		It("should decode the response", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.Name).To(Equal("some-name"))
+		})
+
+		Context("when curl fails", func() {
+			BeforeEach(func() {
+				curlErr = errors.New("no dice")
+			})
+
+			It("should return a suitable error", func() {
+				Expect(err).To(MatchError("/v2/things/guid failed: no dice"))
+			})
+		})
+
+		Context("when the response is not valid JSON", func() {
+			BeforeEach(func() {
+				output = []string{`{`}
+			})
+
+			It("should return a suitable error", func() {
+				Expect(err).To(MatchError("/v2/things/guid returned invalid JSON: unexpected end of JSON input"))
+			})
+		})
+
+		Context("when the response has no body", func() {
+			BeforeEach(func() {
+				output = []string{""}
+			})
+
+			It("should return ErrNoContent", func() {
+				Expect(err).To(Equal(cfutil.ErrNoContent))
+			})
+		})
+
+		Context("when the Cloud Controller returns an error", func() {
+			BeforeEach(func() {
+				output = []string{`{"code": 10003, "description": "You are not authorized to perform the requested action", "error_code": "CF-NotAuthorized"}`}
+			})
+
+			It("should return a CCError", func() {
+				var ccErr *cfutil.CCError
+				Expect(errors.As(err, &ccErr)).To(BeTrue())
+				Expect(ccErr.Code).To(Equal(10003))
+				Expect(ccErr.ErrorCode).To(Equal("CF-NotAuthorized"))
+				Expect(ccErr.IsNotAuthorized()).To(BeTrue())
+				Expect(err).To(MatchError("/v2/things/guid failed: You are not authorized to perform the requested action (CF-NotAuthorized)"))
+			})
+		})
+	})
+
+	Describe("CurlResources", func() {
+		It("should follow next_url to collect every page", func() {
+			fakeCliConnection.CliCommandWithoutTerminalOutputStub = func(args ...string) ([]string, error) {
+				switch args[1] {
+				case "/v2/things":
+					return []string{`{"next_url": "/v2/things?page=2", "resources": [{"metadata": {"guid": "one"}, "entity": {"name": "first"}}]}`}, nil
+				case "/v2/things?page=2":
+					return []string{`{"next_url": null, "resources": [{"metadata": {"guid": "two"}, "entity": {"name": "second"}}]}`}, nil
+				}
+				return nil, errors.New("unexpected path " + args[1])
+			}
+
+			resources, err := cfutil.CurlResources(fakeCliConnection, "/v2/things")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resources).To(HaveLen(2))
+			Expect(resources[0].Metadata.Guid).To(Equal("one"))
+			Expect(resources[1].Metadata.Guid).To(Equal("two"))
+			Expect(string(resources[1].Entity)).To(Equal(`{"name": "second"}`))
+		})
+	})
+})
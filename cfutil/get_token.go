@@ -20,11 +20,9 @@ import (
 	"fmt"
 
 	"strings"
-
-	"code.cloudfoundry.org/cli/plugin"
 )
 
-func GetToken(cliConnection plugin.CliConnection) (string, error) {
+func GetToken(cliConnection Connection) (string, error) {
 	output, err := cliConnection.AccessToken()
 	if err != nil {
 		return "", fmt.Errorf("Access token not available: %s", err)
@@ -0,0 +1,374 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cfutil
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// Target identifies the org and space in which to look up a service instance. An empty Org denotes the org
+// targeted with 'cf target'. An empty Space denotes the targeted space, in which case Org must also be empty.
+type Target struct {
+	Org   string
+	Space string
+}
+
+// Selector identifies the service instances whose logs are required: a named service instance in a target, a
+// service instance GUID or the service instances matching a label selector. AllSpaces widens a label selector from
+// the target's space to every space of the target's org, in which case the target need not name a space.
+type Selector struct {
+	Target        Target
+	Name          string
+	Guid          string
+	LabelSelector string
+	AllSpaces     bool
+}
+
+// ServiceInstance identifies a service instance and its service plan. SharedFrom is set if the instance was found
+// in a space other than the one which owns it.
+type ServiceInstance struct {
+	Name            string
+	Guid            string
+	ServicePlanGuid string
+	SharedFrom      *SharedFrom
+}
+
+// SharedFrom identifies the space which owns a shared service instance.
+type SharedFrom struct {
+	SpaceGuid string `json:"space_guid"`
+	SpaceName string `json:"space_name"`
+	OrgName   string `json:"organization_name"`
+}
+
+// NotFoundError reports that an org, space or service instance does not exist or is not visible to the user.
+type NotFoundError struct {
+	Message string
+}
+
+func (e *NotFoundError) Error() string {
+	return e.Message
+}
+
+// ForbiddenError reports that the user is not authorized to access the given space, such as the space which owns a
+// shared service instance.
+type ForbiddenError struct {
+	Message   string
+	OrgName   string
+	SpaceName string
+}
+
+func (e *ForbiddenError) Error() string {
+	return e.Message
+}
+
+func notFound(format string, a ...interface{}) error {
+	return &NotFoundError{Message: fmt.Sprintf(format, a...)}
+}
+
+type serviceInstanceEntity struct {
+	Name            string `json:"name"`
+	ServicePlanGuid string `json:"service_plan_guid"`
+}
+
+// serviceInstancesPage is a page of a Cloud Controller V3 service instances list response.
+type serviceInstancesPage struct {
+	Pagination struct {
+		Next *struct {
+			Href string `json:"href"`
+		} `json:"next"`
+	} `json:"pagination"`
+	Resources []struct {
+		Guid          string `json:"guid"`
+		Name          string `json:"name"`
+		Relationships struct {
+			ServicePlan struct {
+				Data struct {
+					Guid string `json:"guid"`
+				} `json:"data"`
+			} `json:"service_plan"`
+		} `json:"relationships"`
+	} `json:"resources"`
+}
+
+// GetServiceInstances looks up the service instances identified by the given selector.
+func GetServiceInstances(cliConnection Connection, selector Selector) ([]ServiceInstance, error) {
+	switch {
+	case selector.Guid != "":
+		instance, err := GetServiceInstanceByGuid(cliConnection, selector.Guid)
+		if err != nil {
+			return nil, err
+		}
+		return []ServiceInstance{instance}, nil
+	case selector.LabelSelector != "":
+		return FindServiceInstances(cliConnection, selector.Target, selector.LabelSelector, selector.AllSpaces)
+	default:
+		instance, err := GetServiceInstance(cliConnection, selector.Target, selector.Name)
+		if err != nil {
+			return nil, err
+		}
+		return []ServiceInstance{instance}, nil
+	}
+}
+
+// GetServiceInstanceByGuid looks up the service instance with the given GUID, wherever it resides. Sharing is
+// resolved relative to the targeted space, as it is for an instance looked up by name, or not considered if no
+// space is targeted.
+func GetServiceInstanceByGuid(cliConnection Connection, guid string) (ServiceInstance, error) {
+	var entity serviceInstanceEntity
+	err := Curl(cliConnection, "/v2/service_instances/"+url.PathEscape(guid), &struct {
+		Entity *serviceInstanceEntity `json:"entity"`
+	}{&entity})
+	if err != nil {
+		return ServiceInstance{}, err
+	}
+	instance := ServiceInstance{Name: entity.Name, Guid: guid, ServicePlanGuid: entity.ServicePlanGuid}
+
+	// A GUID does not need a target, so a connection unable to provide one still looks up the instance.
+	space, err := cliConnection.GetCurrentSpace()
+	if err != nil {
+		return instance, nil
+	}
+	return resolveSharing(cliConnection, space.Guid, instance)
+}
+
+// FindServiceInstances returns the managed service instances matching the given label selector, such as
+// "team=payments,tier=prod", in the space named by the target or, if none, in the targeted space. If allSpaces is
+// true, the matching instances in every space of the target's org, or of the targeted org, are returned instead.
+func FindServiceInstances(cliConnection Connection, target Target, labelSelector string, allSpaces bool) ([]ServiceInstance, error) {
+	query := url.Values{}
+	query.Set("label_selector", labelSelector)
+	query.Set("type", "managed")
+
+	spaceGuid := ""
+	switch {
+	case allSpaces:
+		orgGuid, _, err := findOrg(cliConnection, target.Org)
+		if err != nil {
+			return nil, err
+		}
+		query.Set("organization_guids", orgGuid)
+	case target.Space != "":
+		var err error
+		spaceGuid, _, err = findSpace(cliConnection, target)
+		if err != nil {
+			return nil, err
+		}
+		query.Set("space_guids", spaceGuid)
+	default:
+		space, err := cliConnection.GetCurrentSpace()
+		if err != nil {
+			return nil, err
+		}
+		if space.Guid == "" {
+			return nil, fmt.Errorf("No space targeted. Use -s SPACE or 'cf target -s SPACE' to target a space, or --all-spaces to search every space of the targeted org.")
+		}
+		spaceGuid = space.Guid
+		query.Set("space_guids", spaceGuid)
+	}
+
+	instances := []ServiceInstance{}
+	path := "/v3/service_instances?" + query.Encode()
+	for path != "" {
+		var page serviceInstancesPage
+		if err := Curl(cliConnection, path, &page); err != nil {
+			return nil, err
+		}
+		for _, r := range page.Resources {
+			instance, err := resolveSharing(cliConnection, spaceGuid, ServiceInstance{Name: r.Name, Guid: r.Guid, ServicePlanGuid: r.Relationships.ServicePlan.Data.Guid})
+			if err != nil {
+				return nil, err
+			}
+			instances = append(instances, instance)
+		}
+
+		path = ""
+		if page.Pagination.Next != nil && page.Pagination.Next.Href != "" {
+			next, err := url.Parse(page.Pagination.Next.Href)
+			if err != nil {
+				return nil, fmt.Errorf("/v3/service_instances returned an invalid next page URL: %s", err)
+			}
+			path = next.RequestURI()
+		}
+	}
+
+	if len(instances) == 0 {
+		return nil, notFound("No service instances match label selector %s", labelSelector)
+	}
+	return instances, nil
+}
+
+// GetServiceInstance looks up the named service instance. If the target names a space, the instance is looked up
+// through the Cloud Controller API in that space and the cf target is left unchanged. Otherwise the instance is
+// looked up in the targeted space.
+func GetServiceInstance(cliConnection Connection, target Target, serviceInstanceName string) (ServiceInstance, error) {
+	instance, spaceGuid, err := findServiceInstance(cliConnection, target, serviceInstanceName)
+	if err != nil {
+		return ServiceInstance{}, err
+	}
+	return resolveSharing(cliConnection, spaceGuid, instance)
+}
+
+// findServiceInstance looks up the named service instance and returns it along with the GUID of the space in
+// which it was looked up.
+func findServiceInstance(cliConnection Connection, target Target, serviceInstanceName string) (ServiceInstance, string, error) {
+	if target.Space == "" {
+		model, err := cliConnection.GetService(serviceInstanceName)
+		if err != nil {
+			// The cf CLI reports a missing service instance only as text.
+			if strings.HasSuffix(err.Error(), "not found") {
+				return ServiceInstance{}, "", &NotFoundError{Message: err.Error()}
+			}
+			return ServiceInstance{}, "", err
+		}
+		space, err := cliConnection.GetCurrentSpace()
+		if err != nil {
+			return ServiceInstance{}, "", err
+		}
+		return ServiceInstance{Name: serviceInstanceName, Guid: model.Guid, ServicePlanGuid: model.ServicePlan.Guid}, space.Guid, nil
+	}
+
+	spaceGuid, orgName, err := findSpace(cliConnection, target)
+	if err != nil {
+		return ServiceInstance{}, "", err
+	}
+
+	var entity serviceInstanceEntity
+	guid, err := findResource(cliConnection, fmt.Sprintf("/v2/spaces/%s/service_instances", spaceGuid), serviceInstanceName, &entity)
+	if err != nil {
+		return ServiceInstance{}, "", err
+	}
+	if guid == "" {
+		return ServiceInstance{}, "", notFound("Service instance %s not found in org %s / space %s", serviceInstanceName, orgName, target.Space)
+	}
+	return ServiceInstance{Name: serviceInstanceName, Guid: guid, ServicePlanGuid: entity.ServicePlanGuid}, spaceGuid, nil
+}
+
+// findSpace returns the GUID of the space named by the given target and the name of its org.
+func findSpace(cliConnection Connection, target Target) (string, string, error) {
+	orgGuid, orgName, err := findOrg(cliConnection, target.Org)
+	if err != nil {
+		return "", "", err
+	}
+
+	spaceGuid, err := findResource(cliConnection, fmt.Sprintf("/v2/organizations/%s/spaces", orgGuid), target.Space, nil)
+	if err != nil {
+		return "", "", err
+	}
+	if spaceGuid == "" {
+		return "", "", notFound("Space %s not found in org %s", target.Space, orgName)
+	}
+	return spaceGuid, orgName, nil
+}
+
+// resolveSharing determines whether the given service instance, found in the space with the given GUID, is shared
+// from another space and, if so, checks that the user has access to the owning space and obtains the instance's
+// service plan from its owning space. Sharing is not considered if the space GUID is empty.
+func resolveSharing(cliConnection Connection, spaceGuid string, instance ServiceInstance) (ServiceInstance, error) {
+	if spaceGuid == "" {
+		return instance, nil
+	}
+
+	var sharedFrom SharedFrom
+	err := Curl(cliConnection, fmt.Sprintf("/v2/service_instances/%s/shared_from", instance.Guid), &sharedFrom)
+	if err == ErrNoContent || isNotFound(err) {
+		// The instance is not shared or the Cloud Controller predates instance sharing.
+		return instance, nil
+	}
+	if err != nil {
+		return ServiceInstance{}, err
+	}
+	if sharedFrom.SpaceGuid == "" || sharedFrom.SpaceGuid == spaceGuid {
+		// The instance is owned by the space in which it was found, although it may be shared with other spaces.
+		return instance, nil
+	}
+	instance.SharedFrom = &sharedFrom
+
+	var space struct{}
+	err = Curl(cliConnection, "/v2/spaces/"+sharedFrom.SpaceGuid, &space)
+	if ccErr, ok := err.(*CCError); ok && ccErr.IsNotAuthorized() {
+		return ServiceInstance{}, &ForbiddenError{
+			Message: fmt.Sprintf("Service instance %s is shared from org %s / space %s and you are not authorized to access that space. Ask a space manager of %s for the SpaceDeveloper role.",
+				instance.Name, sharedFrom.OrgName, sharedFrom.SpaceName, sharedFrom.SpaceName),
+			OrgName:   sharedFrom.OrgName,
+			SpaceName: sharedFrom.SpaceName,
+		}
+	}
+	if err != nil {
+		return ServiceInstance{}, err
+	}
+
+	var entity serviceInstanceEntity
+	path := "/v2/service_instances/" + instance.Guid
+	if err := Curl(cliConnection, path, &struct {
+		Entity *serviceInstanceEntity `json:"entity"`
+	}{&entity}); err != nil {
+		return ServiceInstance{}, err
+	}
+	if entity.ServicePlanGuid != "" {
+		instance.ServicePlanGuid = entity.ServicePlanGuid
+	}
+	return instance, nil
+}
+
+func isNotFound(err error) bool {
+	ccErr, ok := err.(*CCError)
+	return ok && ccErr.ErrorCode == "CF-NotFound"
+}
+
+// findOrg returns the GUID and name of the named org, or of the targeted org if the name is empty.
+func findOrg(cliConnection Connection, orgName string) (string, string, error) {
+	if orgName == "" {
+		org, err := cliConnection.GetCurrentOrg()
+		if err != nil {
+			return "", "", err
+		}
+		if org.Guid == "" {
+			return "", "", fmt.Errorf("No org targeted. Use -o ORG or 'cf target -o ORG' to target an org.")
+		}
+		return org.Guid, org.Name, nil
+	}
+
+	guid, err := findResource(cliConnection, "/v2/organizations", orgName, nil)
+	if err != nil {
+		return "", "", err
+	}
+	if guid == "" {
+		return "", "", notFound("Organization %s not found", orgName)
+	}
+	return guid, orgName, nil
+}
+
+// findResource returns the GUID of the resource with the given name in the given Cloud Controller V2 list, or an
+// empty string if there is no such resource. If entity is not nil, the resource's entity is decoded into it.
+func findResource(cliConnection Connection, path string, name string, entity interface{}) (string, error) {
+	resources, err := CurlResources(cliConnection, path+"?q="+url.QueryEscape("name:"+name))
+	if err != nil {
+		return "", err
+	}
+	if len(resources) == 0 {
+		return "", nil
+	}
+	if entity != nil {
+		if err := json.Unmarshal(resources[0].Entity, entity); err != nil {
+			return "", fmt.Errorf("%s returned invalid JSON: %s", path, err)
+		}
+	}
+	return resources[0].Metadata.Guid, nil
+}
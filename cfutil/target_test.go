@@ -0,0 +1,386 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package cfutil_test
+
+import (
+	"errors"
+
+	"code.cloudfoundry.org/cli/plugin/models"
+	"code.cloudfoundry.org/cli/plugin/pluginfakes"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/cfutil"
+)
+
+var _ = Describe("GetServiceInstance", func() {
+	var (
+		fakeCliConnection *pluginfakes.FakeCliConnection
+		responses         map[string]string
+		target            cfutil.Target
+		instance          cfutil.ServiceInstance
+		err               error
+	)
+
+	BeforeEach(func() {
+		fakeCliConnection = &pluginfakes.FakeCliConnection{}
+		fakeCliConnection.GetServiceReturns(plugin_models.GetService_Model{Guid: "targeted-si-guid", ServicePlan: plugin_models.GetService_ServicePlan{Guid: "targeted-plan-guid"}}, nil)
+		fakeCliConnection.GetCurrentSpaceReturns(plugin_models.Space{SpaceFields: plugin_models.SpaceFields{Guid: "current-space-guid", Name: "current-space"}}, nil)
+		fakeCliConnection.GetCurrentOrgReturns(plugin_models.Organization{OrganizationFields: plugin_models.OrganizationFields{Guid: "current-org-guid", Name: "current-org"}}, nil)
+		target = cfutil.Target{}
+
+		responses = map[string]string{
+			"/v2/organizations?q=name%3Aother-org":                          `{"resources": [{"metadata": {"guid": "other-org-guid"}, "entity": {"name": "other-org"}}]}`,
+			"/v2/organizations?q=name%3Amissing-org":                        `{"resources": []}`,
+			"/v2/organizations/other-org-guid/spaces?q=name%3Aprod":         `{"resources": [{"metadata": {"guid": "prod-guid"}, "entity": {"name": "prod"}}]}`,
+			"/v2/organizations/current-org-guid/spaces?q=name%3Astaging":    `{"resources": [{"metadata": {"guid": "staging-guid"}, "entity": {"name": "staging"}}]}`,
+			"/v2/organizations/other-org-guid/spaces?q=name%3Amissing":      `{"resources": []}`,
+			"/v2/spaces/prod-guid/service_instances?q=name%3Amy-service":    `{"resources": [{"metadata": {"guid": "prod-si-guid"}, "entity": {"name": "my-service", "service_plan_guid": "prod-plan-guid"}}]}`,
+			"/v2/spaces/staging-guid/service_instances?q=name%3Amy-service": `{"resources": [{"metadata": {"guid": "staging-si-guid"}, "entity": {"name": "my-service", "service_plan_guid": "staging-plan-guid"}}]}`,
+		}
+		fakeCliConnection.CliCommandWithoutTerminalOutputStub = func(args ...string) ([]string, error) {
+			if response, ok := responses[args[1]]; ok {
+				return []string{response}, nil
+			}
+			return []string{`{"resources": []}`}, nil
+		}
+	})
+
+	JustBeforeEach(func() {
+		instance, err = cfutil.GetServiceInstance(fakeCliConnection, target, "my-service")
+	})
+
+	Context("when no space is given", func() {
+		It("should look up the instance in the targeted space", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(instance).To(Equal(cfutil.ServiceInstance{Name: "my-service", Guid: "targeted-si-guid", ServicePlanGuid: "targeted-plan-guid"}))
+			Expect(fakeCliConnection.GetServiceArgsForCall(0)).To(Equal("my-service"))
+		})
+
+		Context("when the lookup fails", func() {
+			BeforeEach(func() {
+				fakeCliConnection.GetServiceReturns(plugin_models.GetService_Model{}, errors.New("Service instance my-service not found"))
+			})
+
+			It("should propagate the error", func() {
+				Expect(err).To(BeAssignableToTypeOf(&cfutil.NotFoundError{}))
+				Expect(err).To(MatchError("Service instance my-service not found"))
+			})
+		})
+	})
+
+	Context("when an org and space are given", func() {
+		BeforeEach(func() {
+			target = cfutil.Target{Org: "other-org", Space: "prod"}
+		})
+
+		It("should look up the instance in that space without changing the target", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(instance).To(Equal(cfutil.ServiceInstance{Name: "my-service", Guid: "prod-si-guid", ServicePlanGuid: "prod-plan-guid"}))
+			Expect(fakeCliConnection.GetServiceCallCount()).To(Equal(0))
+			Expect(fakeCliConnection.CliCommandCallCount()).To(Equal(0))
+		})
+
+		Context("when the org does not exist", func() {
+			BeforeEach(func() {
+				target.Org = "missing-org"
+			})
+
+			It("should return a suitable error", func() {
+				Expect(err).To(BeAssignableToTypeOf(&cfutil.NotFoundError{}))
+				Expect(err).To(MatchError("Organization missing-org not found"))
+			})
+		})
+
+		Context("when the space does not exist", func() {
+			BeforeEach(func() {
+				target.Space = "missing"
+			})
+
+			It("should return a suitable error", func() {
+				Expect(err).To(BeAssignableToTypeOf(&cfutil.NotFoundError{}))
+				Expect(err).To(MatchError("Space missing not found in org other-org"))
+			})
+		})
+
+		Context("when the instance does not exist in the space", func() {
+			BeforeEach(func() {
+				delete(responses, "/v2/spaces/prod-guid/service_instances?q=name%3Amy-service")
+			})
+
+			It("should return a suitable error", func() {
+				Expect(err).To(BeAssignableToTypeOf(&cfutil.NotFoundError{}))
+				Expect(err).To(MatchError("Service instance my-service not found in org other-org / space prod"))
+			})
+		})
+	})
+
+	Describe("shared service instances", func() {
+		BeforeEach(func() {
+			responses["/v2/service_instances/targeted-si-guid/shared_from"] = `{"space_guid": "owner-space-guid", "space_name": "platform", "organization_name": "platform-org"}`
+			responses["/v2/spaces/owner-space-guid"] = `{"metadata": {"guid": "owner-space-guid"}, "entity": {"name": "platform"}}`
+			responses["/v2/service_instances/targeted-si-guid"] = `{"metadata": {"guid": "targeted-si-guid"}, "entity": {"service_plan_guid": "owner-plan-guid"}}`
+		})
+
+		It("should report the owning space and use the instance's own plan", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(instance).To(Equal(cfutil.ServiceInstance{
+				Name:            "my-service",
+				Guid:            "targeted-si-guid",
+				ServicePlanGuid: "owner-plan-guid",
+				SharedFrom:      &cfutil.SharedFrom{SpaceGuid: "owner-space-guid", SpaceName: "platform", OrgName: "platform-org"},
+			}))
+		})
+
+		Context("when the user is not authorized in the owning space", func() {
+			BeforeEach(func() {
+				responses["/v2/spaces/owner-space-guid"] = `{"code": 10003, "description": "You are not authorized to perform the requested action", "error_code": "CF-NotAuthorized"}`
+			})
+
+			It("should return a suitable error", func() {
+				Expect(err).To(MatchError("Service instance my-service is shared from org platform-org / space platform and you are not authorized to access that space. Ask a space manager of platform for the SpaceDeveloper role."))
+				Expect(err).To(BeAssignableToTypeOf(&cfutil.ForbiddenError{}))
+				Expect(err.(*cfutil.ForbiddenError).OrgName).To(Equal("platform-org"))
+				Expect(err.(*cfutil.ForbiddenError).SpaceName).To(Equal("platform"))
+			})
+		})
+
+		Context("when the instance is not shared", func() {
+			BeforeEach(func() {
+				responses["/v2/service_instances/targeted-si-guid/shared_from"] = ""
+			})
+
+			It("should not report an owning space", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(instance.SharedFrom).To(BeNil())
+				Expect(instance.ServicePlanGuid).To(Equal("targeted-plan-guid"))
+			})
+		})
+
+		Context("when the instance is owned by the space in which it was found", func() {
+			BeforeEach(func() {
+				responses["/v2/service_instances/targeted-si-guid/shared_from"] = `{"space_guid": "current-space-guid", "space_name": "current-space", "organization_name": "current-org"}`
+			})
+
+			It("should not report an owning space", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(instance.SharedFrom).To(BeNil())
+			})
+		})
+
+		Context("when the Cloud Controller does not support instance sharing", func() {
+			BeforeEach(func() {
+				responses["/v2/service_instances/targeted-si-guid/shared_from"] = `{"code": 10000, "description": "Unknown request", "error_code": "CF-NotFound"}`
+			})
+
+			It("should treat the instance as not shared", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(instance.SharedFrom).To(BeNil())
+			})
+		})
+	})
+
+	Context("when only a space is given", func() {
+		BeforeEach(func() {
+			target = cfutil.Target{Space: "staging"}
+		})
+
+		It("should look up the space in the targeted org", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(instance.Guid).To(Equal("staging-si-guid"))
+		})
+
+		Context("when no org is targeted", func() {
+			BeforeEach(func() {
+				fakeCliConnection.GetCurrentOrgReturns(plugin_models.Organization{}, nil)
+			})
+
+			It("should return a suitable error", func() {
+				Expect(err).To(MatchError("No org targeted. Use -o ORG or 'cf target -o ORG' to target an org."))
+			})
+		})
+	})
+})
+
+var _ = Describe("GetServiceInstances", func() {
+	var (
+		fakeCliConnection *pluginfakes.FakeCliConnection
+		responses         map[string]string
+		selector          cfutil.Selector
+		result            []cfutil.ServiceInstance
+		err               error
+	)
+
+	BeforeEach(func() {
+		fakeCliConnection = &pluginfakes.FakeCliConnection{}
+		fakeCliConnection.GetServiceReturns(plugin_models.GetService_Model{Guid: "named-si-guid", ServicePlan: plugin_models.GetService_ServicePlan{Guid: "named-plan-guid"}}, nil)
+		fakeCliConnection.GetCurrentOrgReturns(plugin_models.Organization{OrganizationFields: plugin_models.OrganizationFields{Guid: "current-org-guid", Name: "current-org"}}, nil)
+		fakeCliConnection.GetCurrentSpaceReturns(plugin_models.Space{SpaceFields: plugin_models.SpaceFields{Guid: "current-space-guid", Name: "current-space"}}, nil)
+		selector = cfutil.Selector{Name: "my-service"}
+
+		responses = map[string]string{
+			"/v2/service_instances/some-guid":    `{"metadata": {"guid": "some-guid"}, "entity": {"name": "by-guid", "service_plan_guid": "guid-plan-guid"}}`,
+			"/v2/service_instances/missing-guid": `{"code": 60004, "description": "The service instance could not be found: missing-guid", "error_code": "CF-ServiceInstanceNotFound"}`,
+			"/v3/service_instances?label_selector=team%3Dpayments%2Ctier%3Dprod&space_guids=current-space-guid&type=managed": `{
+				"pagination": {"next": {"href": "https://api.example.com/v3/service_instances?label_selector=team%3Dpayments%2Ctier%3Dprod&page=2&space_guids=current-space-guid&type=managed"}},
+				"resources": [{"guid": "si-1", "name": "payments-db", "relationships": {"service_plan": {"data": {"guid": "plan-1"}}}}]
+			}`,
+			"/v3/service_instances?label_selector=team%3Dpayments%2Ctier%3Dprod&page=2&space_guids=current-space-guid&type=managed": `{
+				"pagination": {"next": null},
+				"resources": [{"guid": "si-2", "name": "payments-cache", "relationships": {"service_plan": {"data": {"guid": "plan-2"}}}}]
+			}`,
+			"/v2/organizations/current-org-guid/spaces?q=name%3Aprod":                                               `{"resources": [{"metadata": {"guid": "prod-guid"}, "entity": {"name": "prod"}}]}`,
+			"/v3/service_instances?label_selector=team%3Dpayments&space_guids=prod-guid&type=managed":               `{"pagination": {}, "resources": [{"guid": "si-3", "name": "prod-db", "relationships": {"service_plan": {"data": {"guid": "plan-3"}}}}]}`,
+			"/v3/service_instances?label_selector=team%3Dnobody&space_guids=current-space-guid&type=managed":        `{"pagination": {}, "resources": []}`,
+			"/v3/service_instances?label_selector=team%3Dpayments&organization_guids=current-org-guid&type=managed": `{"pagination": {}, "resources": [{"guid": "si-4", "name": "staging-db", "relationships": {"service_plan": {"data": {"guid": "plan-4"}}}}]}`,
+		}
+		fakeCliConnection.CliCommandWithoutTerminalOutputStub = func(args ...string) ([]string, error) {
+			if response, ok := responses[args[1]]; ok {
+				return []string{response}, nil
+			}
+			return []string{""}, nil
+		}
+	})
+
+	JustBeforeEach(func() {
+		result, err = cfutil.GetServiceInstances(fakeCliConnection, selector)
+	})
+
+	It("should look up a named instance", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal([]cfutil.ServiceInstance{{Name: "my-service", Guid: "named-si-guid", ServicePlanGuid: "named-plan-guid"}}))
+	})
+
+	Context("when a GUID is given", func() {
+		BeforeEach(func() {
+			selector = cfutil.Selector{Guid: "some-guid"}
+		})
+
+		It("should look up the instance without resolving a name", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal([]cfutil.ServiceInstance{{Name: "by-guid", Guid: "some-guid", ServicePlanGuid: "guid-plan-guid"}}))
+			Expect(fakeCliConnection.GetServiceCallCount()).To(Equal(0))
+		})
+
+		Context("when the instance is shared into the targeted space", func() {
+			BeforeEach(func() {
+				responses["/v2/service_instances/some-guid/shared_from"] = `{"space_guid": "owner-space-guid", "space_name": "platform", "organization_name": "platform-org"}`
+				responses["/v2/spaces/owner-space-guid"] = `{"metadata": {"guid": "owner-space-guid"}, "entity": {"name": "platform"}}`
+			})
+
+			It("should report the owning space", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result).To(Equal([]cfutil.ServiceInstance{{
+					Name:            "by-guid",
+					Guid:            "some-guid",
+					ServicePlanGuid: "guid-plan-guid",
+					SharedFrom:      &cfutil.SharedFrom{SpaceGuid: "owner-space-guid", SpaceName: "platform", OrgName: "platform-org"},
+				}}))
+			})
+
+			Context("when the user is not authorized in the owning space", func() {
+				BeforeEach(func() {
+					responses["/v2/spaces/owner-space-guid"] = `{"code": 10003, "description": "You are not authorized to perform the requested action", "error_code": "CF-NotAuthorized"}`
+				})
+
+				It("should return a suitable error", func() {
+					Expect(err).To(BeAssignableToTypeOf(&cfutil.ForbiddenError{}))
+					Expect(err).To(MatchError(ContainSubstring("Service instance by-guid is shared from org platform-org / space platform")))
+				})
+			})
+		})
+
+		Context("when the instance is owned by the targeted space", func() {
+			BeforeEach(func() {
+				fakeCliConnection.GetCurrentSpaceReturns(plugin_models.Space{SpaceFields: plugin_models.SpaceFields{Guid: "owner-space-guid", Name: "platform"}}, nil)
+				responses["/v2/service_instances/some-guid/shared_from"] = `{"space_guid": "owner-space-guid", "space_name": "platform", "organization_name": "platform-org"}`
+			})
+
+			It("should not report an owning space", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result[0].SharedFrom).To(BeNil())
+			})
+		})
+
+		Context("when the instance does not exist", func() {
+			BeforeEach(func() {
+				selector.Guid = "missing-guid"
+			})
+
+			It("should return the Cloud Controller's error", func() {
+				Expect(err).To(MatchError(ContainSubstring("The service instance could not be found: missing-guid")))
+			})
+		})
+	})
+
+	Context("when a label selector is given", func() {
+		BeforeEach(func() {
+			selector = cfutil.Selector{LabelSelector: "team=payments,tier=prod"}
+		})
+
+		It("should return every matching instance from every page", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(Equal([]cfutil.ServiceInstance{
+				{Name: "payments-db", Guid: "si-1", ServicePlanGuid: "plan-1"},
+				{Name: "payments-cache", Guid: "si-2", ServicePlanGuid: "plan-2"},
+			}))
+		})
+
+		Context("when a space is given", func() {
+			BeforeEach(func() {
+				selector = cfutil.Selector{Target: cfutil.Target{Space: "prod"}, LabelSelector: "team=payments"}
+			})
+
+			It("should only return instances in that space", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result).To(Equal([]cfutil.ServiceInstance{{Name: "prod-db", Guid: "si-3", ServicePlanGuid: "plan-3"}}))
+			})
+		})
+
+		Context("when all spaces are to be searched", func() {
+			BeforeEach(func() {
+				selector = cfutil.Selector{LabelSelector: "team=payments", AllSpaces: true}
+			})
+
+			It("should return matching instances in every space of the targeted org", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result).To(Equal([]cfutil.ServiceInstance{{Name: "staging-db", Guid: "si-4", ServicePlanGuid: "plan-4"}}))
+				Expect(fakeCliConnection.GetCurrentSpaceCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when no space is targeted", func() {
+			BeforeEach(func() {
+				fakeCliConnection.GetCurrentSpaceReturns(plugin_models.Space{}, nil)
+			})
+
+			It("should return a suitable error", func() {
+				Expect(err).To(MatchError("No space targeted. Use -s SPACE or 'cf target -s SPACE' to target a space, or --all-spaces to search every space of the targeted org."))
+			})
+		})
+
+		Context("when no instances match", func() {
+			BeforeEach(func() {
+				selector.LabelSelector = "team=nobody"
+			})
+
+			It("should return a suitable error", func() {
+				Expect(err).To(BeAssignableToTypeOf(&cfutil.NotFoundError{}))
+				Expect(err).To(MatchError("No service instances match label selector team=nobody"))
+			})
+		})
+	})
+})
@@ -9,6 +9,9 @@ readonly VERSION="${VERSION?:Environment variable VERSION must be set}"
 
 readonly PLUGIN_NAME="service-instance-logs-cli-plugin"
 readonly PLUGIN_VERSION="${VERSION#v}"
+PLUGIN_COMMIT="$(git -C "${CF_CLI_PLUGIN_INPUT}" rev-parse HEAD 2>/dev/null || true)"
+BUILD_DATE="$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+readonly PLUGIN_COMMIT BUILD_DATE
 
 export GOFLAGS="-mod=vendor"
 
@@ -17,6 +20,8 @@ function run_tests() {
 
 	go install github.com/onsi/ginkgo/v2/ginkgo
 	ginkgo -r
+	# Run each benchmark a few times so that they are kept working.
+	go test -run '^$' -bench . -benchtime 3x ./integration_test
 
 	popd >/dev/null
 }
@@ -26,7 +31,7 @@ function build() {
 	platform="$1"
 	architecture="$2"
 	binary_name="${PLUGIN_NAME}-${platform}-${architecture}-${PLUGIN_VERSION}"
-	version_flag="-X main.pluginVersion=${PLUGIN_VERSION}"
+	version_flag="-X main.pluginVersion=${PLUGIN_VERSION} -X main.pluginCommit=${PLUGIN_COMMIT} -X main.buildDate=${BUILD_DATE}"
 
 	if [ "${platform}" == "windows" ]; then
 		binary_name="${binary_name}.exe"
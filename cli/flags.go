@@ -16,29 +16,245 @@
  */
 package cli
 
-import "fmt"
-import "code.cloudfoundry.org/cli/cf/flags"
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"code.cloudfoundry.org/cli/cf/flags"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/structured"
+)
 
 const (
 	RecentUsage            = "Dump recent logs instead of tailing"
 	SkipSslValidationUsage = "Skip verification of the logs endpoint. Not recommended!"
+	SinkUsage              = "Send logs to stdout, file:PATH or an http(s) URL which accepts newline delimited JSON. May be repeated to send logs to several destinations (default stdout)"
+	RulesUsage             = "Raise alerts for log messages matching the rules in the given JSON file"
+	OnMatchExecUsage       = "Run the given command when an alert is raised, passing the alert as JSON on standard input. The command is killed after 30 seconds. Requires --rules"
+	OutputUsage            = "Output format: text or json (default text)"
+	TopUsage               = "Number of most frequent messages to show (default 10)"
+	AllSpacesUsage         = "Include service instances in all spaces of the targeted org, not only the targeted space"
+	OrgUsage               = "Look up the service instance in the given org instead of the targeted org. Requires -s"
+	SpaceUsage             = "Look up the service instance in the given space instead of the targeted space. The cf target is not changed"
+	GuidUsage              = "Identify the service instance by its GUID instead of by name"
+	NoCacheUsage           = "Discover the service instance's logs endpoint without using or updating the cache"
+	RefreshUsage           = "Discover the service instance's logs endpoint again and update the cache"
+	NoCapabilityCheckUsage = "Assume the logs service supports every flag instead of obtaining the document describing its capabilities"
+	SelectorUsage          = "Identify the managed service instances by a label selector such as 'team=payments,tier=prod'. Instances in the space given by -s, or in the targeted space, are included. Use --all-spaces to search every space of the targeted org"
+	BufferSizeUsage        = "Number of tailed log messages to buffer when output cannot keep up (default 10000)"
+	OverflowUsage          = "What to do when the buffer is full: block, drop-oldest or drop-newest (default block). Dropped messages are reported in the output"
+	ReorderWindowUsage     = "Hold tailed log messages for the given duration, such as 2s, to print them in timestamp order. Messages arriving later are marked as late"
+	JoinMultilineUsage     = "Join the lines of multi-line log messages, such as Java stack traces, into single messages"
+	ContinuationUsage      = "Regular expression matching lines which continue the previous line from the same source. May be repeated (default indented, 'at ...', 'Caused by:' and '... N more' lines). Requires --join-multiline"
+	JoinTimeoutUsage       = "How long to wait for further lines of a multi-line log message (default 1s). Requires --join-multiline"
+	FieldUsage             = "Only show JSON log messages whose field matches: NAME=VALUE, NAME!=VALUE or NAME~=REGEX. Nested fields are named like error.code. May be repeated"
+	ColumnUsage            = "Show the given field of JSON log messages in a column before the message. May be repeated"
+	JSONPrettyUsage        = "Pretty-print JSON log messages"
+	FormatUsage            = "Format log messages with a Go template such as '{{.Time | utc}} {{.SourceInstance}} {{.Message}}', or the name of a format in the plugin configuration"
+	NoRedactUsage          = "Show secrets, such as passwords and tokens, in log messages instead of redacting them. Use only for authorised debugging"
+	VersionUsage           = "Show the plugin version, including any pre-release and build metadata, with the commit, build date and Go version it was built from"
+)
+
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+
+	OverflowBlock      = "block"
+	OverflowDropOldest = "drop-oldest"
+	OverflowDropNewest = "drop-newest"
+
+	defaultTop        = 10
+	defaultBufferSize = 10000
 )
 
-func ParseFlags(args []string) (bool, bool, []string, error) {
+// Flags holds the values of the options passed to a plugin command.
+type Flags struct {
+	Recent            bool
+	SkipSslValidation bool
+	Sinks             []string
+	RulesFile         string
+	OnMatchExec       string
+	Output            string
+	Top               int
+	AllSpaces         bool
+	Org               string
+	Space             string
+	Guid              string
+	Selector          string
+	NoCache           bool
+	Refresh           bool
+	NoCapabilityCheck bool
+	BufferSize        int
+	Overflow          string
+	ReorderWindow     time.Duration
+	JoinMultiline     bool
+	Continuation      []string
+	JoinTimeout       time.Duration
+	Fields            []string
+	Columns           []string
+	JSONPretty        bool
+	Format            string
+	NoRedact          bool
+	Version           bool
+}
+
+func ParseFlags(args []string) (Flags, []string, error) {
 	const (
 		recentFlagName        = "recent"
 		sslValidationFlagName = "skip-ssl-validation"
+		sinkFlagName          = "sink"
+		rulesFlagName         = "rules"
+		onMatchExecFlagName   = "on-match-exec"
+		outputFlagName        = "output"
+		topFlagName           = "top"
+		allSpacesFlagName     = "all-spaces"
+		orgFlagName           = "o"
+		spaceFlagName         = "s"
+		guidFlagName          = "guid"
+		selectorFlagName      = "selector"
+		noCacheFlagName       = "no-cache"
+		refreshFlagName       = "refresh"
+		noCapabilityCheckName = "no-capability-check"
+		bufferSizeFlagName    = "buffer-size"
+		overflowFlagName      = "overflow"
+		reorderWindowFlagName = "reorder-window"
+		joinMultilineFlagName = "join-multiline"
+		continuationFlagName  = "continuation"
+		joinTimeoutFlagName   = "join-timeout"
+		fieldFlagName         = "field"
+		columnFlagName        = "column"
+		jsonPrettyFlagName    = "json-pretty"
+		formatFlagName        = "format"
+		noRedactFlagName      = "no-redact"
+		versionFlagName       = "version"
 	)
 
 	fc := flags.New()
 	//New flag methods take arguments: name, short_name and usage of the string flag
 	fc.NewBoolFlag(recentFlagName, recentFlagName, RecentUsage)
 	fc.NewBoolFlag(sslValidationFlagName, sslValidationFlagName, SkipSslValidationUsage)
+	fc.NewStringSliceFlag(sinkFlagName, sinkFlagName, SinkUsage)
+	fc.NewStringFlag(rulesFlagName, rulesFlagName, RulesUsage)
+	fc.NewStringFlag(onMatchExecFlagName, onMatchExecFlagName, OnMatchExecUsage)
+	fc.NewStringFlagWithDefault(outputFlagName, outputFlagName, OutputUsage, OutputText)
+	fc.NewIntFlagWithDefault(topFlagName, topFlagName, TopUsage, defaultTop)
+	fc.NewBoolFlag(allSpacesFlagName, allSpacesFlagName, AllSpacesUsage)
+	fc.NewStringFlag(orgFlagName, orgFlagName, OrgUsage)
+	fc.NewStringFlag(spaceFlagName, spaceFlagName, SpaceUsage)
+	fc.NewStringFlag(guidFlagName, guidFlagName, GuidUsage)
+	fc.NewStringFlag(selectorFlagName, selectorFlagName, SelectorUsage)
+	fc.NewBoolFlag(noCacheFlagName, noCacheFlagName, NoCacheUsage)
+	fc.NewBoolFlag(refreshFlagName, refreshFlagName, RefreshUsage)
+	fc.NewBoolFlag(noCapabilityCheckName, noCapabilityCheckName, NoCapabilityCheckUsage)
+	fc.NewIntFlagWithDefault(bufferSizeFlagName, bufferSizeFlagName, BufferSizeUsage, defaultBufferSize)
+	fc.NewStringFlagWithDefault(overflowFlagName, overflowFlagName, OverflowUsage, OverflowBlock)
+	fc.NewStringFlag(reorderWindowFlagName, reorderWindowFlagName, ReorderWindowUsage)
+	fc.NewBoolFlag(joinMultilineFlagName, joinMultilineFlagName, JoinMultilineUsage)
+	fc.NewStringSliceFlag(continuationFlagName, continuationFlagName, ContinuationUsage)
+	fc.NewStringFlag(joinTimeoutFlagName, joinTimeoutFlagName, JoinTimeoutUsage)
+	fc.NewStringSliceFlag(fieldFlagName, fieldFlagName, FieldUsage)
+	fc.NewStringSliceFlag(columnFlagName, columnFlagName, ColumnUsage)
+	fc.NewBoolFlag(jsonPrettyFlagName, jsonPrettyFlagName, JSONPrettyUsage)
+	fc.NewStringFlag(formatFlagName, formatFlagName, FormatUsage)
+	fc.NewBoolFlag(noRedactFlagName, noRedactFlagName, NoRedactUsage)
+	fc.NewBoolFlag(versionFlagName, versionFlagName, VersionUsage)
 	err := fc.Parse(args...)
 	if err != nil {
-		return false, false, nil, fmt.Errorf("Error parsing arguments: %s", err)
+		return Flags{}, nil, fmt.Errorf("Error parsing arguments: %s", err)
+	}
+	if fc.IsSet(onMatchExecFlagName) && !fc.IsSet(rulesFlagName) {
+		return Flags{}, nil, fmt.Errorf("Error parsing arguments: --%s requires --%s", onMatchExecFlagName, rulesFlagName)
+	}
+	if fc.IsSet(orgFlagName) && !fc.IsSet(spaceFlagName) {
+		return Flags{}, nil, fmt.Errorf("Error parsing arguments: -%s requires -%s", orgFlagName, spaceFlagName)
+	}
+	if fc.IsSet(guidFlagName) && fc.IsSet(selectorFlagName) {
+		return Flags{}, nil, fmt.Errorf("Error parsing arguments: --%s and --%s cannot be used together", guidFlagName, selectorFlagName)
+	}
+	if fc.IsSet(allSpacesFlagName) && fc.IsSet(spaceFlagName) {
+		return Flags{}, nil, fmt.Errorf("Error parsing arguments: --%s cannot be used with -%s", allSpacesFlagName, spaceFlagName)
+	}
+	if fc.IsSet(guidFlagName) && fc.IsSet(spaceFlagName) {
+		return Flags{}, nil, fmt.Errorf("Error parsing arguments: --%s cannot be used with -%s", guidFlagName, spaceFlagName)
+	}
+	if fc.Bool(noCacheFlagName) && fc.Bool(refreshFlagName) {
+		return Flags{}, nil, fmt.Errorf("Error parsing arguments: --%s and --%s cannot be used together", noCacheFlagName, refreshFlagName)
+	}
+	output := fc.String(outputFlagName)
+	if output != OutputText && output != OutputJSON {
+		return Flags{}, nil, fmt.Errorf("Error parsing arguments: --%s must be %s or %s", outputFlagName, OutputText, OutputJSON)
+	}
+	if fc.IsSet(formatFlagName) && output == OutputJSON {
+		return Flags{}, nil, fmt.Errorf("Error parsing arguments: --%s cannot be used with --%s %s", formatFlagName, outputFlagName, OutputJSON)
+	}
+	if fc.Int(topFlagName) < 0 {
+		return Flags{}, nil, fmt.Errorf("Error parsing arguments: --%s must not be negative", topFlagName)
+	}
+	if fc.Int(bufferSizeFlagName) <= 0 {
+		return Flags{}, nil, fmt.Errorf("Error parsing arguments: --%s must be positive", bufferSizeFlagName)
+	}
+	overflow := fc.String(overflowFlagName)
+	if overflow != OverflowBlock && overflow != OverflowDropOldest && overflow != OverflowDropNewest {
+		return Flags{}, nil, fmt.Errorf("Error parsing arguments: --%s must be %s, %s or %s", overflowFlagName, OverflowBlock, OverflowDropOldest, OverflowDropNewest)
+	}
+	var reorderWindow time.Duration
+	if fc.IsSet(reorderWindowFlagName) {
+		reorderWindow, err = time.ParseDuration(fc.String(reorderWindowFlagName))
+		if err != nil || reorderWindow < 0 {
+			return Flags{}, nil, fmt.Errorf("Error parsing arguments: --%s must be a duration such as 2s", reorderWindowFlagName)
+		}
+	}
+	for _, name := range []string{continuationFlagName, joinTimeoutFlagName} {
+		if fc.IsSet(name) && !fc.Bool(joinMultilineFlagName) {
+			return Flags{}, nil, fmt.Errorf("Error parsing arguments: --%s requires --%s", name, joinMultilineFlagName)
+		}
+	}
+	continuation := fc.StringSlice(continuationFlagName)
+	for _, pattern := range continuation {
+		if _, err := regexp.Compile(pattern); err != nil {
+			return Flags{}, nil, fmt.Errorf("Error parsing arguments: invalid --%s pattern %q: %s", continuationFlagName, pattern, err)
+		}
+	}
+	fields := fc.StringSlice(fieldFlagName)
+	for _, spec := range fields {
+		if _, err := structured.ParseFilter(spec); err != nil {
+			return Flags{}, nil, fmt.Errorf("Error parsing arguments: --%s: %s", fieldFlagName, err)
+		}
+	}
+	var joinTimeout time.Duration
+	if fc.IsSet(joinTimeoutFlagName) {
+		joinTimeout, err = time.ParseDuration(fc.String(joinTimeoutFlagName))
+		if err != nil || joinTimeout <= 0 {
+			return Flags{}, nil, fmt.Errorf("Error parsing arguments: --%s must be a positive duration such as 500ms", joinTimeoutFlagName)
+		}
 	}
-	skipSslValidation := fc.Bool(sslValidationFlagName)
-	recent := fc.Bool(recentFlagName)
-	return recent, skipSslValidation, fc.Args(), nil
+	return Flags{
+		Recent:            fc.Bool(recentFlagName),
+		SkipSslValidation: fc.Bool(sslValidationFlagName),
+		Sinks:             fc.StringSlice(sinkFlagName),
+		RulesFile:         fc.String(rulesFlagName),
+		OnMatchExec:       fc.String(onMatchExecFlagName),
+		Output:            output,
+		Top:               fc.Int(topFlagName),
+		AllSpaces:         fc.Bool(allSpacesFlagName),
+		Org:               fc.String(orgFlagName),
+		Space:             fc.String(spaceFlagName),
+		Guid:              fc.String(guidFlagName),
+		Selector:          fc.String(selectorFlagName),
+		NoCache:           fc.Bool(noCacheFlagName),
+		Refresh:           fc.Bool(refreshFlagName),
+		NoCapabilityCheck: fc.Bool(noCapabilityCheckName),
+		BufferSize:        fc.Int(bufferSizeFlagName),
+		Overflow:          overflow,
+		ReorderWindow:     reorderWindow,
+		JoinMultiline:     fc.Bool(joinMultilineFlagName),
+		Continuation:      continuation,
+		JoinTimeout:       joinTimeout,
+		Fields:            fields,
+		Columns:           fc.StringSlice(columnFlagName),
+		JSONPretty:        fc.Bool(jsonPrettyFlagName),
+		Format:            fc.String(formatFlagName),
+		NoRedact:          fc.Bool(noRedactFlagName),
+		Version:           fc.Bool(versionFlagName),
+	}, fc.Args(), nil
 }
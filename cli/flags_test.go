@@ -17,6 +17,8 @@
 package cli_test
 
 import (
+	"time"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/pivotal-cf/service-instance-logs-cli-plugin/cli"
@@ -27,12 +29,53 @@ var _ = Describe("Flags", func() {
 		args           = []string{"cf", "sil", "my-service", "--recent"}
 		recent         bool
 		sslNoVerify    bool
+		sinks          []string
+		rulesFile      string
+		onMatchExec    string
+		output         string
+		top            int
+		allSpaces      bool
+		org            string
+		space          string
+		guid           string
+		selector       string
+		noCache        bool
+		refresh        bool
+		noCapability   bool
+		bufferSize     int
+		overflow       string
+		reorderWindow  time.Duration
+		joinMultiline  bool
+		continuation   []string
+		joinTimeout    time.Duration
+		fields         []string
+		columns        []string
+		jsonPretty     bool
+		lineFormat     string
+		noRedact       bool
+		version        bool
 		positionalArgs []string
 		err            error
 	)
 
 	JustBeforeEach(func() {
-		recent, sslNoVerify, positionalArgs, err = cli.ParseFlags(args)
+		var parsed cli.Flags
+		parsed, positionalArgs, err = cli.ParseFlags(args)
+		recent, sslNoVerify, sinks = parsed.Recent, parsed.SkipSslValidation, parsed.Sinks
+		rulesFile, onMatchExec = parsed.RulesFile, parsed.OnMatchExec
+		output, top = parsed.Output, parsed.Top
+		allSpaces = parsed.AllSpaces
+		org, space = parsed.Org, parsed.Space
+		guid, selector = parsed.Guid, parsed.Selector
+		noCache, refresh = parsed.NoCache, parsed.Refresh
+		noCapability = parsed.NoCapabilityCheck
+		bufferSize, overflow = parsed.BufferSize, parsed.Overflow
+		reorderWindow = parsed.ReorderWindow
+		joinMultiline, continuation, joinTimeout = parsed.JoinMultiline, parsed.Continuation, parsed.JoinTimeout
+		fields, columns, jsonPretty = parsed.Fields, parsed.Columns, parsed.JSONPretty
+		lineFormat = parsed.Format
+		noRedact = parsed.NoRedact
+		version = parsed.Version
 	})
 
 	Context("when an unexpected flag is received", func() {
@@ -94,6 +137,555 @@ var _ = Describe("Flags", func() {
 		})
 	})
 
+	Describe("sink flag", func() {
+		Context("when the sink flag is repeated", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "my-service", "--sink", "stdout", "--sink", "https://collector/logs"}
+			})
+
+			It("should capture each value in order", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(sinks).To(Equal([]string{"stdout", "https://collector/logs"}))
+			})
+		})
+
+		Context("when the sink flag is not set", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "my-service"}
+			})
+
+			It("should capture no sinks", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(sinks).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("alerting flags", func() {
+		Context("when rules and a command are provided", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "my-service", "--rules", "rules.json", "--on-match-exec", "notify-send alert"}
+			})
+
+			It("should capture the flags' values", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(rulesFile).To(Equal("rules.json"))
+				Expect(onMatchExec).To(Equal("notify-send alert"))
+			})
+		})
+
+		Context("when a command is provided without rules", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "my-service", "--on-match-exec", "notify-send alert"}
+			})
+
+			It("should raise a suitable error", func() {
+				Expect(err).To(MatchError("Error parsing arguments: --on-match-exec requires --rules"))
+			})
+		})
+	})
+
+	Describe("stats flags", func() {
+		Context("when the flags are not set", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "service-logs-stats", "my-service"}
+			})
+
+			It("should use the defaults", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(output).To(Equal(cli.OutputText))
+				Expect(top).To(Equal(10))
+			})
+		})
+
+		Context("when the flags are set", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "service-logs-stats", "my-service", "--output", "json", "--top", "3"}
+			})
+
+			It("should capture the flags' values", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(output).To(Equal(cli.OutputJSON))
+				Expect(top).To(Equal(3))
+			})
+		})
+
+		Context("when the output format is not recognised", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "service-logs-stats", "my-service", "--output", "yaml"}
+			})
+
+			It("should raise a suitable error", func() {
+				Expect(err).To(MatchError("Error parsing arguments: --output must be text or json"))
+			})
+		})
+
+		Context("when top is negative", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "service-logs-stats", "my-service", "--top", "-1"}
+			})
+
+			It("should raise a suitable error", func() {
+				Expect(err).To(MatchError("Error parsing arguments: --top must not be negative"))
+			})
+		})
+	})
+
+	Describe("all spaces flag", func() {
+		Context("when the all spaces flag is set", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "service-logs-instances", "--all-spaces"}
+			})
+
+			It("should capture the flag's value", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(allSpaces).To(BeTrue())
+			})
+		})
+
+		Context("when the all spaces flag is not set", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "service-logs-instances"}
+			})
+
+			It("should capture the flag's value", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(allSpaces).To(BeFalse())
+			})
+		})
+	})
+
+	Describe("org and space flags", func() {
+		Context("when an org and space are given", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "my-service", "-o", "my-org", "-s", "my-space"}
+			})
+
+			It("should capture the flags' values", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(org).To(Equal("my-org"))
+				Expect(space).To(Equal("my-space"))
+			})
+		})
+
+		Context("when only a space is given", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "my-service", "-s", "my-space"}
+			})
+
+			It("should capture the flag's value", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(org).To(BeEmpty())
+				Expect(space).To(Equal("my-space"))
+			})
+		})
+
+		Context("when only an org is given", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "my-service", "-o", "my-org"}
+			})
+
+			It("should raise a suitable error", func() {
+				Expect(err).To(MatchError("Error parsing arguments: -o requires -s"))
+			})
+		})
+	})
+
+	Describe("guid and selector flags", func() {
+		Context("when a guid is given", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "--guid", "some-guid"}
+			})
+
+			It("should capture the flag's value", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(guid).To(Equal("some-guid"))
+			})
+		})
+
+		Context("when a selector is given", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "--selector", "team=payments,tier=prod", "-s", "prod"}
+			})
+
+			It("should capture the flags' values", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(selector).To(Equal("team=payments,tier=prod"))
+				Expect(space).To(Equal("prod"))
+			})
+		})
+
+		Context("when both a guid and a selector are given", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "--guid", "some-guid", "--selector", "team=payments"}
+			})
+
+			It("should raise a suitable error", func() {
+				Expect(err).To(MatchError("Error parsing arguments: --guid and --selector cannot be used together"))
+			})
+		})
+
+		Context("when a selector is to be matched in all spaces", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "--selector", "team=payments", "--all-spaces"}
+			})
+
+			It("should capture the flags' values", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(selector).To(Equal("team=payments"))
+				Expect(allSpaces).To(BeTrue())
+			})
+		})
+
+		Context("when all spaces and a space are given", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "--selector", "team=payments", "--all-spaces", "-s", "prod"}
+			})
+
+			It("should raise a suitable error", func() {
+				Expect(err).To(MatchError("Error parsing arguments: --all-spaces cannot be used with -s"))
+			})
+		})
+
+		Context("when a guid and a space are given", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "--guid", "some-guid", "-s", "prod"}
+			})
+
+			It("should raise a suitable error", func() {
+				Expect(err).To(MatchError("Error parsing arguments: --guid cannot be used with -s"))
+			})
+		})
+	})
+
+	Describe("cache flags", func() {
+		Context("when the flags are not set", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "my-service"}
+			})
+
+			It("should use the cache", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(noCache).To(BeFalse())
+				Expect(refresh).To(BeFalse())
+			})
+		})
+
+		Context("when the no cache flag is set", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "my-service", "--no-cache"}
+			})
+
+			It("should capture the flag's value", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(noCache).To(BeTrue())
+			})
+		})
+
+		Context("when the refresh flag is set", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "my-service", "--refresh"}
+			})
+
+			It("should capture the flag's value", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(refresh).To(BeTrue())
+			})
+		})
+
+		Context("when both flags are set", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "my-service", "--no-cache", "--refresh"}
+			})
+
+			It("should raise a suitable error", func() {
+				Expect(err).To(MatchError("Error parsing arguments: --no-cache and --refresh cannot be used together"))
+			})
+		})
+	})
+
+	Describe("no-capability-check flag", func() {
+		Context("when the flag is not set", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "my-service"}
+			})
+
+			It("should check capabilities by default", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(noCapability).To(BeFalse())
+			})
+		})
+
+		Context("when the flag is set", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "my-service", "--no-capability-check"}
+			})
+
+			It("should capture the flag's value", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(noCapability).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("buffering flags", func() {
+		Context("when the flags are not set", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "my-service"}
+			})
+
+			It("should default to a large blocking buffer", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(bufferSize).To(Equal(10000))
+				Expect(overflow).To(Equal(cli.OverflowBlock))
+			})
+		})
+
+		Context("when the flags are set", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "my-service", "--buffer-size", "50", "--overflow", "drop-oldest"}
+			})
+
+			It("should capture the flags' values", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(bufferSize).To(Equal(50))
+				Expect(overflow).To(Equal(cli.OverflowDropOldest))
+			})
+		})
+
+		Context("when the buffer size is not positive", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "my-service", "--buffer-size", "0"}
+			})
+
+			It("should raise a suitable error", func() {
+				Expect(err).To(MatchError("Error parsing arguments: --buffer-size must be positive"))
+			})
+		})
+
+		Context("when the overflow policy is invalid", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "my-service", "--overflow", "drop-all"}
+			})
+
+			It("should raise a suitable error", func() {
+				Expect(err).To(MatchError("Error parsing arguments: --overflow must be block, drop-oldest or drop-newest"))
+			})
+		})
+	})
+
+	Describe("reorder window flag", func() {
+		Context("when the flag is not set", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "my-service"}
+			})
+
+			It("should not reorder", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(reorderWindow).To(BeZero())
+			})
+		})
+
+		Context("when the flag is set", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "my-service", "--reorder-window", "2s"}
+			})
+
+			It("should capture the flag's value", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(reorderWindow).To(Equal(2 * time.Second))
+			})
+		})
+
+		Context("when the flag is not a duration", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "my-service", "--reorder-window", "2"}
+			})
+
+			It("should raise a suitable error", func() {
+				Expect(err).To(MatchError("Error parsing arguments: --reorder-window must be a duration such as 2s"))
+			})
+		})
+	})
+
+	Describe("multi-line flags", func() {
+		Context("when the flags are not set", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "my-service"}
+			})
+
+			It("should not join lines", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(joinMultiline).To(BeFalse())
+				Expect(continuation).To(BeEmpty())
+				Expect(joinTimeout).To(BeZero())
+			})
+		})
+
+		Context("when the flags are set", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "my-service", "--join-multiline", "--continuation", "^\\+", "--continuation", "^\\|", "--join-timeout", "500ms"}
+			})
+
+			It("should capture the flags' values", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(joinMultiline).To(BeTrue())
+				Expect(continuation).To(Equal([]string{"^\\+", "^\\|"}))
+				Expect(joinTimeout).To(Equal(500 * time.Millisecond))
+			})
+		})
+
+		Context("when a continuation pattern is given without --join-multiline", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "my-service", "--continuation", "^\\+"}
+			})
+
+			It("should raise a suitable error", func() {
+				Expect(err).To(MatchError("Error parsing arguments: --continuation requires --join-multiline"))
+			})
+		})
+
+		Context("when a continuation pattern is invalid", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "my-service", "--join-multiline", "--continuation", "("}
+			})
+
+			It("should raise a suitable error", func() {
+				Expect(err).To(MatchError(HavePrefix(`Error parsing arguments: invalid --continuation pattern "(": `)))
+			})
+		})
+
+		Context("when the join timeout is not a positive duration", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "my-service", "--join-multiline", "--join-timeout", "0s"}
+			})
+
+			It("should raise a suitable error", func() {
+				Expect(err).To(MatchError("Error parsing arguments: --join-timeout must be a positive duration such as 500ms"))
+			})
+		})
+	})
+
+	Describe("JSON message flags", func() {
+		Context("when the flags are not set", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "my-service"}
+			})
+
+			It("should not filter or decorate messages", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(fields).To(BeEmpty())
+				Expect(columns).To(BeEmpty())
+				Expect(jsonPretty).To(BeFalse())
+			})
+		})
+
+		Context("when the flags are set", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "my-service", "--field", "level=error", "--field", "component~=replication", "--column", "level", "--json-pretty"}
+			})
+
+			It("should capture the flags' values", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(fields).To(Equal([]string{"level=error", "component~=replication"}))
+				Expect(columns).To(Equal([]string{"level"}))
+				Expect(jsonPretty).To(BeTrue())
+			})
+		})
+
+		Context("when a field filter is invalid", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "my-service", "--field", "level"}
+			})
+
+			It("should raise a suitable error", func() {
+				Expect(err).To(MatchError(`Error parsing arguments: --field: Invalid field filter "level": expected NAME=VALUE, NAME!=VALUE or NAME~=REGEX`))
+			})
+		})
+
+		Context("when a field filter's regular expression is invalid", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "my-service", "--field", "component~=("}
+			})
+
+			It("should raise a suitable error", func() {
+				Expect(err).To(MatchError(ContainSubstring(`Error parsing arguments: --field: Invalid field filter "component~=(": error parsing regexp`)))
+			})
+		})
+	})
+
+	Describe("format flag", func() {
+		Context("when the flag is set", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "my-service", "--format", "{{.Message}}"}
+			})
+
+			It("should capture the flag's value", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(lineFormat).To(Equal("{{.Message}}"))
+			})
+		})
+
+		Context("when the flag is used with JSON output", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "my-service", "--format", "short", "--output", "json"}
+			})
+
+			It("should raise a suitable error", func() {
+				Expect(err).To(MatchError("Error parsing arguments: --format cannot be used with --output json"))
+			})
+		})
+	})
+
+	Describe("no-redact flag", func() {
+		Context("when the flag is not set", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "my-service"}
+			})
+
+			It("should redact by default", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(noRedact).To(BeFalse())
+			})
+		})
+
+		Context("when the flag is set", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "my-service", "--no-redact"}
+			})
+
+			It("should capture the flag's value", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(noRedact).To(BeTrue())
+			})
+		})
+	})
+
+	Describe("version flag", func() {
+		Context("when the flag is not set", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "my-service"}
+			})
+
+			It("should not show the version", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(version).To(BeFalse())
+			})
+		})
+
+		Context("when the flag is set without a service instance", func() {
+			BeforeEach(func() {
+				args = []string{"cf", "sil", "--version"}
+			})
+
+			It("should capture the flag's value", func() {
+				Expect(err).ToNot(HaveOccurred())
+				Expect(version).To(BeTrue())
+				Expect(positionalArgs).To(Equal([]string{"cf", "sil"}))
+			})
+		})
+	})
+
 	Describe("positional arguments", func() {
 		Context("when positional arguments are provided", func() {
 			BeforeEach(func() {
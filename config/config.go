@@ -0,0 +1,81 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// fileName is the name of the configuration file in the plugin's configuration directory.
+const fileName = "config.json"
+
+// Config is the plugin's user configuration, for example:
+//
+//	{
+//	  "formats": {"short": "{{.Time | timefmt \"15:04:05\"}} {{.SourceInstance}} {{.Message}}"},
+//	  "redactions": [{"name": "api-key", "pattern": "api_key=(\\w+)"}]
+//	}
+type Config struct {
+	// Formats maps names, which may be passed to --format, to log line templates.
+	Formats map[string]string `json:"formats"`
+	// Redactions match secrets to be redacted from log messages in addition to the built-in rules.
+	Redactions []Redaction `json:"redactions"`
+}
+
+// Redaction is a regular expression matching secrets in log messages. If the expression has capturing groups, only
+// the text matched by the groups is redacted.
+type Redaction struct {
+	Name    string `json:"name"`
+	Pattern string `json:"pattern"`
+}
+
+// DefaultPath returns the path of the configuration file in the user's configuration directory.
+func DefaultPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "service-instance-logs-cli-plugin", fileName), nil
+}
+
+// Load reads the configuration file at the given path. A missing file denotes an empty configuration.
+func Load(path string) (Config, error) {
+	contents, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return Config{}, nil
+	}
+	if err != nil {
+		return Config{}, fmt.Errorf("Cannot read configuration file: %s", err)
+	}
+
+	var c Config
+	if err := json.Unmarshal(contents, &c); err != nil {
+		return Config{}, fmt.Errorf("Configuration file %s contained invalid JSON: %s", path, err)
+	}
+	return c, nil
+}
+
+// Format returns the log line template with the given name, if there is one.
+func (c Config) Format(name string) (string, bool) {
+	template, ok := c.Formats[name]
+	return template, ok
+}
@@ -0,0 +1,68 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/config"
+)
+
+var _ = Describe("Config", func() {
+	var path string
+
+	BeforeEach(func() {
+		path = filepath.Join(GinkgoT().TempDir(), "config.json")
+	})
+
+	It("should read named formats", func() {
+		Expect(os.WriteFile(path, []byte(`{"formats": {"short": "{{.Message}}"}}`), 0644)).To(Succeed())
+		c, err := config.Load(path)
+		Expect(err).NotTo(HaveOccurred())
+		template, ok := c.Format("short")
+		Expect(ok).To(BeTrue())
+		Expect(template).To(Equal("{{.Message}}"))
+		_, ok = c.Format("long")
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should read redaction rules", func() {
+		Expect(os.WriteFile(path, []byte(`{"redactions": [{"name": "api-key", "pattern": "api_key=(\\w+)"}]}`), 0644)).To(Succeed())
+		c, err := config.Load(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Redactions).To(Equal([]config.Redaction{{Name: "api-key", Pattern: `api_key=(\w+)`}}))
+	})
+
+	Context("when the file does not exist", func() {
+		It("should return an empty configuration", func() {
+			c, err := config.Load(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(c).To(Equal(config.Config{}))
+		})
+	})
+
+	Context("when the file is not valid JSON", func() {
+		It("should return a suitable error", func() {
+			Expect(os.WriteFile(path, []byte(`{`), 0644)).To(Succeed())
+			_, err := config.Load(path)
+			Expect(err).To(MatchError("Configuration file " + path + " contained invalid JSON: unexpected end of JSON input"))
+		})
+	})
+})
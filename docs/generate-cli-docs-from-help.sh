@@ -4,7 +4,7 @@ if [ ! -z ${DEBUG} ]; then
     set -x
 fi
 
-declare -a SCS_COMMANDS=("service-instance-logs")
+declare -a SCS_COMMANDS=("service-logs" "service-logs-stats" "service-logs-doctor" "service-logs-instances")
 CMD_DOC_FILENAME=cli.md
 
 echo "# Service Instance Logs CF CLI Plugin Docs
@@ -0,0 +1,533 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package doctor
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"code.cloudfoundry.org/cli/plugin"
+	"github.com/gorilla/websocket"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/cfutil"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/format"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logging"
+)
+
+const timeout = 10 * time.Second
+
+// proxyFromEnvironment determines the proxy, if any, through which the plugin connects to a logs endpoint.
+var proxyFromEnvironment = http.ProxyFromEnvironment
+
+// Status is the outcome of a check.
+type Status string
+
+const (
+	Pass Status = "OK"
+	Fail Status = "FAIL"
+	Skip Status = "SKIP"
+)
+
+// Check is the outcome of one step in obtaining the logs of a service instance. Advice describes how to
+// remedy a failure.
+type Check struct {
+	Name   string
+	Status Status
+	Detail string
+	Advice string
+}
+
+// Report is the outcome of all the checks, in the order they were performed.
+type Report struct {
+	Checks []Check
+}
+
+// Passed reports whether every check passed.
+func (r Report) Passed() bool {
+	for _, c := range r.Checks {
+		if c.Status != Pass {
+			return false
+		}
+	}
+	return true
+}
+
+// Write prints the report to the given writer.
+func (r Report) Write(w io.Writer) {
+	for _, c := range r.Checks {
+		var status string
+		switch c.Status {
+		case Pass:
+			status = format.Green(string(c.Status))
+		case Fail:
+			status = format.Red(string(c.Status))
+		default:
+			status = string(c.Status)
+		}
+		fmt.Fprintf(w, "%-4s  %s", status, format.Bold(c.Name))
+		if c.Detail != "" {
+			fmt.Fprintf(w, ": %s", c.Detail)
+		}
+		fmt.Fprintln(w)
+		if c.Status == Fail && c.Advice != "" {
+			fmt.Fprintf(w, "      Hint: %s\n", c.Advice)
+		}
+	}
+}
+
+type doctor struct {
+	cliConnection     plugin.CliConnection
+	skipSslValidation bool
+	report            Report
+	failed            bool
+
+	serviceInstanceGUID string
+	servicePlanGUID     string
+	serviceGUID         string
+	rawEndpoint         string
+	endpoint            *url.URL
+	address             string
+	proxy               *url.URL
+	accessToken         string
+}
+
+// Run walks through each step which the plugin performs to obtain the logs of the given service instance and
+// reports the outcome of each. Once a step fails, steps which depend on it are skipped.
+func Run(cliConnection plugin.CliConnection, selector cfutil.Selector, skipSslValidation bool) Report {
+	d := &doctor{cliConnection: cliConnection, skipSslValidation: skipSslValidation}
+
+	d.check("Service instance lookup", func() (string, string, error) {
+		instances, err := cfutil.GetServiceInstances(cliConnection, selector)
+		if err != nil {
+			return "", "Check the name with 'cf services' and that the intended org and space are targeted with 'cf target' or -o and -s.", err
+		}
+		if len(instances) > 1 {
+			return "", "Diagnose one service instance at a time by giving its name or --guid.",
+				fmt.Errorf("Label selector %s matches %d service instances", selector.LabelSelector, len(instances))
+		}
+		instance := instances[0]
+		d.serviceInstanceGUID = instance.Guid
+		d.servicePlanGUID = instance.ServicePlanGuid
+		if instance.SharedFrom != nil {
+			return fmt.Sprintf("guid %s, shared from org %s / space %s", instance.Guid, instance.SharedFrom.OrgName, instance.SharedFrom.SpaceName), "", nil
+		}
+		return "guid " + instance.Guid, "", nil
+	})
+
+	d.check("Service plan lookup", func() (string, string, error) {
+		serviceGUID, err := logging.ObtainServiceGuid(cliConnection, d.servicePlanGUID)
+		if err != nil {
+			return "", fmt.Sprintf("Check that the plan is visible to you with 'cf curl /v2/service_plans/%s'.", d.servicePlanGUID), err
+		}
+		d.serviceGUID = serviceGUID
+		return "service offering " + serviceGUID, "", nil
+	})
+
+	d.check("Catalog metadata", func() (string, string, error) {
+		endpoint, err := logging.ObtainServiceInstanceLogsEndpoint(cliConnection, d.serviceGUID)
+		if err != nil {
+			return "", fmt.Sprintf("Inspect the 'extra' field with 'cf curl /v2/services/%s'. The service broker must advertise serviceInstanceLogsEndpoint; ask your Cloud Foundry operator whether a newer broker is available.", d.serviceGUID), err
+		}
+		d.rawEndpoint = endpoint
+		return "serviceInstanceLogsEndpoint " + endpoint, "", nil
+	})
+
+	d.check("Endpoint URL", d.checkEndpointURL)
+	d.check("DNS resolution", d.checkDNS)
+	d.check("TCP connection", d.checkTCP)
+	if d.endpoint == nil || (d.endpoint.Scheme == "https" && (d.proxy == nil || d.tunnels())) {
+		d.check("TLS handshake", d.checkTLS)
+	}
+
+	d.check("Access token", d.checkToken)
+	d.check("Logs service capabilities", d.checkCapabilities)
+	d.check("Recent logs request", d.checkRecentLogs)
+	d.check("Websocket upgrade", d.checkWebsocket)
+
+	return d.report
+}
+
+// check performs a step, unless an earlier step has failed, and records its outcome. The step returns a detail
+// message on success or advice and an error on failure.
+func (d *doctor) check(name string, step func() (string, string, error)) {
+	if d.failed {
+		d.report.Checks = append(d.report.Checks, Check{Name: name, Status: Skip})
+		return
+	}
+
+	detail, advice, err := step()
+	if err != nil {
+		d.failed = true
+		d.report.Checks = append(d.report.Checks, Check{Name: name, Status: Fail, Detail: err.Error(), Advice: advice})
+		return
+	}
+	d.report.Checks = append(d.report.Checks, Check{Name: name, Status: Pass, Detail: detail})
+}
+
+func (d *doctor) checkEndpointURL() (string, string, error) {
+	const advice = "The service broker advertised an unusable endpoint. Ask your Cloud Foundry operator to check the broker configuration."
+
+	u, err := url.Parse(d.rawEndpoint)
+	if err != nil {
+		return "", advice, err
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", advice, fmt.Errorf("scheme %q is not http or https", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return "", advice, errors.New("host not specified")
+	}
+
+	proxy, err := proxyFromEnvironment(&http.Request{URL: u})
+	if err != nil {
+		return "", "Check the HTTPS_PROXY, HTTP_PROXY and NO_PROXY environment variables.", fmt.Errorf("invalid proxy: %s", err)
+	}
+
+	d.endpoint = u
+	d.address = hostPort(u)
+	d.proxy = proxy
+
+	detail := u.String()
+	if u.Scheme == "http" {
+		detail += " (not encrypted)"
+	}
+	if proxy != nil {
+		detail += " through proxy " + proxy.Redacted()
+	}
+	return detail, "", nil
+}
+
+// hostPort returns the host and port of the given URL, defaulting the port according to the scheme.
+func hostPort(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		switch u.Scheme {
+		case "http":
+			port = "80"
+		case "socks5", "socks5h":
+			port = "1080"
+		default:
+			port = "443"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+// tunnels reports whether connections to the endpoint are tunnelled through the proxy with HTTP CONNECT requests,
+// so that the TLS handshake with the endpoint can be checked through the proxy.
+func (d *doctor) tunnels() bool {
+	return d.proxy.Scheme == "http" || d.proxy.Scheme == "https"
+}
+
+// checkDNS resolves the endpoint's host or, if there is a proxy, the proxy's host, since the proxy resolves and
+// connects to the endpoint's host itself.
+func (d *doctor) checkDNS() (string, string, error) {
+	if d.proxy != nil {
+		addrs, err := net.LookupHost(d.proxy.Hostname())
+		if err != nil {
+			return "", fmt.Sprintf("Check that the proxy %s can be resolved from this machine and that HTTPS_PROXY or HTTP_PROXY names the intended proxy.", d.proxy.Hostname()), err
+		}
+		return "proxy " + d.proxy.Hostname() + ": " + strings.Join(addrs, ", "), "", nil
+	}
+
+	addrs, err := net.LookupHost(d.endpoint.Hostname())
+	if err != nil {
+		return "", fmt.Sprintf("Check that %s can be resolved from this machine, for example with 'nslookup %s', and that your DNS or VPN configuration gives access to the platform.", d.endpoint.Hostname(), d.endpoint.Hostname()), err
+	}
+	return strings.Join(addrs, ", "), "", nil
+}
+
+func (d *doctor) checkTCP() (string, string, error) {
+	if d.proxy != nil {
+		address := hostPort(d.proxy)
+		conn, err := net.DialTimeout("tcp", address, timeout)
+		if err != nil {
+			return "", fmt.Sprintf("Check that the proxy at %s accepts connections from this machine.", address), err
+		}
+		conn.Close()
+		return "connected to proxy " + address, "", nil
+	}
+
+	conn, err := net.DialTimeout("tcp", d.address, timeout)
+	if err != nil {
+		return "", fmt.Sprintf("Check that no firewall or proxy blocks connections from this machine to %s.", d.address), err
+	}
+	conn.Close()
+	return "connected to " + d.address, "", nil
+}
+
+// tunnelError reports that the proxy did not open a tunnel to the endpoint.
+type tunnelError struct {
+	err error
+}
+
+func (e *tunnelError) Error() string {
+	return e.err.Error()
+}
+
+// dial connects to the endpoint, through the proxy if there is one, and performs the TLS handshake.
+func (d *doctor) dial(insecure bool) (*tls.Conn, error) {
+	config := &tls.Config{ServerName: d.endpoint.Hostname(), InsecureSkipVerify: insecure}
+	if d.proxy == nil {
+		return tls.DialWithDialer(&net.Dialer{Timeout: timeout}, "tcp", d.address, config)
+	}
+
+	tunnel, err := d.dialTunnel()
+	if err != nil {
+		return nil, &tunnelError{err}
+	}
+	conn := tls.Client(tunnel, config)
+	conn.SetDeadline(time.Now().Add(timeout))
+	if err := conn.Handshake(); err != nil {
+		tunnel.Close()
+		return nil, err
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+// dialTunnel asks the proxy to open a tunnel to the endpoint with an HTTP CONNECT request, as the plugin does.
+func (d *doctor) dialTunnel() (net.Conn, error) {
+	conn, err := net.DialTimeout("tcp", hostPort(d.proxy), timeout)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+	if d.proxy.Scheme == "https" {
+		conn = tls.Client(conn, &tls.Config{ServerName: d.proxy.Hostname()})
+	}
+
+	req := &http.Request{Method: http.MethodConnect, URL: &url.URL{Opaque: d.address}, Host: d.address, Header: http.Header{}}
+	if user := d.proxy.User; user != nil {
+		password, _ := user.Password()
+		req.Header.Set("Proxy-Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(user.Username()+":"+password)))
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		conn.Close()
+		return nil, fmt.Errorf("proxy %s refused to connect to %s: %s", d.proxy.Host, d.address, resp.Status)
+	}
+	conn.SetDeadline(time.Time{})
+	return conn, nil
+}
+
+func (d *doctor) checkTLS() (string, string, error) {
+	conn, err := d.dial(false)
+	if err == nil {
+		defer conn.Close()
+		chain := conn.ConnectionState().PeerCertificates
+		leaf := chain[0]
+		return fmt.Sprintf("certificate for %s issued by %s, valid until %s (chain of %d)",
+			leaf.Subject.CommonName, leaf.Issuer.CommonName, leaf.NotAfter.Format("2006-01-02"), len(chain)), "", nil
+	}
+
+	if d.skipSslValidation {
+		// The plugin does not verify the certificate in this case, so only a failure of the handshake itself matters.
+		if insecureConn, insecureErr := d.dial(true); insecureErr == nil {
+			insecureConn.Close()
+			return fmt.Sprintf("certificate not verified (--skip-ssl-validation): %s", err), "", nil
+		}
+	}
+
+	var tunnel *tunnelError
+	var unknownAuthority x509.UnknownAuthorityError
+	var invalid x509.CertificateInvalidError
+	var hostname x509.HostnameError
+	advice := "Check the platform's certificate configuration with your Cloud Foundry operator."
+	switch {
+	case errors.As(err, &tunnel):
+		advice = fmt.Sprintf("Check that the proxy %s allows connections to %s, or add %s to NO_PROXY if it should be reached directly.", d.proxy.Host, d.address, d.endpoint.Hostname())
+	case errors.As(err, &unknownAuthority):
+		advice = "The certificate is not signed by a trusted authority. Add the platform's CA certificate to this machine's trust store or, at your own risk, use --skip-ssl-validation."
+	case errors.As(err, &invalid) && invalid.Reason == x509.Expired:
+		advice = "The certificate has expired or is not yet valid. Check this machine's clock and ask your Cloud Foundry operator to renew the certificate."
+	case errors.As(err, &hostname):
+		advice = "The certificate does not match the endpoint host. Ask your Cloud Foundry operator to check the broker's serviceInstanceLogsEndpoint and certificate."
+	}
+	return "", advice, err
+}
+
+func (d *doctor) checkToken() (string, string, error) {
+	const advice = "Log in again with 'cf login'."
+
+	token, err := cfutil.GetToken(d.cliConnection)
+	if err != nil {
+		return "", advice, err
+	}
+	d.accessToken = token
+
+	expiry, err := tokenExpiry(token)
+	if err != nil {
+		return "", advice, err
+	}
+	if !expiry.After(time.Now()) {
+		return "", "Your session has expired. Log in again with 'cf login'.", fmt.Errorf("token expired at %s", expiry.Format(time.RFC3339))
+	}
+	return "expires " + expiry.Format(time.RFC3339), "", nil
+}
+
+func tokenExpiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, errors.New("access token is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("access token payload could not be decoded: %s", err)
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, fmt.Errorf("access token payload contained invalid JSON: %s", err)
+	}
+	if claims.Exp == 0 {
+		return time.Time{}, errors.New("access token has no expiry")
+	}
+	return time.Unix(claims.Exp, 0), nil
+}
+
+func (d *doctor) tlsConfig() *tls.Config {
+	return &tls.Config{InsecureSkipVerify: d.skipSslValidation}
+}
+
+func (d *doctor) httpClient() *http.Client {
+	return &http.Client{
+		Timeout:   timeout,
+		Transport: &http.Transport{Proxy: proxyFromEnvironment, TLSClientConfig: d.tlsConfig()},
+	}
+}
+
+func (d *doctor) checkCapabilities() (string, string, error) {
+	info, err := logclient.FetchInfo(context.Background(), d.httpClient(), d.rawEndpoint, d.accessToken)
+	if err != nil {
+		// The plugin assumes the capabilities of an older logs service in this case, so this is not a failure.
+		return fmt.Sprintf("info document unusable, so assuming protocol version %d: %s", logclient.ProtocolVersion, err), "", nil
+	}
+	if !info.SupportsProtocol(logclient.ProtocolVersion) {
+		return "", "Check for a newer version of the plugin.",
+			fmt.Errorf("protocol versions %s are supported but this plugin implements version %d", joinInts(info.ProtocolVersions), logclient.ProtocolVersion)
+	}
+	if info.ProtocolVersions == nil && info.Backends == nil && info.Filters == nil && info.MaxRecentWindow == 0 && !info.RecentOrdered {
+		return fmt.Sprintf("no info document, so assuming protocol version %d", logclient.ProtocolVersion), "", nil
+	}
+
+	details := []string{"protocol versions " + joinInts(info.ProtocolVersions)}
+	if len(info.Backends) > 0 {
+		details = append(details, "backends "+strings.Join(info.Backends, ", "))
+	}
+	if len(info.Filters) > 0 {
+		details = append(details, "filters "+strings.Join(info.Filters, ", "))
+	}
+	if info.MaxRecentWindow > 0 {
+		details = append(details, "recent logs cover at most "+info.MaxRecentWindow.String())
+	}
+	if info.RecentOrdered {
+		details = append(details, "recent logs in timestamp order")
+	}
+	return strings.Join(details, "; "), "", nil
+}
+
+func joinInts(values []int) string {
+	if len(values) == 0 {
+		return fmt.Sprint(logclient.ProtocolVersion)
+	}
+	s := make([]string, len(values))
+	for i, v := range values {
+		s[i] = fmt.Sprint(v)
+	}
+	return strings.Join(s, ", ")
+}
+
+func (d *doctor) checkRecentLogs() (string, string, error) {
+	recentURL := logclient.RecentLogsURL(d.endpoint, d.serviceInstanceGUID)
+	req, err := http.NewRequest(http.MethodGet, recentURL, nil)
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Authorization", "bearer "+d.accessToken)
+
+	resp, err := d.httpClient().Do(req)
+	if err != nil {
+		return "", "Check that no proxy between this machine and the platform rejects the request.", err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode == http.StatusOK {
+		return fmt.Sprintf("GET %s returned %s", recentURL, resp.Status), "", nil
+	}
+	return "", statusAdvice(resp.StatusCode, d.serviceGUID), fmt.Errorf("GET %s returned %s", recentURL, resp.Status)
+}
+
+func (d *doctor) checkWebsocket() (string, string, error) {
+	streamEndpoint, err := logging.ConvertServiceInstanceLogsEndpoint(d.rawEndpoint)
+	if err != nil {
+		return "", "", err
+	}
+	streamURL := streamEndpoint + logclient.StreamPath(d.serviceInstanceGUID)
+
+	dialer := websocket.Dialer{
+		Proxy:            proxyFromEnvironment,
+		HandshakeTimeout: timeout,
+		TLSClientConfig:  d.tlsConfig(),
+	}
+	conn, resp, err := dialer.Dial(streamURL, http.Header{"Authorization": []string{"bearer " + d.accessToken}})
+	if err != nil {
+		if resp != nil {
+			return "", statusAdvice(resp.StatusCode, d.serviceGUID), fmt.Errorf("upgrade of %s returned %s", streamURL, resp.Status)
+		}
+		return "", "Check that any proxy between this machine and the platform supports websockets.", err
+	}
+	conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+	conn.Close()
+	return "upgraded " + streamURL, "", nil
+}
+
+func statusAdvice(statusCode int, serviceGUID string) string {
+	switch statusCode {
+	case http.StatusUnauthorized:
+		return "The logs endpoint rejected your access token. Log in again with 'cf login'."
+	case http.StatusForbidden:
+		return "You are not authorised to view this service instance's logs. Check your roles with 'cf space-users ORG SPACE'; space developers and auditors may view logs."
+	case http.StatusNotFound:
+		return fmt.Sprintf("The logs endpoint does not know this service instance. Check that it still exists with 'cf services' and inspect the advertised endpoint with 'cf curl /v2/services/%s'.", serviceGUID)
+	default:
+		return "The logs endpoint is not healthy. Ask your Cloud Foundry operator to check the service broker."
+	}
+}
@@ -0,0 +1,356 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package doctor_test
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"code.cloudfoundry.org/cli/plugin/models"
+	"code.cloudfoundry.org/cli/plugin/pluginfakes"
+	"github.com/gorilla/websocket"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/cfutil"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/doctor"
+)
+
+var _ = Describe("Doctor", func() {
+	const (
+		serviceInstanceName = "siname"
+		serviceInstanceGUID = "870cdf18-7e15-435a-8459-6c38a8452d79"
+		servicePlanGUID     = "D6000A16-99D7-4E92-8FB7-5EC1E33D633B"
+		serviceGUID         = "aaaa-bbbb-cccc-dddd"
+	)
+
+	var (
+		fakeCliConnection *pluginfakes.FakeCliConnection
+		server            *httptest.Server
+		useTLS            bool
+		recentLogsStatus  int
+		infoDocument      string
+		endpoint          string
+		skipSslValidation bool
+		report            doctor.Report
+	)
+
+	jwt := func(expiry time.Time) string {
+		encode := base64.RawURLEncoding.EncodeToString
+		return encode([]byte(`{"alg":"none"}`)) + "." + encode([]byte(fmt.Sprintf(`{"exp":%d}`, expiry.Unix()))) + ".sig"
+	}
+
+	statuses := func() []doctor.Status {
+		result := []doctor.Status{}
+		for _, c := range report.Checks {
+			result = append(result, c.Status)
+		}
+		return result
+	}
+
+	find := func(name string) doctor.Check {
+		for _, c := range report.Checks {
+			if c.Name == name {
+				return c
+			}
+		}
+		Fail("check not found: " + name)
+		return doctor.Check{}
+	}
+
+	BeforeEach(func() {
+		useTLS = true
+		skipSslValidation = true
+		recentLogsStatus = http.StatusOK
+		infoDocument = ""
+		endpoint = ""
+
+		fakeCliConnection = &pluginfakes.FakeCliConnection{}
+		fakeCliConnection.GetServiceReturns(plugin_models.GetService_Model{Guid: serviceInstanceGUID, ServicePlan: plugin_models.GetService_ServicePlan{Guid: servicePlanGUID}}, nil)
+		fakeCliConnection.AccessTokenReturns("bearer "+jwt(time.Now().Add(time.Hour)), nil)
+		fakeCliConnection.CliCommandWithoutTerminalOutputStub = func(args ...string) ([]string, error) {
+			switch args[1] {
+			case "/v2/service_plans/" + servicePlanGUID:
+				return []string{fmt.Sprintf(`{"entity": {"service_guid": "%s"}}`, serviceGUID)}, nil
+			case "/v2/services/" + serviceGUID:
+				return []string{fmt.Sprintf(`{"entity": {"extra": "{\"serviceInstanceLogsEndpoint\":\"%s\"}"}}`, endpoint)}, nil
+			default:
+				return nil, nil
+			}
+		}
+	})
+
+	JustBeforeEach(func() {
+		mux := http.NewServeMux()
+		mux.HandleFunc("/.well-known/service-instance-logs", func(rw http.ResponseWriter, r *http.Request) {
+			if infoDocument == "" {
+				rw.WriteHeader(http.StatusNotFound)
+				return
+			}
+			io.WriteString(rw, infoDocument)
+		})
+		mux.HandleFunc("/logs/"+serviceInstanceGUID+"/recentlogs", func(rw http.ResponseWriter, r *http.Request) {
+			rw.WriteHeader(recentLogsStatus)
+		})
+		mux.HandleFunc("/logs/"+serviceInstanceGUID+"/stream", func(rw http.ResponseWriter, r *http.Request) {
+			upgrader := websocket.Upgrader{}
+			conn, err := upgrader.Upgrade(rw, r, nil)
+			if err == nil {
+				conn.Close()
+			}
+		})
+		server = httptest.NewUnstartedServer(mux)
+		server.Config.ErrorLog = log.New(io.Discard, "", 0) // expected handshake failures are not interesting
+		if useTLS {
+			server.StartTLS()
+		} else {
+			server.Start()
+		}
+		if endpoint == "" {
+			endpoint = server.URL + "/logs/"
+		}
+
+		report = doctor.Run(fakeCliConnection, cfutil.Selector{Name: serviceInstanceName}, skipSslValidation)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	Context("when everything works", func() {
+		It("should pass every check", func() {
+			Expect(report.Passed()).To(BeTrue())
+			Expect(report.Checks).To(HaveLen(11))
+			Expect(find("TLS handshake").Detail).To(HavePrefix("certificate not verified (--skip-ssl-validation)"))
+			Expect(find("Logs service capabilities").Detail).To(Equal("no info document, so assuming protocol version 1"))
+		})
+
+		It("should print the outcome of each check", func() {
+			output := gbytes.NewBuffer()
+			report.Write(output)
+			Expect(output).To(gbytes.Say("Service instance lookup"))
+			Expect(output).To(gbytes.Say("guid " + serviceInstanceGUID))
+			Expect(output).To(gbytes.Say("Websocket upgrade"))
+		})
+	})
+
+	Context("when the endpoint is not encrypted", func() {
+		BeforeEach(func() {
+			useTLS = false
+		})
+
+		It("should not check the TLS handshake", func() {
+			Expect(report.Passed()).To(BeTrue())
+			Expect(report.Checks).To(HaveLen(10))
+			Expect(find("Endpoint URL").Detail).To(HaveSuffix("(not encrypted)"))
+		})
+	})
+
+	Context("when the service instance cannot be found", func() {
+		BeforeEach(func() {
+			fakeCliConnection.GetServiceReturns(plugin_models.GetService_Model{}, errors.New("Service instance siname not found"))
+		})
+
+		It("should fail the lookup and skip the remaining checks", func() {
+			Expect(report.Passed()).To(BeFalse())
+			Expect(statuses()).To(Equal([]doctor.Status{doctor.Fail, doctor.Skip, doctor.Skip, doctor.Skip, doctor.Skip,
+				doctor.Skip, doctor.Skip, doctor.Skip, doctor.Skip, doctor.Skip, doctor.Skip}))
+			Expect(report.Checks[0].Advice).To(ContainSubstring("cf services"))
+		})
+
+		It("should print advice for the failure", func() {
+			output := gbytes.NewBuffer()
+			report.Write(output)
+			Expect(output).To(gbytes.Say("FAIL"))
+			Expect(output).To(gbytes.Say("Service instance siname not found"))
+			Expect(output).To(gbytes.Say("Hint: Check the name with 'cf services'"))
+			Expect(output).To(gbytes.Say("SKIP"))
+		})
+	})
+
+	Context("when the catalog does not advertise an endpoint", func() {
+		BeforeEach(func() {
+			endpoint = " "
+			fakeCliConnection.CliCommandWithoutTerminalOutputStub = func(args ...string) ([]string, error) {
+				if args[1] == "/v2/services/"+serviceGUID {
+					return []string{`{"entity": {"extra": "{}"}}`}, nil
+				}
+				return []string{fmt.Sprintf(`{"entity": {"service_guid": "%s"}}`, serviceGUID)}, nil
+			}
+		})
+
+		It("should fail the catalog check with advice", func() {
+			check := find("Catalog metadata")
+			Expect(check.Status).To(Equal(doctor.Fail))
+			Expect(check.Advice).To(ContainSubstring("cf curl /v2/services/" + serviceGUID))
+		})
+	})
+
+	Context("when the endpoint has an unsupported scheme", func() {
+		BeforeEach(func() {
+			endpoint = "ftp://service-instance-logs/"
+		})
+
+		It("should fail the endpoint URL check", func() {
+			check := find("Endpoint URL")
+			Expect(check.Status).To(Equal(doctor.Fail))
+			Expect(check.Detail).To(Equal(`scheme "ftp" is not http or https`))
+		})
+	})
+
+	Context("when the certificate is not trusted", func() {
+		BeforeEach(func() {
+			skipSslValidation = false
+		})
+
+		It("should fail the TLS check with advice", func() {
+			check := find("TLS handshake")
+			Expect(check.Status).To(Equal(doctor.Fail))
+			Expect(check.Advice).To(ContainSubstring("--skip-ssl-validation"))
+			Expect(find("Access token").Status).To(Equal(doctor.Skip))
+		})
+	})
+
+	Context("when connections go through a proxy", func() {
+		var (
+			proxy    *httptest.Server
+			connects atomic.Int32
+			refuse   bool
+		)
+
+		BeforeEach(func() {
+			connects.Store(0)
+			refuse = false
+			proxy = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+				connects.Add(1)
+				if r.Method != http.MethodConnect || refuse {
+					rw.WriteHeader(http.StatusForbidden)
+					return
+				}
+				target, err := net.Dial("tcp", r.Host)
+				if err != nil {
+					rw.WriteHeader(http.StatusBadGateway)
+					return
+				}
+				conn, _, err := rw.(http.Hijacker).Hijack()
+				if err != nil {
+					target.Close()
+					return
+				}
+				io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+				go func() {
+					io.Copy(target, conn)
+					target.Close()
+				}()
+				go func() {
+					io.Copy(conn, target)
+					conn.Close()
+				}()
+			}))
+			proxyURL, err := url.Parse(proxy.URL)
+			Expect(err).NotTo(HaveOccurred())
+			doctor.SetProxy(http.ProxyURL(proxyURL))
+		})
+
+		AfterEach(func() {
+			doctor.SetProxy(http.ProxyFromEnvironment)
+			proxy.Close()
+		})
+
+		It("should check the connection to the endpoint through the proxy", func() {
+			Expect(report.Passed()).To(BeTrue())
+			Expect(find("Endpoint URL").Detail).To(HaveSuffix(" through proxy " + proxy.URL))
+			Expect(find("DNS resolution").Detail).To(HavePrefix("proxy 127.0.0.1: "))
+			Expect(find("TCP connection").Detail).To(Equal("connected to proxy " + proxy.Listener.Addr().String()))
+			Expect(find("TLS handshake").Detail).To(HavePrefix("certificate not verified (--skip-ssl-validation)"))
+			Expect(connects.Load()).To(BeNumerically(">=", 4))
+		})
+
+		Context("when the proxy refuses to connect to the endpoint", func() {
+			BeforeEach(func() {
+				refuse = true
+			})
+
+			It("should fail the TLS check with advice about the proxy", func() {
+				check := find("TLS handshake")
+				Expect(check.Status).To(Equal(doctor.Fail))
+				Expect(check.Detail).To(ContainSubstring("403 Forbidden"))
+				Expect(check.Advice).To(ContainSubstring("NO_PROXY"))
+			})
+		})
+	})
+
+	Context("when the access token has expired", func() {
+		BeforeEach(func() {
+			fakeCliConnection.AccessTokenReturns("bearer "+jwt(time.Now().Add(-time.Hour)), nil)
+		})
+
+		It("should fail the token check with advice", func() {
+			check := find("Access token")
+			Expect(check.Status).To(Equal(doctor.Fail))
+			Expect(check.Detail).To(HavePrefix("token expired at"))
+			Expect(check.Advice).To(ContainSubstring("cf login"))
+		})
+	})
+
+	Context("when the recent logs request is forbidden", func() {
+		BeforeEach(func() {
+			recentLogsStatus = http.StatusForbidden
+		})
+
+		It("should fail the recent logs check with advice", func() {
+			check := find("Recent logs request")
+			Expect(check.Status).To(Equal(doctor.Fail))
+			Expect(check.Detail).To(ContainSubstring("403 Forbidden"))
+			Expect(check.Advice).To(ContainSubstring("cf space-users"))
+		})
+	})
+
+	Context("when the logs service publishes an info document", func() {
+		BeforeEach(func() {
+			infoDocument = `{"protocol_versions":[1,2],"backends":["recent","stream"],"filters":["field"],"max_recent_window":"24h","recent_ordered":true}`
+		})
+
+		It("should report its capabilities", func() {
+			check := find("Logs service capabilities")
+			Expect(check.Status).To(Equal(doctor.Pass))
+			Expect(check.Detail).To(Equal("protocol versions 1, 2; backends recent, stream; filters field; recent logs cover at most 24h0m0s; recent logs in timestamp order"))
+		})
+	})
+
+	Context("when the logs service implements another protocol version", func() {
+		BeforeEach(func() {
+			infoDocument = `{"protocol_versions":[2]}`
+		})
+
+		It("should fail the capabilities check with advice", func() {
+			check := find("Logs service capabilities")
+			Expect(check.Status).To(Equal(doctor.Fail))
+			Expect(check.Detail).To(Equal("protocol versions 2 are supported but this plugin implements version 1"))
+			Expect(check.Advice).To(ContainSubstring("newer version of the plugin"))
+		})
+	})
+})
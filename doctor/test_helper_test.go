@@ -0,0 +1,11 @@
+package doctor
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// Allow the proxy to be replaced, but only in tests (since the name of this file ends in "...test.go").
+func SetProxy(proxy func(*http.Request) (*url.URL, error)) {
+	proxyFromEnvironment = proxy
+}
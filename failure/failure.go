@@ -0,0 +1,147 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package failure
+
+import (
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/cfutil"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logging"
+)
+
+// Category names a kind of failure. Each category has its own exit code, which is documented and must not change
+// since scripts rely on it.
+type Category string
+
+const (
+	General               Category = "error"
+	Usage                 Category = "usage"
+	NotFound              Category = "not-found"
+	EndpointNotAdvertised Category = "endpoint-not-advertised"
+	Unsupported           Category = "unsupported"
+	Unauthorized          Category = "unauthorized"
+	Forbidden             Category = "forbidden"
+	TLS                   Category = "tls"
+	Connection            Category = "connection"
+)
+
+var exitCodes = map[Category]int{
+	General:               1,
+	Usage:                 2,
+	NotFound:              3,
+	EndpointNotAdvertised: 4,
+	Unsupported:           5,
+	Unauthorized:          6,
+	Forbidden:             7,
+	TLS:                   8,
+	Connection:            9,
+}
+
+// ExitCode returns the exit code of the plugin when it fails with a failure of the given category.
+func (c Category) ExitCode() int {
+	if code, ok := exitCodes[c]; ok {
+		return code
+	}
+	return exitCodes[General]
+}
+
+// Failure describes why a command failed, for people and for scripts.
+type Failure struct {
+	Code     int      `json:"code"`
+	Category Category `json:"category"`
+	Message  string   `json:"message"`
+	Hint     string   `json:"hint,omitempty"`
+}
+
+// New returns a failure of the given category with the given message.
+func New(category Category, message string) Failure {
+	return Failure{Code: category.ExitCode(), Category: category, Message: message}
+}
+
+// Classify determines the category of the given error and returns a failure describing it, with a hint from the
+// catalog of hints if there is one. Errors which are not recognised are general failures.
+func Classify(err error) Failure {
+	f := New(categorise(err), err.Error())
+	f.Hint = hint(err)
+	return f
+}
+
+func categorise(err error) Category {
+	var notFound *cfutil.NotFoundError
+	var forbidden *cfutil.ForbiddenError
+	var ccErr *cfutil.CCError
+	var unsupported *logging.UnsupportedError
+	var status *logclient.StatusError
+	var unknownAuthority x509.UnknownAuthorityError
+	var invalid x509.CertificateInvalidError
+	var hostname x509.HostnameError
+	switch {
+	case errors.As(err, &notFound):
+		return NotFound
+	case errors.As(err, &forbidden):
+		return Forbidden
+	case errors.As(err, &ccErr):
+		return ccCategory(ccErr)
+	case errors.Is(err, logging.ErrEndpointNotAdvertised):
+		return EndpointNotAdvertised
+	case errors.As(err, &unsupported):
+		return Unsupported
+	case errors.As(err, &status):
+		return statusCategory(status.StatusCode)
+	case errors.As(err, new(*logclient.TLSError)),
+		errors.As(err, &unknownAuthority), errors.As(err, &invalid), errors.As(err, &hostname):
+		return TLS
+	case errors.As(err, new(*logclient.ConnectionError)):
+		return Connection
+	}
+	return General
+}
+
+func ccCategory(ccErr *cfutil.CCError) Category {
+	switch {
+	case ccErr.ErrorCode == "CF-InvalidAuthToken":
+		return Unauthorized
+	case ccErr.IsNotAuthorized():
+		return Forbidden
+	case strings.HasSuffix(ccErr.ErrorCode, "NotFound"):
+		return NotFound
+	}
+	return General
+}
+
+func statusCategory(statusCode int) Category {
+	switch statusCode {
+	case http.StatusUnauthorized:
+		return Unauthorized
+	case http.StatusForbidden:
+		return Forbidden
+	case http.StatusNotFound:
+		return NotFound
+	}
+	return General
+}
+
+// WriteJSON writes the failure to the given writer as a JSON object on a single line.
+func (f Failure) WriteJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(f)
+}
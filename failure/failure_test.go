@@ -0,0 +1,117 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package failure_test
+
+import (
+	"bytes"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"syscall"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/cfutil"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/failure"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logging"
+)
+
+var _ = Describe("Failure", func() {
+	Describe("Classify", func() {
+		DescribeTable("should categorise errors",
+			func(err error, category failure.Category, code int) {
+				f := failure.Classify(err)
+				Expect(f.Category).To(Equal(category))
+				Expect(f.Code).To(Equal(code))
+				Expect(f.Message).To(Equal(err.Error()))
+			},
+			Entry("unrecognised", errors.New("some error"), failure.General, 1),
+			Entry("missing service instance", &cfutil.NotFoundError{Message: "Service instance si not found"}, failure.NotFound, 3),
+			Entry("missing Cloud Controller resource", &cfutil.CCError{Path: "/v2/service_instances/guid", ErrorCode: "CF-ServiceInstanceNotFound"}, failure.NotFound, 3),
+			Entry("shared service instance", &cfutil.ForbiddenError{Message: "not authorized", OrgName: "platform-org", SpaceName: "platform"}, failure.Forbidden, 7),
+			Entry("missing endpoint", logging.ErrEndpointNotAdvertised, failure.EndpointNotAdvertised, 4),
+			Entry("unsupported logs", &logging.UnsupportedError{ServiceInstance: "si", Message: "no recent logs"}, failure.Unsupported, 5),
+			Entry("rejected token", &logclient.StatusError{StatusCode: http.StatusUnauthorized, Err: errors.New("Unauthorized error: invalid token")}, failure.Unauthorized, 6),
+			Entry("expired Cloud Controller token", &cfutil.CCError{Path: "/v2/info", ErrorCode: "CF-InvalidAuthToken"}, failure.Unauthorized, 6),
+			Entry("refused access", &logclient.StatusError{StatusCode: http.StatusForbidden, Err: errors.New("forbidden")}, failure.Forbidden, 7),
+			Entry("missing logs", &logclient.StatusError{StatusCode: http.StatusNotFound, Err: errors.New("not found")}, failure.NotFound, 3),
+			Entry("server error", &logclient.StatusError{StatusCode: http.StatusInternalServerError, Err: errors.New("not OK")}, failure.General, 1),
+			Entry("unverified certificate", &logclient.TLSError{Err: errors.New("x509: certificate signed by unknown authority")}, failure.TLS, 8),
+			Entry("unverified Cloud Controller certificate", fmt.Errorf("Get https://api: %w", x509.UnknownAuthorityError{}), failure.TLS, 8),
+			Entry("unreachable logs service", &logclient.ConnectionError{Err: errors.New("connection refused")}, failure.Connection, 9),
+			Entry("error of one of several service instances", fmt.Errorf("service instance si: %w", &logclient.ConnectionError{Err: errors.New("connection refused")}), failure.Connection, 9),
+		)
+
+		DescribeTable("should suggest next steps",
+			func(err error, hint string) {
+				Expect(failure.Classify(err).Hint).To(Equal(hint))
+			},
+			Entry("unrecognised", errors.New("some error"), ""),
+			Entry("missing service instance", &cfutil.NotFoundError{Message: "Service instance si not found"},
+				"Check the name with 'cf services' and that the intended org and space are targeted with 'cf target' or -o and -s."),
+			Entry("shared service instance", &cfutil.ForbiddenError{Message: "not authorized", OrgName: "platform-org", SpaceName: "platform"},
+				"Check your roles with 'cf space-users platform-org platform'."),
+			Entry("missing Cloud Controller resource", &cfutil.CCError{Path: "/v2/service_instances/guid", ErrorCode: "CF-ServiceInstanceNotFound"},
+				"Check that the service instance still exists with 'cf services' and, if you gave --guid, check its GUID with 'cf service SERVICE_INSTANCE --guid'."),
+			Entry("expired Cloud Controller token", &cfutil.CCError{Path: "/v2/info", ErrorCode: "CF-InvalidAuthToken"},
+				"Your session may have expired. Log in again with 'cf login'."),
+			Entry("missing endpoint", &logging.EndpointNotAdvertisedError{ServiceGuid: "service-guid"},
+				"Inspect the 'extra' field of the service offering with 'cf curl /v2/services/service-guid'. The service broker must advertise serviceInstanceLogsEndpoint; ask your Cloud Foundry operator whether a newer broker is available."),
+			Entry("rejected token", &logclient.StatusError{StatusCode: http.StatusUnauthorized, Err: errors.New("Unauthorized error: invalid token")},
+				"Your session may have expired. Log in again with 'cf login'."),
+			Entry("refused access", &logging.ServiceInstanceError{ServiceInstance: "si", ServiceGuid: "service-guid", Err: &logclient.StatusError{StatusCode: http.StatusForbidden, Err: errors.New("forbidden")}},
+				"Viewing the logs of service instance si requires the SpaceDeveloper or SpaceAuditor role in its space. Check your roles with 'cf space-users ORG SPACE'."),
+			Entry("missing logs", &logging.ServiceInstanceError{ServiceInstance: "si", ServiceGuid: "service-guid", Err: &logclient.StatusError{StatusCode: http.StatusNotFound, Err: errors.New("not found")}},
+				"Check that service instance si still exists with 'cf services'. If it does, check the serviceInstanceLogsEndpoint advertised by its service offering with 'cf curl /v2/services/service-guid'."),
+			Entry("missing logs of an unidentified service instance", &logclient.StatusError{StatusCode: http.StatusNotFound, Err: errors.New("not found")},
+				"Check that a service instance still exists with 'cf services'. If it does, 'cf service-logs-doctor' checks the logs endpoint advertised by its service offering."),
+			Entry("server error", &logclient.StatusError{StatusCode: http.StatusInternalServerError, Err: errors.New("not OK")}, ""),
+			Entry("unknown certificate authority", &logclient.TLSError{Err: fmt.Errorf("Error connecting: %w", x509.UnknownAuthorityError{})},
+				"The certificate is not signed by a trusted authority. Add the platform's CA certificate to this machine's trust store or, at your own risk, try --skip-ssl-validation."),
+			Entry("mismatched certificate", &logclient.TLSError{Err: fmt.Errorf("Error connecting: %w", x509.HostnameError{Host: "logs.example.com", Certificate: &x509.Certificate{}})},
+				"The certificate does not match the endpoint host. Ask your Cloud Foundry operator to check the broker's serviceInstanceLogsEndpoint and certificate."),
+			Entry("unverified certificate reported as text", &logclient.TLSError{Err: errors.New("x509: certificate signed by unknown authority")},
+				"Check the platform's certificate configuration with your Cloud Foundry operator or, at your own risk, try --skip-ssl-validation."),
+			Entry("unresolvable host", &logclient.ConnectionError{Err: &net.DNSError{Err: "no such host", Name: "logs.example.com", IsNotFound: true}},
+				"Check that logs.example.com can be resolved from this machine, for example with 'nslookup logs.example.com', and that your DNS or VPN configuration gives access to the platform."),
+			Entry("refused connection", &logclient.ConnectionError{Err: &net.OpError{Op: "dial", Net: "tcp", Addr: &net.TCPAddr{IP: net.IPv4(10, 0, 0, 1), Port: 443}, Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}},
+				"Nothing accepted the connection to 10.0.0.1:443. Check that no firewall or proxy blocks connections from this machine and ask your Cloud Foundry operator whether the logs service is running."),
+			Entry("unreachable logs service", &logclient.ConnectionError{Err: errors.New("Error dialing trafficcontroller server: dial tcp: i/o timeout")},
+				"Check that no firewall or proxy blocks connections from this machine to the logs service. 'cf service-logs-doctor' checks each step of the connection."),
+		)
+	})
+
+	Describe("WriteJSON", func() {
+		It("should write the failure as a JSON object on one line", func() {
+			var buffer bytes.Buffer
+			f := failure.New(failure.Usage, "Service instance name not specified.")
+			f.Hint = "some hint"
+			Expect(f.WriteJSON(&buffer)).To(Succeed())
+			Expect(buffer.String()).To(Equal(`{"code":2,"category":"usage","message":"Service instance name not specified.","hint":"some hint"}` + "\n"))
+		})
+
+		It("should omit an empty hint", func() {
+			var buffer bytes.Buffer
+			Expect(failure.New(failure.General, "some error").WriteJSON(&buffer)).To(Succeed())
+			Expect(buffer.String()).To(Equal(`{"code":1,"category":"error","message":"some error"}` + "\n"))
+		})
+	})
+})
@@ -0,0 +1,157 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package failure
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"syscall"
+
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/cfutil"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logging"
+)
+
+const (
+	loginHint = "Your session may have expired. Log in again with 'cf login'."
+	rolesHint = "Viewing the logs of %s requires the SpaceDeveloper or SpaceAuditor role in its space. Check your roles with 'cf space-users ORG SPACE'."
+)
+
+// hints is the catalog of hints, tried in order so that more specific errors come first. Each entry suggests the
+// next steps for errors of one type and returns an empty string for other errors.
+var hints = []func(err error) string{
+	notFoundHint,
+	forbiddenHint,
+	ccErrorHint,
+	endpointNotAdvertisedHint,
+	statusHint,
+	tlsHint,
+	connectionHint,
+}
+
+// hint returns the first hint in the catalog for the given error, or an empty string if there is none.
+func hint(err error) string {
+	for _, h := range hints {
+		if s := h(err); s != "" {
+			return s
+		}
+	}
+	return ""
+}
+
+func notFoundHint(err error) string {
+	var notFound *cfutil.NotFoundError
+	if !errors.As(err, &notFound) {
+		return ""
+	}
+	return "Check the name with 'cf services' and that the intended org and space are targeted with 'cf target' or -o and -s."
+}
+
+func forbiddenHint(err error) string {
+	var forbidden *cfutil.ForbiddenError
+	if !errors.As(err, &forbidden) {
+		return ""
+	}
+	return fmt.Sprintf("Check your roles with 'cf space-users %s %s'.", forbidden.OrgName, forbidden.SpaceName)
+}
+
+func ccErrorHint(err error) string {
+	var ccErr *cfutil.CCError
+	if !errors.As(err, &ccErr) {
+		return ""
+	}
+	switch ccCategory(ccErr) {
+	case Unauthorized:
+		return loginHint
+	case Forbidden:
+		return fmt.Sprintf(rolesHint, "a service instance")
+	case NotFound:
+		return "Check that the service instance still exists with 'cf services' and, if you gave --guid, check its GUID with 'cf service SERVICE_INSTANCE --guid'."
+	}
+	return ""
+}
+
+func endpointNotAdvertisedHint(err error) string {
+	var notAdvertised *logging.EndpointNotAdvertisedError
+	if !errors.As(err, &notAdvertised) {
+		return ""
+	}
+	return fmt.Sprintf("Inspect the 'extra' field of the service offering with 'cf curl /v2/services/%s'. The service broker must advertise serviceInstanceLogsEndpoint; ask your Cloud Foundry operator whether a newer broker is available.",
+		notAdvertised.ServiceGuid)
+}
+
+func statusHint(err error) string {
+	var status *logclient.StatusError
+	if !errors.As(err, &status) {
+		return ""
+	}
+	instance := "a service instance"
+	var instanceErr *logging.ServiceInstanceError
+	if errors.As(err, &instanceErr) {
+		instance = "service instance " + instanceErr.ServiceInstance
+	}
+
+	switch status.StatusCode {
+	case http.StatusUnauthorized:
+		return loginHint
+	case http.StatusForbidden:
+		return fmt.Sprintf(rolesHint, instance)
+	case http.StatusNotFound:
+		if instanceErr != nil && instanceErr.ServiceGuid != "" {
+			return fmt.Sprintf("Check that %s still exists with 'cf services'. If it does, check the serviceInstanceLogsEndpoint advertised by its service offering with 'cf curl /v2/services/%s'.",
+				instance, instanceErr.ServiceGuid)
+		}
+		return fmt.Sprintf("Check that %s still exists with 'cf services'. If it does, 'cf service-logs-doctor' checks the logs endpoint advertised by its service offering.", instance)
+	}
+	return ""
+}
+
+func tlsHint(err error) string {
+	var unknownAuthority x509.UnknownAuthorityError
+	var invalid x509.CertificateInvalidError
+	var hostname x509.HostnameError
+	switch {
+	case errors.As(err, &unknownAuthority):
+		return "The certificate is not signed by a trusted authority. Add the platform's CA certificate to this machine's trust store or, at your own risk, try --skip-ssl-validation."
+	case errors.As(err, &invalid) && invalid.Reason == x509.Expired:
+		return "The certificate has expired or is not yet valid. Check this machine's clock and ask your Cloud Foundry operator to renew the certificate."
+	case errors.As(err, &hostname):
+		return "The certificate does not match the endpoint host. Ask your Cloud Foundry operator to check the broker's serviceInstanceLogsEndpoint and certificate."
+	case errors.As(err, new(*logclient.TLSError)):
+		return "Check the platform's certificate configuration with your Cloud Foundry operator or, at your own risk, try --skip-ssl-validation."
+	}
+	return ""
+}
+
+func connectionHint(err error) string {
+	var dnsErr *net.DNSError
+	var opErr *net.OpError
+	switch {
+	case errors.As(err, &dnsErr):
+		return fmt.Sprintf("Check that %s can be resolved from this machine, for example with 'nslookup %s', and that your DNS or VPN configuration gives access to the platform.",
+			dnsErr.Name, dnsErr.Name)
+	case errors.Is(err, syscall.ECONNREFUSED) && errors.As(err, &opErr) && opErr.Addr != nil:
+		return fmt.Sprintf("Nothing accepted the connection to %s. Check that no firewall or proxy blocks connections from this machine and ask your Cloud Foundry operator whether the logs service is running.",
+			opErr.Addr)
+	case errors.As(err, new(*logclient.ConnectionError)):
+		return "Check that no firewall or proxy blocks connections from this machine to the logs service. 'cf service-logs-doctor' checks each step of the connection."
+	}
+	return ""
+}
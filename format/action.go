@@ -19,7 +19,6 @@ package format
 import (
 	"fmt"
 	"io"
-	"strings"
 
 	"code.cloudfoundry.org/cli/plugin"
 	"github.com/fatih/color"
@@ -32,34 +31,45 @@ var (
 	Red   func(format string, a ...interface{}) string = color.New(color.FgRed).SprintfFunc()
 )
 
-// Run a given action with a given progress message, writing the output to the given writer and invoking a failure closure if an error occurs.
-func RunAction(cliConnection plugin.CliConnection, message string, action func() error, writer io.Writer, onFailure func()) {
-	printStartAction(cliConnection, message, writer)
+// Run a given action with a given progress message, writing the output to the given writer and invoking a failure closure with the error if one occurs.
+func RunAction(cliConnection plugin.CliConnection, message string, action func() error, writer io.Writer, onFailure func(error)) {
+	RunActionInSpace(cliConnection, "", "", message, action, writer, onFailure)
+}
+
+// Run a given action like RunAction, but report the given org and space in the progress message. An empty org or space name denotes the targeted one.
+func RunActionInSpace(cliConnection plugin.CliConnection, org string, space string, message string, action func() error, writer io.Writer, onFailure func(error)) {
+	printStartAction(cliConnection, org, space, message, writer)
 	err := action()
 	if err != nil {
-		Diagnose(err.Error(), writer, onFailure)
+		onFailure(err)
 		return
 	}
 }
 
-// Run a given action writing the output to the given writer and invoking a failure closure if an error occurs.
-func RunActionQuietly(cliConnection plugin.CliConnection, action func() error, writer io.Writer, onFailure func()) {
+// Run a given action without a progress message, invoking a failure closure with the error if one occurs.
+func RunActionQuietly(cliConnection plugin.CliConnection, action func() error, onFailure func(error)) {
 	err := action()
 	if err != nil {
-		Diagnose(err.Error(), writer, onFailure)
+		onFailure(err)
 		return
 	}
 }
 
-func printStartAction(cliConnection plugin.CliConnection, message string, writer io.Writer) {
-	orgModel, err := cliConnection.GetCurrentOrg()
-	if err != nil {
-		return
+func printStartAction(cliConnection plugin.CliConnection, org string, space string, message string, writer io.Writer) {
+	if org == "" {
+		orgModel, err := cliConnection.GetCurrentOrg()
+		if err != nil {
+			return
+		}
+		org = orgModel.Name
 	}
 
-	spaceModel, err := cliConnection.GetCurrentSpace()
-	if err != nil {
-		return
+	if space == "" {
+		spaceModel, err := cliConnection.GetCurrentSpace()
+		if err != nil {
+			return
+		}
+		space = spaceModel.Name
 	}
 
 	user, err := cliConnection.Username()
@@ -67,17 +77,13 @@ func printStartAction(cliConnection plugin.CliConnection, message string, writer
 		return
 	}
 
-	fmt.Fprintf(writer, "%s in org %s / space %s as %s...\n", message, Bold(Cyan(orgModel.Name)), Bold(Cyan(spaceModel.Name)), Bold(Cyan(user)))
+	fmt.Fprintf(writer, "%s in org %s / space %s as %s...\n", message, Bold(Cyan(org)), Bold(Cyan(space)), Bold(Cyan(user)))
 }
 
-func Diagnose(message string, writer io.Writer, onFailure func()) {
-	fmt.Fprintf(writer, "%s\n", Bold(Red("FAILED")))
-
-	hint := ""
-	if strings.Contains(message, "unknown authority") {
-		hint = "Hint: try --skip-ssl-validation at your own risk.\n"
+// Diagnose prints a failure message, followed by the given hint unless it is empty, to the given writer.
+func Diagnose(message string, hint string, writer io.Writer) {
+	fmt.Fprintf(writer, "%s\n%s\n", Bold(Red("FAILED")), message)
+	if hint != "" {
+		fmt.Fprintf(writer, "Hint: %s\n", hint)
 	}
-
-	fmt.Fprintf(writer, "%s\n%s", message, hint)
-	onFailure()
 }
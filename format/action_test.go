@@ -31,16 +31,12 @@ import (
 
 var _ = Describe("Actions", func() {
 	Describe("RunAction", func() {
-		const (
-			testMessage = "some message"
-			failMessage = "FAILED"
-			certHint    = "Hint: try --skip-ssl-validation at your own risk.\n"
-		)
+		const testMessage = "some message"
 
 		var (
 			fakeCliConnection *pluginfakes.FakeCliConnection
 			action            func() error
-			onFailure         func()
+			failure           error
 			output            string
 		)
 
@@ -71,12 +67,14 @@ var _ = Describe("Actions", func() {
 				return nil
 			}
 
-			onFailure = func() {}
+			failure = nil
 		})
 
 		JustBeforeEach(func() {
 			writer := &bytes.Buffer{}
-			format.RunAction(fakeCliConnection, testMessage, action, writer, onFailure)
+			format.RunAction(fakeCliConnection, testMessage, action, writer, func(err error) {
+				failure = err
+			})
 			output = writer.String()
 		})
 
@@ -135,6 +133,10 @@ var _ = Describe("Actions", func() {
 			})
 		})
 
+		It("should not invoke the failure closure", func() {
+			Expect(failure).NotTo(HaveOccurred())
+		})
+
 		Context("when the action fails", func() {
 			BeforeEach(func() {
 				action = func() error {
@@ -142,35 +144,58 @@ var _ = Describe("Actions", func() {
 				}
 			})
 
-			It("should print a failure message", func() {
-				Expect(output).To(ContainSubstring(failMessage))
+			It("should pass the error to the failure closure", func() {
+				Expect(failure).To(MatchError("Fake Error"))
 			})
 		})
+	})
+
+	Describe("RunActionInSpace", func() {
+		const testMessage = "some message"
 
-		Context("when the action fails with a certificate error", func() {
+		var (
+			fakeCliConnection *pluginfakes.FakeCliConnection
+			org               string
+			output            string
+		)
+
+		BeforeEach(func() {
+			fakeCliConnection = &pluginfakes.FakeCliConnection{}
+			fakeCliConnection.GetCurrentOrgReturns(plugin_models.Organization{OrganizationFields: plugin_models.OrganizationFields{Name: "someOrg"}}, nil)
+			fakeCliConnection.GetCurrentSpaceReturns(plugin_models.Space{SpaceFields: plugin_models.SpaceFields{Name: "someSpace"}}, nil)
+			fakeCliConnection.UsernameReturns("someUser", nil)
+			org = "otherOrg"
+		})
+
+		JustBeforeEach(func() {
+			writer := &bytes.Buffer{}
+			format.RunActionInSpace(fakeCliConnection, org, "otherSpace", testMessage, func() error { return nil }, writer, func(error) {})
+			output = writer.String()
+		})
+
+		It("should report the given org and space", func() {
+			Expect(output).To(Equal(testMessage + fmt.Sprintf(" in org %s / space %s as %s...\n",
+				format.Bold(format.Cyan("otherOrg")), format.Bold(format.Cyan("otherSpace")), format.Bold(format.Cyan("someUser")))))
+			Expect(fakeCliConnection.GetCurrentSpaceCallCount()).To(Equal(0))
+		})
+
+		Context("when no org is given", func() {
 			BeforeEach(func() {
-				action = func() error {
-					return errors.New("Error: unknown authority")
-				}
+				org = ""
 			})
 
-			It("should print a suitable hint", func() {
-				Expect(output).To(ContainSubstring(certHint))
+			It("should report the targeted org", func() {
+				Expect(output).To(ContainSubstring(format.Bold(format.Cyan("someOrg"))))
+				Expect(output).To(ContainSubstring(format.Bold(format.Cyan("otherSpace"))))
 			})
 		})
 	})
 
 	Describe("RunActionQuietly", func() {
-		const (
-			failMessage = "FAILED"
-			certHint    = "Hint: try --skip-ssl-validation at your own risk.\n"
-		)
-
 		var (
 			fakeCliConnection *pluginfakes.FakeCliConnection
 			action            func() error
-			onFailure         func()
-			output            string
+			failure           error
 		)
 
 		BeforeEach(func() {
@@ -200,13 +225,17 @@ var _ = Describe("Actions", func() {
 				return nil
 			}
 
-			onFailure = func() {}
+			failure = nil
 		})
 
 		JustBeforeEach(func() {
-			writer := &bytes.Buffer{}
-			format.RunActionQuietly(fakeCliConnection, action, writer, onFailure)
-			output = writer.String()
+			format.RunActionQuietly(fakeCliConnection, action, func(err error) {
+				failure = err
+			})
+		})
+
+		It("should not invoke the failure closure", func() {
+			Expect(failure).NotTo(HaveOccurred())
 		})
 
 		Context("when the action fails", func() {
@@ -216,20 +245,39 @@ var _ = Describe("Actions", func() {
 				}
 			})
 
-			It("should print a failure message", func() {
-				Expect(output).To(ContainSubstring(failMessage))
+			It("should pass the error to the failure closure", func() {
+				Expect(failure).To(MatchError("Fake Error"))
 			})
 		})
+	})
+
+	Describe("Diagnose", func() {
+		var (
+			hint   string
+			output string
+		)
+
+		BeforeEach(func() {
+			hint = ""
+		})
+
+		JustBeforeEach(func() {
+			writer := &bytes.Buffer{}
+			format.Diagnose("Fake Error", hint, writer)
+			output = writer.String()
+		})
+
+		It("should print a failure message", func() {
+			Expect(output).To(Equal(format.Bold(format.Red("FAILED")) + "\nFake Error\n"))
+		})
 
-		Context("when the action fails with a certificate error", func() {
+		Context("when there is a hint", func() {
 			BeforeEach(func() {
-				action = func() error {
-					return errors.New("Error: unknown authority")
-				}
+				hint = "try --skip-ssl-validation at your own risk."
 			})
 
-			It("should print a suitable hint", func() {
-				Expect(output).To(ContainSubstring(certHint))
+			It("should print the hint", func() {
+				Expect(output).To(HaveSuffix("Fake Error\nHint: try --skip-ssl-validation at your own risk.\n"))
 			})
 		})
 	})
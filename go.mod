@@ -30,7 +30,7 @@ require (
 	github.com/elazarl/goproxy v0.0.0-20170413182129-aacba83f36a5 // indirect
 	github.com/fatih/color v1.18.0
 	github.com/gogo/protobuf v1.3.2
-	github.com/gorilla/websocket v1.5.3 // indirect
+	github.com/gorilla/websocket v1.5.3
 	github.com/mattn/go-colorable v0.1.14 // indirect
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	gopkg.in/check.v1 v1.0.0-20201130134442-10cb98267c6c // indirect
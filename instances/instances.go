@@ -0,0 +1,237 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package instances
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"sort"
+	"text/tabwriter"
+
+	"code.cloudfoundry.org/cli/plugin"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/cfutil"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logging"
+)
+
+// Instance describes a service instance and whether its logs are available to the plugin.
+type Instance struct {
+	Name          string
+	Guid          string
+	Space         string
+	Offering      string
+	Plan          string
+	LogsSupported bool
+	EndpointHost  string
+	// Problem explains why the offering could not be inspected, if it could not.
+	Problem string
+}
+
+type serviceInstanceEntity struct {
+	Name            string `json:"name"`
+	ServicePlanGuid string `json:"service_plan_guid"`
+	SpaceGuid       string `json:"space_guid"`
+}
+
+type spaceEntity struct {
+	Name string `json:"name"`
+}
+
+type offering struct {
+	label    string
+	endpoint string
+	err      error
+}
+
+type lister struct {
+	cliConnection plugin.CliConnection
+	plans         map[string]logging.ServicePlanEntity
+	offerings     map[string]offering
+}
+
+// List returns the managed service instances in the targeted space, or in every space of the targeted org if
+// allSpaces is true, sorted by space and name.
+func List(cliConnection plugin.CliConnection, allSpaces bool) ([]Instance, error) {
+	var query string
+	spaceNames := map[string]string{}
+
+	if allSpaces {
+		org, err := cliConnection.GetCurrentOrg()
+		if err != nil {
+			return nil, err
+		}
+		if org.Guid == "" {
+			return nil, fmt.Errorf("No org targeted. Use 'cf target -o ORG' to target an org.")
+		}
+		spaces, err := cfutil.CurlResources(cliConnection, fmt.Sprintf("/v2/organizations/%s/spaces", org.Guid))
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range spaces {
+			var entity spaceEntity
+			if err := json.Unmarshal(s.Entity, &entity); err != nil {
+				return nil, fmt.Errorf("/v2/organizations/%s/spaces returned invalid JSON: %s", org.Guid, err)
+			}
+			spaceNames[s.Metadata.Guid] = entity.Name
+		}
+		query = "organization_guid:" + org.Guid
+	} else {
+		space, err := cliConnection.GetCurrentSpace()
+		if err != nil {
+			return nil, err
+		}
+		if space.Guid == "" {
+			return nil, fmt.Errorf("No space targeted. Use 'cf target -s SPACE' to target a space.")
+		}
+		spaceNames[space.Guid] = space.Name
+		query = "space_guid:" + space.Guid
+	}
+
+	resources, err := cfutil.CurlResources(cliConnection, "/v2/service_instances?q="+url.QueryEscape(query))
+	if err != nil {
+		return nil, err
+	}
+
+	l := &lister{
+		cliConnection: cliConnection,
+		plans:         map[string]logging.ServicePlanEntity{},
+		offerings:     map[string]offering{},
+	}
+
+	result := []Instance{}
+	for _, r := range resources {
+		var entity serviceInstanceEntity
+		if err := json.Unmarshal(r.Entity, &entity); err != nil {
+			return nil, fmt.Errorf("/v2/service_instances returned invalid JSON: %s", err)
+		}
+		result = append(result, l.describe(r.Metadata.Guid, entity, spaceNames[entity.SpaceGuid]))
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Space != result[j].Space {
+			return result[i].Space < result[j].Space
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+func (l *lister) describe(guid string, entity serviceInstanceEntity, space string) Instance {
+	instance := Instance{Name: entity.Name, Guid: guid, Space: space}
+
+	plan, err := l.plan(entity.ServicePlanGuid)
+	if err != nil {
+		instance.Problem = err.Error()
+		return instance
+	}
+	instance.Plan = plan.Name
+
+	o := l.offering(plan.ServiceGuid)
+	instance.Offering = o.label
+	if o.err != nil {
+		instance.Problem = o.err.Error()
+		return instance
+	}
+
+	instance.LogsSupported = true
+	if u, err := url.Parse(o.endpoint); err == nil {
+		instance.EndpointHost = u.Host
+	}
+	return instance
+}
+
+func (l *lister) plan(guid string) (logging.ServicePlanEntity, error) {
+	if plan, ok := l.plans[guid]; ok {
+		return plan, nil
+	}
+	var plan logging.ServicePlanStructure
+	if err := cfutil.Curl(l.cliConnection, "/v2/service_plans/"+guid, &plan); err != nil {
+		return logging.ServicePlanEntity{}, err
+	}
+	l.plans[guid] = plan.Entity
+	return plan.Entity, nil
+}
+
+func (l *lister) offering(guid string) offering {
+	if o, ok := l.offerings[guid]; ok {
+		return o
+	}
+	var service logging.ServiceStructure
+	o := offering{}
+	if err := cfutil.Curl(l.cliConnection, "/v2/services/"+guid, &service); err != nil {
+		o.err = err
+	} else {
+		o.label = service.Entity.Label
+		o.endpoint, o.err = service.ServiceInstanceLogsEndpoint()
+	}
+	l.offerings[guid] = o
+	return o
+}
+
+// Write prints the given instances as a table, including the space column if requested, followed by notes on any
+// problems encountered when inspecting them.
+func Write(w io.Writer, instances []Instance, showSpace bool) error {
+	if len(instances) == 0 {
+		_, err := fmt.Fprintln(w, "No service instances found.")
+		return err
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 4, 3, ' ', 0)
+	// Colour escape sequences would upset the column alignment, so the table is written without them.
+	if showSpace {
+		fmt.Fprint(tw, "space\t")
+	}
+	fmt.Fprint(tw, "name\tservice\tplan\tlogs\tendpoint\n")
+
+	for _, i := range instances {
+		if showSpace {
+			fmt.Fprintf(tw, "%s\t", i.Space)
+		}
+		logs := "no"
+		switch {
+		case i.LogsSupported:
+			logs = "yes"
+		case i.Offering == "" && i.Problem != "":
+			logs = "unknown"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", i.Name, i.Offering, i.Plan, logs, i.EndpointHost)
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	return writeProblems(w, instances, showSpace)
+}
+
+// writeProblems prints, under the table, why the logs of each instance with a problem are not known to be available.
+func writeProblems(w io.Writer, instances []Instance, showSpace bool) error {
+	header := "\nNotes:\n"
+	for _, i := range instances {
+		if i.Problem == "" {
+			continue
+		}
+		name := i.Name
+		if showSpace {
+			name = i.Space + "/" + i.Name
+		}
+		if _, err := fmt.Fprintf(w, "%s  %s: %s\n", header, name, i.Problem); err != nil {
+			return err
+		}
+		header = ""
+	}
+	return nil
+}
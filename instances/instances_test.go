@@ -0,0 +1,161 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package instances_test
+
+import (
+	"bytes"
+	"errors"
+
+	"code.cloudfoundry.org/cli/plugin/models"
+	"code.cloudfoundry.org/cli/plugin/pluginfakes"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/instances"
+)
+
+var _ = Describe("Instances", func() {
+	var (
+		fakeCliConnection *pluginfakes.FakeCliConnection
+		responses         map[string]string
+		allSpaces         bool
+		result            []instances.Instance
+		err               error
+	)
+
+	BeforeEach(func() {
+		allSpaces = false
+		fakeCliConnection = &pluginfakes.FakeCliConnection{}
+		fakeCliConnection.GetCurrentSpaceReturns(plugin_models.Space{SpaceFields: plugin_models.SpaceFields{Guid: "space-guid", Name: "dev"}}, nil)
+		fakeCliConnection.GetCurrentOrgReturns(plugin_models.Organization{OrganizationFields: plugin_models.OrganizationFields{Guid: "org-guid", Name: "myorg"}}, nil)
+
+		responses = map[string]string{
+			"/v2/service_instances?q=space_guid%3Aspace-guid": `{"next_url": null, "resources": [
+				{"metadata": {"guid": "si-2"}, "entity": {"name": "mysql", "service_plan_guid": "plan-b", "space_guid": "space-guid"}},
+				{"metadata": {"guid": "si-1"}, "entity": {"name": "config", "service_plan_guid": "plan-a", "space_guid": "space-guid"}},
+				{"metadata": {"guid": "si-3"}, "entity": {"name": "config2", "service_plan_guid": "plan-a", "space_guid": "space-guid"}}
+			]}`,
+			"/v2/organizations/org-guid/spaces": `{"next_url": null, "resources": [
+				{"metadata": {"guid": "space-guid"}, "entity": {"name": "dev"}},
+				{"metadata": {"guid": "other-space-guid"}, "entity": {"name": "prod"}}
+			]}`,
+			"/v2/service_instances?q=organization_guid%3Aorg-guid": `{"next_url": null, "resources": [
+				{"metadata": {"guid": "si-4"}, "entity": {"name": "config", "service_plan_guid": "plan-a", "space_guid": "other-space-guid"}},
+				{"metadata": {"guid": "si-1"}, "entity": {"name": "config", "service_plan_guid": "plan-a", "space_guid": "space-guid"}}
+			]}`,
+			"/v2/service_plans/plan-a": `{"entity": {"name": "standard", "service_guid": "svc-a"}}`,
+			"/v2/service_plans/plan-b": `{"entity": {"name": "small", "service_guid": "svc-b"}}`,
+			"/v2/services/svc-a":       `{"entity": {"label": "p-config-server", "extra": "{\"serviceInstanceLogsEndpoint\":\"https://logs.example.com/logs/\"}"}}`,
+			"/v2/services/svc-b":       `{"entity": {"label": "p-mysql", "extra": "{\"documentationUrl\":\"http://docs\"}"}}`,
+		}
+
+		fakeCliConnection.CliCommandWithoutTerminalOutputStub = func(args ...string) ([]string, error) {
+			if response, ok := responses[args[1]]; ok {
+				return []string{response}, nil
+			}
+			return nil, errors.New("unexpected path " + args[1])
+		}
+	})
+
+	JustBeforeEach(func() {
+		result, err = instances.List(fakeCliConnection, allSpaces)
+	})
+
+	It("should list the instances in the targeted space sorted by name", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal([]instances.Instance{
+			{Name: "config", Guid: "si-1", Space: "dev", Offering: "p-config-server", Plan: "standard", LogsSupported: true, EndpointHost: "logs.example.com"},
+			{Name: "config2", Guid: "si-3", Space: "dev", Offering: "p-config-server", Plan: "standard", LogsSupported: true, EndpointHost: "logs.example.com"},
+			{Name: "mysql", Guid: "si-2", Space: "dev", Offering: "p-mysql", Plan: "small",
+				Problem: "/v2/services did not contain a service instance logs endpoint: maybe the broker version is too old"},
+		}))
+	})
+
+	It("should look up each plan and offering only once", func() {
+		Expect(fakeCliConnection.CliCommandWithoutTerminalOutputCallCount()).To(Equal(5))
+	})
+
+	Context("when listing all spaces in the org", func() {
+		BeforeEach(func() {
+			allSpaces = true
+		})
+
+		It("should list the instances in every space sorted by space and name", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result).To(HaveLen(2))
+			Expect(result[0].Space).To(Equal("dev"))
+			Expect(result[1].Space).To(Equal("prod"))
+			Expect(result[1].Guid).To(Equal("si-4"))
+		})
+	})
+
+	Context("when no space is targeted", func() {
+		BeforeEach(func() {
+			fakeCliConnection.GetCurrentSpaceReturns(plugin_models.Space{}, nil)
+		})
+
+		It("should return a suitable error", func() {
+			Expect(err).To(MatchError("No space targeted. Use 'cf target -s SPACE' to target a space."))
+		})
+	})
+
+	Context("when a plan is not visible", func() {
+		BeforeEach(func() {
+			responses["/v2/service_plans/plan-b"] = `{"code": 10003, "description": "You are not authorized to perform the requested action", "error_code": "CF-NotAuthorized"}`
+		})
+
+		It("should still list the instance", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result[2].Name).To(Equal("mysql"))
+			Expect(result[2].LogsSupported).To(BeFalse())
+			Expect(result[2].Problem).To(ContainSubstring("CF-NotAuthorized"))
+		})
+	})
+
+	Describe("Write", func() {
+		It("should print a table", func() {
+			Expect(err).NotTo(HaveOccurred())
+			var buffer bytes.Buffer
+			Expect(instances.Write(&buffer, result, false)).To(Succeed())
+			Expect(buffer.String()).To(Equal(
+				"name      service           plan       logs   endpoint\n" +
+					"config    p-config-server   standard   yes    logs.example.com\n" +
+					"config2   p-config-server   standard   yes    logs.example.com\n" +
+					"mysql     p-mysql           small      no     \n" +
+					"\n" +
+					"Notes:\n" +
+					"  mysql: /v2/services did not contain a service instance logs endpoint: maybe the broker version is too old\n"))
+		})
+
+		It("should qualify the notes with the space when requested", func() {
+			var buffer bytes.Buffer
+			Expect(instances.Write(&buffer, result[2:], true)).To(Succeed())
+			Expect(buffer.String()).To(HaveSuffix("Notes:\n  dev/mysql: /v2/services did not contain a service instance logs endpoint: maybe the broker version is too old\n"))
+		})
+
+		It("should include the space when requested", func() {
+			var buffer bytes.Buffer
+			Expect(instances.Write(&buffer, result[:1], true)).To(Succeed())
+			Expect(buffer.String()).To(HavePrefix("space   name"))
+		})
+
+		It("should report when there are no instances", func() {
+			var buffer bytes.Buffer
+			Expect(instances.Write(&buffer, nil, false)).To(Succeed())
+			Expect(buffer.String()).To(Equal("No service instances found.\n"))
+		})
+	})
+})
@@ -0,0 +1,104 @@
+package main_test
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"testing"
+
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/sink"
+)
+
+const (
+	benchmarkServerAddress = "127.0.0.1:8889"
+	benchmarkEndpointUrl   = "ws://" + benchmarkServerAddress
+	benchmarkLogEntries    = 10000
+)
+
+// BenchmarkRecentLogs measures the rate at which recent logs are obtained from the test server, sorted and written
+// as text.
+func BenchmarkRecentLogs(b *testing.B) {
+	startBenchmarkServer(b)
+	logClient := logclient.NewLogClientBuilder().Endpoint(benchmarkEndpointUrl).Build()
+	s := sink.NewBufferedWriterSink(io.Discard, nil)
+	defer s.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := logClient.StreamRecentLogs(context.Background(), serviceGuid, oauthToken, s.Write); err != nil {
+			b.Fatal(err)
+		}
+	}
+	if err := s.Flush(); err != nil {
+		b.Fatal(err)
+	}
+	reportRecordRate(b)
+}
+
+// BenchmarkTailingLogs measures the rate at which logs are tailed from the test server and written as text.
+func BenchmarkTailingLogs(b *testing.B) {
+	startBenchmarkServer(b)
+	s := sink.NewBufferedWriterSink(io.Discard, nil)
+	defer s.Close()
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		// Cancelling closes the consumer, so each iteration needs its own log client.
+		logClient := logclient.NewLogClientBuilder().Endpoint(benchmarkEndpointUrl).Build()
+		ctx, cancel := context.WithCancel(context.Background())
+		records, errs := logClient.TailingLogs(ctx, serviceGuid, oauthToken)
+		received := 0
+		for records != nil || errs != nil {
+			select {
+			case record, ok := <-records:
+				if !ok {
+					records = nil
+					continue
+				}
+				if err := s.Write(record); err != nil {
+					b.Fatal(err)
+				}
+				// The test server holds the connection open once it has sent every log entry.
+				if received++; received == benchmarkLogEntries {
+					cancel()
+				}
+			case err, ok := <-errs:
+				if !ok {
+					errs = nil
+					continue
+				}
+				if ctx.Err() == nil {
+					b.Fatal(err)
+				}
+			}
+		}
+		cancel()
+		if received < benchmarkLogEntries {
+			b.Fatalf("Received %d log entries, expected %d", received, benchmarkLogEntries)
+		}
+	}
+	if err := s.Flush(); err != nil {
+		b.Fatal(err)
+	}
+	reportRecordRate(b)
+}
+
+// startBenchmarkServer starts a test server which returns benchmarkLogEntries log entries, newest first, and stops
+// it when the benchmark ends.
+func startBenchmarkServer(b *testing.B) {
+	b.Helper()
+	server, _, err := startTestServer("-num", strconv.Itoa(benchmarkLogEntries), "-addr", benchmarkServerAddress)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() {
+		stopTestServer(server)
+	})
+}
+
+func reportRecordRate(b *testing.B) {
+	b.ReportMetric(float64(b.N*benchmarkLogEntries)/b.Elapsed().Seconds(), "records/s")
+}
@@ -1,12 +1,11 @@
 package main_test
 
 import (
-	"bufio"
+	"context"
 	"fmt"
 	"os/exec"
 	"strconv"
 	"strings"
-	"syscall"
 	"time"
 
 	. "github.com/onsi/ginkgo/v2"
@@ -27,51 +26,19 @@ const (
 var (
 	testServer *exec.Cmd
 	logClient  logclient.LogClient
-	logs       []string
+	logs       []logclient.LogRecord
 	err        error
 )
 
 var _ = BeforeSuite(func() {
-	testServer = exec.Command("go")
-	testServer.SysProcAttr = &syscall.SysProcAttr{Setpgid: true} // required for successful termination of server later
-	testServer.Args = append(testServer.Args, "run", "testserver.go")
-	testServer.Args = append(testServer.Args, "-num", strconv.Itoa(requestedNumberOfLogEntries))
-	testServer.Args = append(testServer.Args, "-oldlast")
-	testServer.Args = append(testServer.Args, "-addr", testServerAddress)
-
-	stdout, err := testServer.StdoutPipe()
-	Expect(err).NotTo(HaveOccurred())
-
-	err = testServer.Start()
+	var announcement string
+	testServer, announcement, err = startTestServer("-num", strconv.Itoa(requestedNumberOfLogEntries), "-oldlast", "-addr", testServerAddress)
 	Expect(err).NotTo(HaveOccurred())
-
-	// The test server does not start immediately but, rather than have a fixed sleep, block for up to a maximum
-	// of 10 seconds until the test server writes its startup status message out.
-	c1 := make(chan string, 1)
-
-	go func() {
-		r := bufio.NewReader(stdout)
-		line, _, err := r.ReadLine()
-		Expect(err).NotTo(HaveOccurred())
-		c1 <- string(line)
-	}()
-
-	select {
-	case line := <-c1:
-		fmt.Printf("%s\n", line)
-	case <-time.After(time.Second * 10):
-		Fail("Timed out waiting for test server to start")
-	}
+	fmt.Printf("%s\n", announcement)
 })
 
 var _ = AfterSuite(func() {
-	// testServer.Process.Kill() does not kill the test server as expected but the following code
-	// will kill the test server process. See https://groups.google.com/forum/#!topic/Golang-Nuts/XoQ3RhFBJl8
-	processGroupId, err := syscall.Getpgid(testServer.Process.Pid)
-	Expect(err).NotTo(HaveOccurred())
-
-	err = syscall.Kill(-processGroupId, 15) // note the minus sign
-	Expect(err).NotTo(HaveOccurred())
+	Expect(stopTestServer(testServer)).To(Succeed())
 })
 
 var _ = Describe("Logclient integration test", func() {
@@ -82,15 +49,15 @@ var _ = Describe("Logclient integration test", func() {
 
 	Describe("Verify Logclient interaction with service instance logs endpoint", func() {
 		JustBeforeEach(func() {
-			logs, err = logClient.RecentLogs(serviceGuid, oauthToken)
+			logs, err = logClient.RecentLogs(context.Background(), serviceGuid, oauthToken)
 			Expect(err).NotTo(HaveOccurred())
 		})
 
 		Context("when recent logs are requested", func() {
 			It("should have sorted log entries by timestamp in ascending order (most recent last)", func() {
 				for i := 0; i < len(logs)-1; i++ {
-					currentTimestamp := getUnixTimestampFromLogEntry(logs[i])
-					nextTimestamp := getUnixTimestampFromLogEntry(logs[i+1])
+					currentTimestamp := getUnixTimestampFromLogEntry(logs[i].String())
+					nextTimestamp := getUnixTimestampFromLogEntry(logs[i+1].String())
 					Expect(currentTimestamp).To(BeNumerically("<", nextTimestamp))
 				}
 			})
@@ -98,6 +65,16 @@ var _ = Describe("Logclient integration test", func() {
 	})
 })
 
+var _ = Describe("Logs service info integration test", func() {
+	It("should obtain the capabilities of the logs service", func() {
+		info, err := logclient.NewLogClientBuilder().Endpoint(endpointUrl).Info(context.Background(), oauthToken)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.SupportsProtocol(logclient.ProtocolVersion)).To(BeTrue())
+		Expect(info.Backends).To(Equal([]string{logclient.BackendRecent, logclient.BackendStream}))
+		Expect(info.MaxRecentWindow).To(Equal(24 * time.Hour))
+	})
+})
+
 func getUnixTimestampFromLogEntry(logMessage string) int64 {
 	timestampString := strings.Split(logMessage, " ")[0]
 	t, err := time.Parse(logTimestampFormat, timestampString)
@@ -0,0 +1,61 @@
+package main_test
+
+import (
+	"bufio"
+	"errors"
+	"os/exec"
+	"syscall"
+	"time"
+)
+
+// startTestServer runs the test server with the given arguments. Rather than have a fixed sleep, it blocks for up
+// to a maximum of 10 seconds until the test server writes its startup status message out, which it returns.
+func startTestServer(args ...string) (*exec.Cmd, string, error) {
+	server := exec.Command("go", append([]string{"run", "testserver.go"}, args...)...)
+	server.SysProcAttr = &syscall.SysProcAttr{Setpgid: true} // required for successful termination of server later
+
+	stdout, err := server.StdoutPipe()
+	if err != nil {
+		return nil, "", err
+	}
+	if err := server.Start(); err != nil {
+		return nil, "", err
+	}
+
+	type announcement struct {
+		line string
+		err  error
+	}
+	started := make(chan announcement, 1)
+	go func() {
+		line, _, err := bufio.NewReader(stdout).ReadLine()
+		started <- announcement{line: string(line), err: err}
+	}()
+
+	select {
+	case a := <-started:
+		if a.err != nil {
+			stopTestServer(server)
+			return nil, "", a.err
+		}
+		return server, a.line, nil
+	case <-time.After(time.Second * 10):
+		stopTestServer(server)
+		return nil, "", errors.New("Timed out waiting for test server to start")
+	}
+}
+
+// stopTestServer stops a test server started by startTestServer.
+func stopTestServer(server *exec.Cmd) error {
+	// server.Process.Kill() does not kill the test server as expected but the following code
+	// will kill the test server process. See https://groups.google.com/forum/#!topic/Golang-Nuts/XoQ3RhFBJl8
+	processGroupId, err := syscall.Getpgid(server.Process.Pid)
+	if err != nil {
+		return err
+	}
+	if err := syscall.Kill(-processGroupId, 15); err != nil { // note the minus sign
+		return err
+	}
+	server.Wait()
+	return nil
+}
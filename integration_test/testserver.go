@@ -6,12 +6,14 @@ import (
 	"io"
 	"log"
 	"mime/multipart"
+	"net"
 	"net/http"
 	"time"
 
 	"github.com/cloudfoundry/dropsonde/envelope_sender"
 	"github.com/cloudfoundry/sonde-go/events"
 	"github.com/gogo/protobuf/proto"
+	"github.com/gorilla/websocket"
 )
 
 var (
@@ -104,6 +106,45 @@ func dumpServiceLogs(rw http.ResponseWriter, r *http.Request) {
 	}
 }
 
+var upgrader = websocket.Upgrader{}
+
+// streamServiceLogs sends log entries over a websocket, as when logs are tailed, and then holds the connection open
+// until the client closes it, so that the client does not reconnect.
+func streamServiceLogs(rw http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(rw, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	var i int64
+	for i = 0; i < numberOfLogEntriesReturned; i++ {
+		env := &events.Envelope{
+			EventType: events.Envelope_LogMessage.Enum(),
+			Origin:    proto.String("origin"),
+			LogMessage: makeLogMessage("appID",
+				fmt.Sprintf("This is log message %d", i),
+				"sourceType",
+				"sourceInstance",
+				events.LogMessage_OUT,
+				0),
+		}
+		data, err := proto.Marshal(env)
+		if err != nil {
+			panic(fmt.Sprintf("Error marshalling envelope: %s", err.Error()))
+		}
+		if err := conn.WriteMessage(websocket.BinaryMessage, data); err != nil {
+			return
+		}
+	}
+
+	for {
+		if _, _, err := conn.ReadMessage(); err != nil {
+			return
+		}
+	}
+}
+
 func makeAscendingGenerator() func() int64 {
 	i := int64(0)
 	return func() (result int64) {
@@ -131,6 +172,12 @@ func apiInfo(rw http.ResponseWriter, r *http.Request) {
 	io.WriteString(rw, `{ "api_version": "2.75.0", "app_ssh_endpoint": "0.0.0.0:2222", "app_ssh_host_key_fingerprint": "9f:ae:12:42:19:33:6e:cc:5b:5b:44:af:13:a1:04:22", "app_ssh_oauth_client": "ssh-proxy", "authorization_endpoint": "http://0.0.0.0:8888", "build": "", "description": "fake api for integration testing purposes", "doppler_logging_endpoint": "wss://0.0.0.0:443", "logging_endpoint": "wss://0.0.0.0:443", "min_cli_version": "6.22.0", "min_recommended_cli_version": "6.23.0", "name": "integration", "routing_endpoint": "https://0.0.0.0:8888/routing", "support": "https://support.pivotal.io", "token_endpoint": "http://0.0.0.0:8888", "version": 0}`)
 }
 
+// noinspection GoUnusedParameter
+func logsServiceInfo(rw http.ResponseWriter, r *http.Request) {
+	rw.Header().Set("Content-Type", "application/json")
+	io.WriteString(rw, `{"protocol_versions": [1], "backends": ["recent", "stream"], "filters": [], "max_recent_window": "24h"}`)
+}
+
 // noinspection GoUnusedParameter
 func login(rw http.ResponseWriter, r *http.Request) {
 	rw.Header().Set("Content-Type", "application/json;charset=utf-8")
@@ -144,6 +191,35 @@ func login(rw http.ResponseWriter, r *http.Request) {
 }`)
 }
 
+const (
+	testClientID     = "ci-client"
+	testClientSecret = "ci-secret"
+)
+
+// token grants tokens. The client credentials grant requires the test client's credentials. Other grants are
+// treated as logins.
+func token(rw http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "client_credentials" {
+		login(rw, r)
+		return
+	}
+
+	rw.Header().Set("Content-Type", "application/json;charset=utf-8")
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok || clientID != testClientID || clientSecret != testClientSecret {
+		rw.WriteHeader(http.StatusUnauthorized)
+		io.WriteString(rw, `{"error": "unauthorized", "error_description": "Bad credentials"}`)
+		return
+	}
+	io.WriteString(rw, `{
+  "access_token": "eyJhbGciOiJub25lIn0.eyJjbGllbnRfaWQiOiJjaS1jbGllbnQiLCJncmFudF90eXBlIjoiY2xpZW50X2NyZWRlbnRpYWxzIn0.",
+  "expires_in": 43199,
+  "jti": "5d4b5a4a8f6d4b0c9a1f3e2d1c0b9a88",
+  "scope": "cloud_controller.read",
+  "token_type": "bearer"
+}`)
+}
+
 // noinspection GoUnusedParameter
 func servicesInfo(rw http.ResponseWriter, r *http.Request) {
 	rw.Header().Set("Content-Type", "application/json;charset=utf-8")
@@ -437,7 +513,7 @@ func main() {
 		fmt.Printf(`Usage: testserver [options]
 
 Starts a simple test log server that will return a number of recent log
-entries for a fake SCS service.
+entries, or stream them over a websocket, for a fake SCS service.
 
 `)
 		flag.PrintDefaults()
@@ -453,11 +529,17 @@ entries for a fake SCS service.
 	writeOldestMessagesLast = *oldLastPtr
 
 	log.SetFlags(0)
+
+	// Listen before announcing startup so that clients waiting for the announcement can connect immediately.
+	listener, err := net.Listen("tcp", *addrPtr)
+	if err != nil {
+		log.Fatal(err)
+	}
 	fmt.Printf("Server starting on %s\n", *addrPtr)
 
 	http.HandleFunc("/v2/info", apiInfo)
 	http.HandleFunc("/login", login)
-	http.HandleFunc("/oauth/token", login)
+	http.HandleFunc("/oauth/token", token)
 	http.HandleFunc("/v2/organizations", orgsInfo)
 	http.HandleFunc("/v2/spaces", spacesInfo)
 	http.HandleFunc("/v2/spaces/test-space-guid/summary", servicesSummary)
@@ -465,8 +547,10 @@ entries for a fake SCS service.
 	http.HandleFunc("/v2/spaces/test-space-guid/service_instances", serviceInstances)
 	http.HandleFunc("/v2/services/test-service-guid", testServiceInstanceInfo)
 	http.HandleFunc("/logs/test-service-instance-guid/recentlogs", dumpServiceLogs)
+	http.HandleFunc("/logs/test-service-instance-guid/stream", streamServiceLogs)
+	http.HandleFunc("/.well-known/service-instance-logs", logsServiceInfo)
 
-	if err := http.ListenAndServe(*addrPtr, nil); err != nil {
+	if err := http.Serve(listener, nil); err != nil {
 		log.Fatal(err)
 	}
 }
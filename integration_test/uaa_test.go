@@ -0,0 +1,33 @@
+package main_test
+
+import (
+	"context"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/standalone"
+)
+
+const uaaEndpoint = "http://" + testServerAddress
+
+var _ = Describe("UAA client credentials integration test", func() {
+	It("should obtain a token with which to retrieve recent logs", func() {
+		uaa := standalone.NewUAA(uaaEndpoint, "ci-client", "ci-secret", nil).ClientCredentials()
+		token, err := uaa.AccessToken(context.Background())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(token).NotTo(BeEmpty())
+
+		logClient := logclient.NewLogClientBuilder().Endpoint(endpointUrl).Build()
+		logs, err := logClient.RecentLogs(context.Background(), serviceGuid, token)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(logs).To(HaveLen(requestedNumberOfLogEntries))
+	})
+
+	It("should report rejected client credentials", func() {
+		uaa := standalone.NewUAA(uaaEndpoint, "ci-client", "wrong-secret", http.DefaultClient).ClientCredentials()
+		_, err := uaa.AccessToken(context.Background())
+		Expect(err).To(MatchError("Cannot authenticate as UAA client ci-client: Bad credentials (unauthorized)"))
+	})
+})
@@ -0,0 +1,97 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package logclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+
+	noaa_errors "github.com/cloudfoundry/noaa/errors"
+)
+
+// StatusError reports that the logs service responded with an unsuccessful HTTP status.
+type StatusError struct {
+	StatusCode int
+	Err        error
+}
+
+func (e *StatusError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *StatusError) Unwrap() error {
+	return e.Err
+}
+
+// TLSError reports that the certificate presented by the logs service could not be verified.
+type TLSError struct {
+	Err error
+}
+
+func (e *TLSError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *TLSError) Unwrap() error {
+	return e.Err
+}
+
+// ConnectionError reports that the logs service could not be reached.
+type ConnectionError struct {
+	Err error
+}
+
+func (e *ConnectionError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ConnectionError) Unwrap() error {
+	return e.Err
+}
+
+// classifyError wraps an error from the logs service, or from the noaa consumer, in a StatusError, TLSError or
+// ConnectionError where it is recognised, and otherwise returns it unchanged, including nil. The noaa consumer
+// reports websocket dial failures only as text, so the text is inspected too.
+func classifyError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var unauthorized *noaa_errors.UnauthorizedError
+	var unknownAuthority x509.UnknownAuthorityError
+	var invalid x509.CertificateInvalidError
+	var hostname x509.HostnameError
+	var verification *tls.CertificateVerificationError
+	var opErr *net.OpError
+	var dnsErr *net.DNSError
+	message := err.Error()
+	switch {
+	case errors.As(err, new(*StatusError)), errors.As(err, new(*TLSError)), errors.As(err, new(*ConnectionError)):
+		return err
+	case errors.As(err, &unauthorized), strings.Contains(message, "Unauthorized error:"):
+		return &StatusError{StatusCode: http.StatusUnauthorized, Err: err}
+	case errors.As(err, &unknownAuthority), errors.As(err, &invalid), errors.As(err, &hostname), errors.As(err, &verification),
+		strings.Contains(message, "x509:"):
+		return &TLSError{Err: err}
+	case errors.As(err, &opErr), errors.As(err, &dnsErr), strings.Contains(message, "dial tcp"):
+		return &ConnectionError{Err: err}
+	}
+	return err
+}
@@ -0,0 +1,166 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package logclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// InfoPath is the well-known path, on the host of a service instance logs endpoint, of the document in which the
+// logs service describes its capabilities.
+const InfoPath = "/.well-known/service-instance-logs"
+
+// ProtocolVersion is the version of the protocol for obtaining recent and tailed logs which this plugin implements.
+const ProtocolVersion = 1
+
+// Backends through which a logs service may serve logs.
+const (
+	BackendRecent = "recent"
+	BackendStream = "stream"
+)
+
+// FilterField is the server-side filter which selects JSON log messages by field, like the --field flag.
+const FilterField = "field"
+
+const infoTimeout = 10 * time.Second
+
+// Info describes the capabilities of a logs service: the protocol versions it implements, the backends through
+// which it serves logs, the filters it can apply before sending log records, the longest period covered by recent
+// logs and whether it sends recent logs in timestamp order. A logs service which does not publish an info document is
+// described by the zero value, which implements ProtocolVersion, serves recent and streamed logs, applies no
+// filters, has no stated recent window and sends recent logs in no particular order.
+type Info struct {
+	ProtocolVersions []int         `json:"protocol_versions,omitempty"`
+	Backends         []string      `json:"backends,omitempty"`
+	Filters          []string      `json:"filters,omitempty"`
+	MaxRecentWindow  time.Duration `json:"max_recent_window,omitempty"`
+	RecentOrdered    bool          `json:"recent_ordered,omitempty"`
+}
+
+type infoDocument struct {
+	ProtocolVersions []int    `json:"protocol_versions"`
+	Backends         []string `json:"backends"`
+	Filters          []string `json:"filters"`
+	MaxRecentWindow  string   `json:"max_recent_window"`
+	RecentOrdered    bool     `json:"recent_ordered"`
+}
+
+// SupportsProtocol reports whether the logs service implements the given protocol version.
+func (i Info) SupportsProtocol(version int) bool {
+	if len(i.ProtocolVersions) == 0 {
+		return version == ProtocolVersion
+	}
+	for _, v := range i.ProtocolVersions {
+		if v == version {
+			return true
+		}
+	}
+	return false
+}
+
+// SupportsBackend reports whether the logs service serves logs through the given backend.
+func (i Info) SupportsBackend(backend string) bool {
+	if len(i.Backends) == 0 {
+		return backend == BackendRecent || backend == BackendStream
+	}
+	return contains(i.Backends, backend)
+}
+
+// SupportsFilter reports whether the logs service can apply the given filter before sending log records.
+func (i Info) SupportsFilter(filter string) bool {
+	return contains(i.Filters, filter)
+}
+
+// InfoURL returns the URL of the info document of the logs service with the given endpoint, which may be the
+// http(s) URL used for recent logs or the websocket URL used for tailing.
+func InfoURL(endpoint *url.URL) string {
+	scheme := "https"
+	if endpoint.Scheme == "ws" || endpoint.Scheme == "http" {
+		scheme = "http"
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, endpoint.Host, InfoPath)
+}
+
+// FetchInfo obtains the info document of the logs service with the given endpoint. If the logs service does not
+// publish an info document, the zero Info is returned.
+func FetchInfo(ctx context.Context, client *http.Client, endpoint string, authToken string) (Info, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return Info{}, err
+	}
+	infoURL := InfoURL(u)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, infoURL, nil)
+	if err != nil {
+		return Info{}, err
+	}
+	req.Header.Set("Authorization", "bearer "+authToken)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return Info{}, classifyError(err)
+	}
+	defer resp.Body.Close()
+
+	// Older logs services do not publish an info document and may reject requests for unknown paths.
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound, http.StatusMethodNotAllowed, http.StatusNotImplemented:
+		return Info{}, nil
+	default:
+		return Info{}, &StatusError{StatusCode: resp.StatusCode, Err: fmt.Errorf("GET %s returned %s", infoURL, resp.Status)}
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Info{}, err
+	}
+	var doc infoDocument
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return Info{}, fmt.Errorf("%s contained invalid JSON: %s", infoURL, err)
+	}
+
+	info := Info{
+		ProtocolVersions: doc.ProtocolVersions,
+		Backends:         doc.Backends,
+		Filters:          doc.Filters,
+		RecentOrdered:    doc.RecentOrdered,
+	}
+	if doc.MaxRecentWindow != "" {
+		info.MaxRecentWindow, err = time.ParseDuration(doc.MaxRecentWindow)
+		if err != nil || info.MaxRecentWindow < 0 {
+			return Info{}, fmt.Errorf("%s contained an invalid max_recent_window %q", infoURL, doc.MaxRecentWindow)
+		}
+	}
+	return info, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,138 @@
+package logclient_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+)
+
+var _ = Describe("Info", func() {
+	Describe("FetchInfo", func() {
+		var (
+			server        *httptest.Server
+			status        int
+			body          string
+			requestPath   string
+			authorization string
+			info          logclient.Info
+			err           error
+		)
+
+		BeforeEach(func() {
+			status = http.StatusOK
+			body = `{"protocol_versions":[1,2],"backends":["recent"],"filters":["field"],"max_recent_window":"24h","recent_ordered":true}`
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				requestPath = r.URL.Path
+				authorization = r.Header.Get("Authorization")
+				w.WriteHeader(status)
+				w.Write([]byte(body))
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		JustBeforeEach(func() {
+			endpoint := strings.Replace(server.URL, "http://", "ws://", 1)
+			info, err = logclient.FetchInfo(context.Background(), server.Client(), endpoint, "some-token")
+		})
+
+		It("should request the well-known info document with the access token", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(requestPath).To(Equal("/.well-known/service-instance-logs"))
+			Expect(authorization).To(Equal("bearer some-token"))
+		})
+
+		It("should parse the info document", func() {
+			Expect(info).To(Equal(logclient.Info{
+				ProtocolVersions: []int{1, 2},
+				Backends:         []string{"recent"},
+				Filters:          []string{"field"},
+				MaxRecentWindow:  24 * time.Hour,
+				RecentOrdered:    true,
+			}))
+		})
+
+		Context("when the logs service does not publish an info document", func() {
+			BeforeEach(func() {
+				status = http.StatusNotFound
+			})
+
+			It("should return the zero info", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(info).To(Equal(logclient.Info{}))
+			})
+		})
+
+		Context("when the request fails", func() {
+			BeforeEach(func() {
+				status = http.StatusInternalServerError
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(MatchError(ContainSubstring("/.well-known/service-instance-logs returned 500 Internal Server Error")))
+			})
+		})
+
+		Context("when the info document is not valid JSON", func() {
+			BeforeEach(func() {
+				body = "<html>"
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(MatchError(ContainSubstring("contained invalid JSON")))
+			})
+		})
+
+		Context("when the max recent window is not a duration", func() {
+			BeforeEach(func() {
+				body = `{"max_recent_window":"a day"}`
+			})
+
+			It("should return an error", func() {
+				Expect(err).To(MatchError(ContainSubstring(`contained an invalid max_recent_window "a day"`)))
+			})
+		})
+	})
+
+	Describe("InfoURL", func() {
+		DescribeTable("mapping endpoints to the info document",
+			func(endpoint string, expected string) {
+				u, err := url.Parse(endpoint)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(logclient.InfoURL(u)).To(Equal(expected))
+			},
+			Entry("https", "https://some.host/logs/", "https://some.host/.well-known/service-instance-logs"),
+			Entry("wss", "wss://some.host", "https://some.host/.well-known/service-instance-logs"),
+			Entry("ws", "ws://some.host:8080", "http://some.host:8080/.well-known/service-instance-logs"),
+		)
+	})
+
+	Describe("capabilities", func() {
+		It("should describe a logs service without an info document as supporting this plugin", func() {
+			info := logclient.Info{}
+			Expect(info.SupportsProtocol(logclient.ProtocolVersion)).To(BeTrue())
+			Expect(info.SupportsProtocol(logclient.ProtocolVersion + 1)).To(BeFalse())
+			Expect(info.SupportsBackend(logclient.BackendRecent)).To(BeTrue())
+			Expect(info.SupportsBackend(logclient.BackendStream)).To(BeTrue())
+			Expect(info.SupportsFilter(logclient.FilterField)).To(BeFalse())
+		})
+
+		It("should describe the capabilities advertised by an info document", func() {
+			info := logclient.Info{ProtocolVersions: []int{2}, Backends: []string{logclient.BackendStream}, Filters: []string{logclient.FilterField}}
+			Expect(info.SupportsProtocol(logclient.ProtocolVersion)).To(BeFalse())
+			Expect(info.SupportsProtocol(2)).To(BeTrue())
+			Expect(info.SupportsBackend(logclient.BackendRecent)).To(BeFalse())
+			Expect(info.SupportsBackend(logclient.BackendStream)).To(BeTrue())
+			Expect(info.SupportsFilter(logclient.FilterField)).To(BeTrue())
+		})
+	})
+})
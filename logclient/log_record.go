@@ -0,0 +1,90 @@
+package logclient
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/cloudfoundry/sonde-go/events"
+)
+
+// LogRecord is a single service instance log message, independent of the wire format used to deliver it.
+type LogRecord struct {
+	Timestamp      time.Time `json:"timestamp"`
+	SourceType     string    `json:"source_type"`
+	SourceInstance string    `json:"source_instance"`
+	MessageType    string    `json:"message_type"`
+	Message        string    `json:"message"`
+	// ServiceInstance names the service instance which produced the record when logs of several service instances
+	// are combined.
+	ServiceInstance string `json:"service_instance,omitempty"`
+	// Late is set when a tailed record arrived too late to be delivered in timestamp order.
+	Late bool `json:"late,omitempty"`
+	// Fields holds the parsed message when the message is a JSON object and JSON messages are being parsed.
+	Fields map[string]interface{} `json:"-"`
+	// Columns holds values extracted from Fields for display before the message.
+	Columns []string `json:"-"`
+}
+
+// NewLogRecord converts a log message received from the service instance logs endpoint into a LogRecord.
+func NewLogRecord(msg *events.LogMessage) LogRecord {
+	return LogRecord{
+		Timestamp:      convertTimestampEpochNanosToTime(msg),
+		SourceType:     msg.GetSourceType(),
+		SourceInstance: msg.GetSourceInstance(),
+		MessageType:    msg.GetMessageType().String(),
+		Message:        string(msg.GetMessage()),
+	}
+}
+
+// String renders the record in the plugin's standard single line format, preceded by the service instance name
+// if there is one. Late records are marked as such after the message type, which is followed by any columns.
+func (r LogRecord) String() string {
+	return string(r.AppendText(make([]byte, 0, 64+len(r.Message))))
+}
+
+// AppendText appends the record, rendered as by String, to the given buffer and returns the extended buffer. Only
+// growing the buffer allocates, so reusing a buffer renders records without garbage.
+func (r LogRecord) AppendText(dst []byte) []byte {
+	dst = r.Timestamp.In(CurrentTimezoneLocation).AppendFormat(dst, LogTimestampFormat)
+	dst = append(dst, ' ')
+	if r.ServiceInstance != "" {
+		dst = append(dst, r.ServiceInstance...)
+		dst = append(dst, ' ')
+	}
+	dst = append(dst, '[')
+	dst = append(dst, r.SourceType...)
+	dst = append(dst, '/')
+	dst = append(dst, r.SourceInstance...)
+	dst = append(dst, "] "...)
+	dst = append(dst, r.MessageType...)
+	if r.Late {
+		dst = append(dst, " (late)"...)
+	}
+	for _, column := range r.Columns {
+		dst = append(dst, ' ')
+		dst = append(dst, column...)
+	}
+	dst = append(dst, ' ')
+	return append(dst, r.Message...)
+}
+
+// MarshalJSON encodes the record as a JSON object. If the message has been parsed into fields, the message is
+// encoded as a nested object rather than as a string.
+func (r LogRecord) MarshalJSON() ([]byte, error) {
+	type plainRecord LogRecord
+	if r.Fields == nil {
+		return json.Marshal(plainRecord(r))
+	}
+	return json.Marshal(struct {
+		plainRecord
+		Message map[string]interface{} `json:"message"`
+	}{plainRecord(r), r.Fields})
+}
+
+func convertTimestampEpochNanosToTime(message *events.LogMessage) time.Time {
+	// The message timestamp appears to be epoch nanoseconds
+	timestamp := message.GetTimestamp()
+	secs := timestamp / 1000000000
+	nanos := timestamp - (1000000000 * secs)
+	return time.Unix(secs, nanos)
+}
@@ -0,0 +1,92 @@
+package logclient_test
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+)
+
+var _ = Describe("LogRecord", func() {
+	var record logclient.LogRecord
+
+	BeforeEach(func() {
+		record = logclient.LogRecord{
+			Timestamp:      time.Unix(1500000000, 0),
+			SourceType:     "ST",
+			SourceInstance: "SI",
+			MessageType:    "OUT",
+			Message:        "MESSAGE",
+		}
+	})
+
+	It("should render in the standard format", func() {
+		Expect(record.String()).To(Equal(time.Unix(1500000000, 0).In(logclient.CurrentTimezoneLocation).Format(logclient.LogTimestampFormat) + " [ST/SI] OUT MESSAGE"))
+	})
+
+	Context("when the record is labelled with a service instance", func() {
+		BeforeEach(func() {
+			record.ServiceInstance = "my-db"
+		})
+
+		It("should render the service instance name before the source", func() {
+			Expect(record.String()).To(HaveSuffix(" my-db [ST/SI] OUT MESSAGE"))
+		})
+	})
+
+	Context("when the record has columns", func() {
+		BeforeEach(func() {
+			record.Columns = []string{"error", "-"}
+		})
+
+		It("should render the columns before the message", func() {
+			Expect(record.String()).To(HaveSuffix(" [ST/SI] OUT error - MESSAGE"))
+		})
+	})
+
+	Describe("AppendText", func() {
+		It("should append the record as rendered by String", func() {
+			record.ServiceInstance = "my-db"
+			record.Columns = []string{"error"}
+			record.Late = true
+			Expect(string(record.AppendText([]byte("> ")))).To(Equal("> " + record.String()))
+		})
+
+		It("should not allocate when the buffer is large enough", func() {
+			buffer := make([]byte, 0, 256)
+			Expect(testing.AllocsPerRun(100, func() {
+				buffer = record.AppendText(buffer[:0])
+			})).To(BeZero())
+		})
+	})
+
+	Describe("JSON encoding", func() {
+		It("should encode the message as a string", func() {
+			Expect(json.Marshal(record)).To(MatchJSON(`{"timestamp": "` + record.Timestamp.Format(time.RFC3339Nano) + `", "source_type": "ST", "source_instance": "SI", "message_type": "OUT", "message": "MESSAGE"}`))
+		})
+
+		Context("when the message has been parsed into fields", func() {
+			BeforeEach(func() {
+				record.Message = `{"level": "error"}`
+				record.Fields = map[string]interface{}{"level": "error"}
+			})
+
+			It("should nest the fields as an object", func() {
+				Expect(json.Marshal(record)).To(MatchJSON(`{"timestamp": "` + record.Timestamp.Format(time.RFC3339Nano) + `", "source_type": "ST", "source_instance": "SI", "message_type": "OUT", "message": {"level": "error"}}`))
+			})
+		})
+	})
+
+	Context("when the record arrived late", func() {
+		BeforeEach(func() {
+			record.Late = true
+		})
+
+		It("should mark the record as late", func() {
+			Expect(record.String()).To(HaveSuffix(" [ST/SI] OUT (late) MESSAGE"))
+		})
+	})
+})
@@ -1,14 +1,16 @@
 package logclient
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
+	"net"
+	"net/http"
 	"net/url"
 	"time"
 
 	"os"
 
-	"github.com/cloudfoundry/noaa"
 	"github.com/cloudfoundry/noaa/consumer"
 	"github.com/cloudfoundry/sonde-go/events"
 )
@@ -17,9 +19,22 @@ const LogTimestampFormat = "2006-01-02T15:04:05.00-0700"
 
 var CurrentTimezoneLocation = time.Now().Location()
 
+// Requests to a logs endpoint give up if a connection cannot be established, or the response does not start, within
+// these timeouts. The duration of the whole response is not limited, since recent logs may take a while to send, but
+// a response which stalls for recentIdleTimeout is abandoned.
+const (
+	dialTimeout           = 30 * time.Second
+	responseHeaderTimeout = 60 * time.Second
+	idleConnTimeout       = 90 * time.Second
+	recentIdleTimeout     = 60 * time.Second
+)
+
 type logClientBuilder struct {
 	endpoint           string
 	insecureSkipVerify bool
+	filters            url.Values
+	recentOrdered      bool
+	tokenSource        TokenSource
 }
 
 func NewLogClientBuilder() *logClientBuilder {
@@ -28,9 +43,45 @@ func NewLogClientBuilder() *logClientBuilder {
 
 func (builder *logClientBuilder) Endpoint(url string) LogClientBuilder {
 	builder.endpoint = url
+	builder.filters = nil
+	builder.recentOrdered = false
 	return builder
 }
 
+func (builder *logClientBuilder) RecentOrdered(ordered bool) LogClientBuilder {
+	builder.recentOrdered = ordered
+	return builder
+}
+
+func (builder *logClientBuilder) Filters(filters url.Values) LogClientBuilder {
+	builder.filters = filters
+	return builder
+}
+
+func (builder *logClientBuilder) TokenSource(source TokenSource) LogClientBuilder {
+	builder.tokenSource = source
+	return builder
+}
+
+func (builder *logClientBuilder) Info(ctx context.Context, authToken string) (Info, error) {
+	client := builder.httpClient()
+	client.Timeout = infoTimeout
+	return FetchInfo(ctx, client, builder.endpoint, authToken)
+}
+
+func (builder *logClientBuilder) httpClient() *http.Client {
+	return &http.Client{
+		Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			DialContext:           (&net.Dialer{Timeout: dialTimeout}).DialContext,
+			TLSClientConfig:       &tls.Config{InsecureSkipVerify: builder.insecureSkipVerify},
+			TLSHandshakeTimeout:   dialTimeout,
+			ResponseHeaderTimeout: responseHeaderTimeout,
+			IdleConnTimeout:       idleConnTimeout,
+		},
+	}
+}
+
 func (builder *logClientBuilder) InsecureSkipVerify(skipVerify bool) LogClientBuilder {
 	builder.insecureSkipVerify = skipVerify
 	return builder
@@ -44,6 +95,9 @@ func (dp *debugPrinter) Print(title, dump string) {
 
 func (builder *logClientBuilder) Build() LogClient {
 	cons := consumer.New(builder.endpoint, &tls.Config{InsecureSkipVerify: builder.insecureSkipVerify}, nil)
+	if builder.tokenSource != nil {
+		cons.RefreshTokenFrom(builder.tokenSource)
+	}
 	return builder.BuildFromConsumer(cons)
 }
 
@@ -53,114 +107,157 @@ func (builder *logClientBuilder) BuildFromConsumer(cons Consumer) LogClient {
 		dbgPrinter := &debugPrinter{}
 		cons.SetDebugPrinter(dbgPrinter)
 	}
-	recentPathBuilder := func(trafficControllerUrl *url.URL, appGuid string, endpoint string) string {
-		scheme := "https"
-		if trafficControllerUrl.Scheme == "ws" {
-			scheme = "http"
-		}
-
-		return fmt.Sprintf("%s://%s/logs/%s/%s", scheme, trafficControllerUrl.Host, appGuid, endpoint)
+	query := ""
+	if len(builder.filters) > 0 {
+		query = "?" + builder.filters.Encode()
 	}
+	cons.SetStreamPathBuilder(func(appGuid string) string {
+		return StreamPath(appGuid) + query
+	})
 
-	cons.SetRecentPathBuilder(recentPathBuilder)
+	return &logClient{
+		endpoint:      builder.endpoint,
+		consumer:      cons,
+		httpClient:    builder.httpClient(),
+		query:         query,
+		recentOrdered: builder.recentOrdered,
+		runSize:       DefaultRecentRunSize,
+		idleTimeout:   recentIdleTimeout,
+		debug:         tracing,
+	}
+}
 
-	streamPathBuilder := func(appGuid string) string {
-		return fmt.Sprintf("/logs/%s/stream", appGuid)
+func recentPathBuilder(trafficControllerUrl *url.URL, appGuid string, endpoint string) string {
+	// The endpoint is a websocket URL when tailing but the catalog's http(s) URL when retrieving recent logs.
+	scheme := "https"
+	if trafficControllerUrl.Scheme == "ws" || trafficControllerUrl.Scheme == "http" {
+		scheme = "http"
 	}
 
-	cons.SetStreamPathBuilder(streamPathBuilder)
+	return fmt.Sprintf("%s://%s/logs/%s/%s", scheme, trafficControllerUrl.Host, appGuid, endpoint)
+}
 
-	return &logClient{
-		endpoint: builder.endpoint,
-		consumer: cons,
-		sorter:   &sorter{},
+// RecentLogsURL returns the URL from which the recent logs of the given service instance are obtained.
+func RecentLogsURL(trafficControllerUrl *url.URL, serviceGUID string) string {
+	return recentPathBuilder(trafficControllerUrl, serviceGUID, "recentlogs")
+}
+
+// StreamPath returns the path, relative to the websocket endpoint, from which the logs of the given service
+// instance are tailed.
+func StreamPath(serviceGUID string) string {
+	return fmt.Sprintf("/logs/%s/stream", serviceGUID)
+}
+
+// TokenSource returns a current access token, without a "bearer " prefix.
+type TokenSource func() (string, error)
+
+// RefreshAuthToken returns a current access token in the form the noaa consumer expects.
+func (source TokenSource) RefreshAuthToken() (string, error) {
+	token, err := source()
+	if err != nil {
+		return "", err
 	}
+	return "bearer " + token, nil
 }
 
+// LogClientBuilder builds log clients for a logs endpoint.
+//
+// Endpoint sets the endpoint and clears any filters and ordering. Filters sets the filters, named as in Info, which
+// the logs service applies before sending log records. RecentOrdered declares that the logs service sends recent logs
+// in timestamp order, so they need not be sorted. TokenSource sets a source of access tokens with which tailing
+// reconnects when the access token it was given has expired. Info obtains the info document of the logs service at
+// the endpoint.
 //go:generate counterfeiter -o logclientfakes/fake_log_client_builder.go . LogClientBuilder
 type LogClientBuilder interface {
 	Endpoint(url string) LogClientBuilder
 	InsecureSkipVerify(skipVerify bool) LogClientBuilder
+	Filters(filters url.Values) LogClientBuilder
+	RecentOrdered(ordered bool) LogClientBuilder
+	TokenSource(source TokenSource) LogClientBuilder
+	Info(ctx context.Context, authToken string) (Info, error)
 	Build() LogClient
 }
 
+// LogClient obtains the logs of service instances.
+//
+// TailingLogs streams log records and errors until the upstream stream ends or the context is cancelled. On
+// cancellation, the websocket connection is closed with a close frame and any records already received are still
+// delivered. Both channels are then closed, so callers must receive from both until they are closed.
+//
+// StreamRecentLogs calls the given function with each recent log record in timestamp order, as soon as the order is
+// known, and stops at the first error it returns. The response is decoded incrementally and, unless the logs service
+// sends records in timestamp order, sorted with a bounded external merge sort, so records beyond a fixed number are
+// held in temporary files rather than in memory. RecentLogs returns every recent log record in timestamp order.
+//
+// Both return the context's error if the context is cancelled before the recent logs are received.
 //go:generate counterfeiter -o logclientfakes/fake_log_client.go . LogClient
 type LogClient interface {
-	RecentLogs(serviceGUID string, authToken string) ([]string, error)
-	TailingLogs(serviceGUID string, authToken string) (<-chan string, <-chan error)
+	RecentLogs(ctx context.Context, serviceGUID string, authToken string) ([]LogRecord, error)
+	StreamRecentLogs(ctx context.Context, serviceGUID string, authToken string, each func(LogRecord) error) error
+	TailingLogs(ctx context.Context, serviceGUID string, authToken string) (<-chan LogRecord, <-chan error)
 }
 
 // Wrap interactions with NOAA consumer.consumer inside an interface whose behaviour can be faked in tests
 //go:generate counterfeiter -o logclientfakes/fake_consumer.go . Consumer
 type Consumer interface {
-	SetRecentPathBuilder(b consumer.RecentPathBuilder)
 	SetStreamPathBuilder(b consumer.StreamPathBuilder)
 	SetDebugPrinter(debugPrinter consumer.DebugPrinter)
-	RecentLogs(appGuid string, authToken string) ([]*events.LogMessage, error)
 	TailingLogs(appGuid, authToken string) (<-chan *events.LogMessage, <-chan error)
-}
-
-// Wrap interactions with noaa.SortRecent inside an interface whose behaviour can be faked in tests
-//go:generate counterfeiter -o logclientfakes/fake_sorter.go . Sorter
-type Sorter interface {
-	SortRecent(messages []*events.LogMessage) []*events.LogMessage
-}
-
-type sorter struct {
-}
-
-func (s *sorter) SortRecent(messages []*events.LogMessage) []*events.LogMessage {
-	return noaa.SortRecent(messages)
+	Close() error
 }
 
 type logClient struct {
-	endpoint string
-	consumer Consumer
-	sorter   Sorter
+	endpoint      string
+	consumer      Consumer
+	httpClient    *http.Client
+	query         string
+	recentOrdered bool
+	runSize       int
+	tempDir       string
+	idleTimeout   time.Duration
+	debug         bool
 }
 
-func (lc *logClient) RecentLogs(serviceGUID string, authToken string) ([]string, error) {
-	messages, err := lc.consumer.RecentLogs(serviceGUID, "bearer "+authToken)
-	if err != nil {
-		return nil, err
-	}
-
-	messages = lc.sorter.SortRecent(messages)
-
-	result := []string{}
-	for _, msg := range messages {
-		result = append(result, convertLogMessageToString(msg))
-	}
-
-	return result, nil
-}
-
-func (lc *logClient) TailingLogs(serviceGUID string, authToken string) (<-chan string, <-chan error) {
+func (lc *logClient) TailingLogs(ctx context.Context, serviceGUID string, authToken string) (<-chan LogRecord, <-chan error) {
 	msgChan, errorChan := lc.consumer.TailingLogs(serviceGUID, "bearer "+authToken)
-	strMsgChan := make(chan string)
+	recordChan := make(chan LogRecord)
+	errChan := make(chan error)
+	done := make(chan struct{})
 
+	// Closing the consumer sends a websocket close frame, after which the consumer closes its channels.
 	go func() {
-		for msg := range msgChan {
-			strMsgChan <- convertLogMessageToString(msg)
+		select {
+		case <-ctx.Done():
+			lc.consumer.Close()
+		case <-done:
 		}
 	}()
 
-	return strMsgChan, errorChan
-}
-
-func convertLogMessageToString(msg *events.LogMessage) string {
-	return fmt.Sprintf("%s [%s/%s] %s %s",
-		convertTimestampEpochNanosToString(msg),
-		msg.GetSourceType(),
-		msg.GetSourceInstance(),
-		msg.GetMessageType().String(),
-		string(msg.GetMessage()))
-}
+	go func() {
+		defer close(done)
+		defer close(errChan)
+		defer close(recordChan)
+		for msgChan != nil || errorChan != nil {
+			select {
+			case msg, ok := <-msgChan:
+				if !ok {
+					msgChan = nil
+					continue
+				}
+				recordChan <- NewLogRecord(msg)
+			case err, ok := <-errorChan:
+				if !ok {
+					errorChan = nil
+					continue
+				}
+				// The consumer sends a nil error when it is closed, such as when tailing is interrupted.
+				if err == nil {
+					continue
+				}
+				errChan <- classifyError(err)
+			}
+		}
+	}()
 
-func convertTimestampEpochNanosToString(message *events.LogMessage) string {
-	// The message timestamp appears to be epoch nanoseconds
-	timestamp := message.GetTimestamp()
-	secs := timestamp / 1000000000
-	nanos := timestamp - (1000000000 * secs)
-	return time.Unix(secs, nanos).In(CurrentTimezoneLocation).Format(LogTimestampFormat)
+	return recordChan, errChan
 }
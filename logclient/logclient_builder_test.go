@@ -15,11 +15,19 @@ var _ = Describe("LogclientBuilder", func() {
 	var (
 		fakeConsumer *logclientfakes.FakeConsumer
 		builder      logclient.LogClientBuilder
+		filters      url.Values
 	)
 
+	BeforeEach(func() {
+		filters = nil
+	})
+
 	JustBeforeEach(func() {
 		fakeConsumer = &logclientfakes.FakeConsumer{}
-		builder = logclient.NewLogClientBuilder()
+		builder = logclient.NewLogClientBuilder().Endpoint("wss://some.host")
+		if filters != nil {
+			builder = builder.Filters(filters)
+		}
 		if b, ok := builder.(logclient.BuildWithConsumer); ok {
 			b.BuildFromConsumer(fakeConsumer)
 		} else {
@@ -68,27 +76,23 @@ var _ = Describe("LogclientBuilder", func() {
 		})
 	})
 
-	Describe("recent path builder", func() {
-		var recentPathBuilder consumer.RecentPathBuilder
-
-		JustBeforeEach(func() {
-			recentPathBuilder = fakeConsumer.SetRecentPathBuilderArgsForCall(0)
-		})
-
-		It("should be set", func() {
-			Expect(fakeConsumer.SetRecentPathBuilderCallCount()).To(Equal(1))
+	Describe("RecentLogsURL", func() {
+		It("should correctly compute the recent logs URL for a ws traffic controller scheme", func() {
+			url, err := url.Parse("ws://some.host/a/path")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(logclient.RecentLogsURL(url, "appguid")).To(Equal("http://some.host/logs/appguid/recentlogs"))
 		})
 
-		It("should correctly compute the recent path for a ws traffic controller scheme", func() {
-			url, err := url.Parse("ws://some.host/a/path")
+		It("should correctly compute the recent logs URL for an http endpoint scheme", func() {
+			url, err := url.Parse("http://some.host/a/path")
 			Expect(err).NotTo(HaveOccurred())
-			Expect(recentPathBuilder(url, "appguid", "endpoint")).To(Equal("http://some.host/logs/appguid/endpoint"))
+			Expect(logclient.RecentLogsURL(url, "appguid")).To(Equal("http://some.host/logs/appguid/recentlogs"))
 		})
 
-		It("should correctly compute the recent path for a wss traffic controller scheme", func() {
+		It("should correctly compute the recent logs URL for a wss traffic controller scheme", func() {
 			url, err := url.Parse("wss://some.host/a/path")
 			Expect(err).NotTo(HaveOccurred())
-			Expect(recentPathBuilder(url, "appguid", "endpoint")).To(Equal("https://some.host/logs/appguid/endpoint"))
+			Expect(logclient.RecentLogsURL(url, "appguid")).To(Equal("https://some.host/logs/appguid/recentlogs"))
 		})
 	})
 
@@ -106,5 +110,26 @@ var _ = Describe("LogclientBuilder", func() {
 		It("should correctly compute the stream path", func() {
 			Expect(streamPathBuilder("appguid")).To(Equal("/logs/appguid/stream"))
 		})
+
+		Context("when filters are set", func() {
+			BeforeEach(func() {
+				filters = url.Values{"field": []string{"level=error", "user~=^a"}}
+			})
+
+			It("should pass the filters as query parameters", func() {
+				Expect(streamPathBuilder("appguid")).To(Equal("/logs/appguid/stream?field=level%3Derror&field=user~%3D%5Ea"))
+			})
+		})
+
+		Context("when the endpoint is set again after filters are set", func() {
+			BeforeEach(func() {
+				filters = url.Values{"field": []string{"level=error"}}
+			})
+
+			It("should clear the filters", func() {
+				builder.Endpoint("wss://other.host").(logclient.BuildWithConsumer).BuildFromConsumer(fakeConsumer)
+				Expect(fakeConsumer.SetStreamPathBuilderArgsForCall(1)("appguid")).To(Equal("/logs/appguid/stream"))
+			})
+		})
 	})
 })
@@ -1,11 +1,22 @@
 package logclient_test
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strconv"
+	"sync"
 	"time"
 
+	"github.com/cloudfoundry/noaa/consumer"
+	noaa_errors "github.com/cloudfoundry/noaa/errors"
 	"github.com/cloudfoundry/sonde-go/events"
+	"github.com/gogo/protobuf/proto"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
@@ -23,15 +34,16 @@ var _ = Describe("Logclient", func() {
 	var (
 		logClient        logclient.LogClient
 		fakeConsumer     *logclientfakes.FakeConsumer
-		fakeSorter       *logclientfakes.FakeSorter
 		testError        error
 		currentTimestamp int64
+		ctx              context.Context
+		cancel           context.CancelFunc
 	)
 
 	BeforeEach(func() {
 		testError = errors.New(errMessage)
+		ctx, cancel = context.WithCancel(context.Background())
 		fakeConsumer = &logclientfakes.FakeConsumer{}
-		fakeSorter = &logclientfakes.FakeSorter{}
 
 		builder := logclient.NewLogClientBuilder()
 		logClient = builder.InsecureSkipVerify(true).Endpoint(endpointUrl).Build()
@@ -45,76 +57,157 @@ var _ = Describe("Logclient", func() {
 
 	Describe("RecentLogs", func() {
 		var (
-			result              []string
+			server              *httptest.Server
+			mutex               sync.Mutex
+			requests            []*http.Request
+			status              int
+			contentType         string
+			messages            []*events.LogMessage
+			filters             url.Values
+			ordered             bool
+			runSize             int
+			tempDir             string
+			result              []logclient.LogRecord
 			err                 error
 			mostRecentTimestamp int64
 			olderTimestamp      int64
 			oldestTimestamp     int64
 		)
 
+		BeforeEach(func() {
+			requests = nil
+			status = http.StatusOK
+			contentType = ""
+			messages = nil
+			filters = nil
+			ordered = false
+			runSize = 0
+			tempDir = GinkgoT().TempDir()
+
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				mutex.Lock()
+				requests = append(requests, r)
+				mutex.Unlock()
+				if status != http.StatusOK {
+					w.WriteHeader(status)
+					w.Write([]byte("invalid token"))
+					return
+				}
+				writeRecentLogs(w, contentType, messages)
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
 		JustBeforeEach(func() {
-			result, err = logClient.RecentLogs(serviceGuid, authToken)
+			builder := logclient.NewLogClientBuilder().Endpoint(server.URL)
+			if filters != nil {
+				builder = builder.Filters(filters)
+			}
+			logClient = builder.RecentOrdered(ordered).Build()
+			if logClient, ok := logClient.(logclient.FieldSetter); ok {
+				if runSize > 0 {
+					logClient.SetRecentRunSize(runSize)
+				}
+				logClient.SetTempDir(tempDir)
+			} else {
+				Fail("logClient did not implement FieldSetter")
+			}
+
+			result, err = logClient.RecentLogs(ctx, serviceGuid, authToken)
+		})
+
+		It("should request the recent logs of the supplied service instance", func() {
+			Expect(requests).To(HaveLen(1))
+			Expect(requests[0].URL.Path).To(Equal("/logs/" + serviceGuid + "/recentlogs"))
+			Expect(requests[0].URL.RawQuery).To(BeEmpty())
 		})
 
-		Context("when request for recent logs from consumer returns an error", func() {
+		It("should use the supplied authToken", func() {
+			Expect(requests[0].Header.Get("Authorization")).To(Equal("bearer " + authToken))
+		})
+
+		Context("when filters are set", func() {
 			BeforeEach(func() {
-				fakeConsumer.RecentLogsReturns([]*events.LogMessage{}, testError)
+				filters = url.Values{"field": []string{"level=error"}}
 			})
 
-			It("should call the consumer RecentLogs function", func() {
-				Expect(fakeConsumer.RecentLogsCallCount()).To(Equal(1))
+			It("should pass the filters as query parameters", func() {
+				Expect(requests[0].URL.RawQuery).To(Equal("field=level%3Derror"))
 			})
+		})
 
-			It("should use the supplied serviceGUID and authToken for the consumer call", func() {
-				svcGuid, token := fakeConsumer.RecentLogsArgsForCall(0)
-				Expect(svcGuid).To(Equal(serviceGuid))
-				Expect(token).To(Equal("bearer " + authToken))
+		Context("when the request is unauthorized", func() {
+			BeforeEach(func() {
+				status = http.StatusUnauthorized
 			})
 
-			It("should propagate the error", func() {
-				Expect(err).To(MatchError(errMessage))
+			It("should return an unauthorized error", func() {
+				var statusErr *logclient.StatusError
+				Expect(errors.As(err, &statusErr)).To(BeTrue())
+				Expect(statusErr.StatusCode).To(Equal(http.StatusUnauthorized))
+				Expect(errors.As(err, new(*noaa_errors.UnauthorizedError))).To(BeTrue())
+				Expect(err).To(MatchError("Unauthorized error: invalid token"))
 			})
 		})
 
-		Context("when request for recent logs from consumer returns normally", func() {
-			var consumerRecentLogsResponse []*events.LogMessage
+		Context("when the request fails", func() {
+			BeforeEach(func() {
+				status = http.StatusInternalServerError
+			})
 
+			It("should return an error", func() {
+				var statusErr *logclient.StatusError
+				Expect(errors.As(err, &statusErr)).To(BeTrue())
+				Expect(statusErr.StatusCode).To(Equal(http.StatusInternalServerError))
+				Expect(errors.Is(err, consumer.ErrNotOK)).To(BeTrue())
+			})
+		})
+
+		Context("when the logs service cannot be reached", func() {
 			BeforeEach(func() {
-				if logClient, ok := logClient.(logclient.FieldSetter); ok {
-					logClient.SetSorter(fakeSorter)
-				} else {
-					Fail("logClient did not implement FieldSetter")
-				}
+				server.Close()
+			})
 
-				consumerRecentLogsResponse = []*events.LogMessage{}
-				fakeConsumer.RecentLogsReturns(consumerRecentLogsResponse, nil)
+			It("should return a connection error", func() {
+				Expect(err).To(BeAssignableToTypeOf(&logclient.ConnectionError{}))
+				Expect(err.Error()).To(HavePrefix("Error connecting to the service instance logs endpoint " + server.URL))
 			})
+		})
 
-			It("should call the consumer RecentLogs function", func() {
-				Expect(fakeConsumer.RecentLogsCallCount()).To(Equal(1))
+		Context("when the certificate of the logs service cannot be verified", func() {
+			BeforeEach(func() {
+				server.Close()
+				server = httptest.NewTLSServer(server.Config.Handler)
 			})
 
-			It("should use the supplied serviceGUID and authToken for the consumer call", func() {
-				svcGuid, token := fakeConsumer.RecentLogsArgsForCall(0)
-				Expect(svcGuid).To(Equal(serviceGuid))
-				Expect(token).To(Equal("bearer " + authToken))
+			It("should return a TLS error", func() {
+				Expect(err).To(BeAssignableToTypeOf(&logclient.TLSError{}))
+				Expect(err).To(MatchError(ContainSubstring("certificate")))
 			})
+		})
 
-			It("should call the sorter", func() {
-				Expect(fakeSorter.SortRecentCallCount()).To(Equal(1))
+		Context("when the response is not multipart", func() {
+			BeforeEach(func() {
+				contentType = "text/plain"
 			})
 
-			It("should pass the log messages received from the consumer to the sorter", func() {
-				messages := fakeSorter.SortRecentArgsForCall(0)
-				Expect(messages).To(Equal(consumerRecentLogsResponse))
+			It("should return an error", func() {
+				Expect(err).To(Equal(consumer.ErrBadResponse))
 			})
+		})
 
-			It("should return normally", func() {
+		Context("when the response contains no log messages", func() {
+			It("should return no records", func() {
 				Expect(err).NotTo(HaveOccurred())
+				Expect(result).To(BeEmpty())
 			})
 		})
 
-		Context("when request for recent logs from consumer returns normally and received log messages are out of time sequence", func() {
+		Context("when received log messages are out of time sequence", func() {
 			BeforeEach(func() {
 				currentTimestamp = time.Now().UnixNano()
 				mostRecentTimestamp = currentTimestamp
@@ -124,18 +217,7 @@ var _ = Describe("Logclient", func() {
 				lm1 := createLogMessage("RECENT", events.LogMessage_OUT, mostRecentTimestamp)
 				lm2 := createLogMessage("OLDER", events.LogMessage_OUT, olderTimestamp)
 				lm3 := createLogMessage("OLDEST", events.LogMessage_ERR, oldestTimestamp)
-
-				fakeConsumer.RecentLogsReturns([]*events.LogMessage{&lm1, &lm2, &lm3}, nil)
-			})
-
-			It("should call the consumer RecentLogs function", func() {
-				Expect(fakeConsumer.RecentLogsCallCount()).To(Equal(1))
-			})
-
-			It("should use the supplied serviceGUID and authToken for the consumer call", func() {
-				svcGuid, token := fakeConsumer.RecentLogsArgsForCall(0)
-				Expect(svcGuid).To(Equal(serviceGuid))
-				Expect(token).To(Equal("bearer " + authToken))
+				messages = []*events.LogMessage{&lm1, &lm2, &lm3}
 			})
 
 			It("should return normally", func() {
@@ -145,34 +227,36 @@ var _ = Describe("Logclient", func() {
 			It("should return correctly formatted messages sorted by timestamp with oldest first", func() {
 				Expect(len(result)).To(Equal(3))
 
-				Expect(result[0]).Should(Equal(fmt.Sprintf("%s [ST-OLDEST/SI-OLDEST] ERR MESSAGE-OLDEST",
+				Expect(result[0].String()).Should(Equal(fmt.Sprintf("%s [ST-OLDEST/SI-OLDEST] ERR MESSAGE-OLDEST",
 					formatUnixTimestamp(oldestTimestamp))))
-				Expect(result[1]).Should(Equal(fmt.Sprintf("%s [ST-OLDER/SI-OLDER] OUT MESSAGE-OLDER",
+				Expect(result[1].String()).Should(Equal(fmt.Sprintf("%s [ST-OLDER/SI-OLDER] OUT MESSAGE-OLDER",
 					formatUnixTimestamp(olderTimestamp))))
-				Expect(result[2]).Should(Equal(fmt.Sprintf("%s [ST-RECENT/SI-RECENT] OUT MESSAGE-RECENT",
+				Expect(result[2].String()).Should(Equal(fmt.Sprintf("%s [ST-RECENT/SI-RECENT] OUT MESSAGE-RECENT",
 					formatUnixTimestamp(mostRecentTimestamp))))
 			})
+
+			Context("when the logs service sends recent logs in timestamp order", func() {
+				BeforeEach(func() {
+					ordered = true
+				})
+
+				It("should return the messages in the order received", func() {
+					Expect(len(result)).To(Equal(3))
+					Expect(result[0].Message).To(Equal("MESSAGE-RECENT"))
+					Expect(result[1].Message).To(Equal("MESSAGE-OLDER"))
+					Expect(result[2].Message).To(Equal("MESSAGE-OLDEST"))
+				})
+			})
 		})
 
-		Context("when request for recent logs returns normally and received log messages all have same timestamp", func() {
+		Context("when received log messages all have same timestamp", func() {
 			BeforeEach(func() {
 				currentTimestamp = time.Now().UnixNano()
 
 				lm1 := createLogMessage("RECEIVED-FIRST", events.LogMessage_OUT, currentTimestamp)
 				lm2 := createLogMessage("RECEIVED-SECOND", events.LogMessage_OUT, currentTimestamp)
 				lm3 := createLogMessage("RECEIVED-THIRD", events.LogMessage_ERR, currentTimestamp)
-
-				fakeConsumer.RecentLogsReturns([]*events.LogMessage{&lm1, &lm2, &lm3}, nil)
-			})
-
-			It("should call the consumer RecentLogs function", func() {
-				Expect(fakeConsumer.RecentLogsCallCount()).To(Equal(1))
-			})
-
-			It("should use the supplied serviceGUID and authToken for the consumer call", func() {
-				svcGuid, token := fakeConsumer.RecentLogsArgsForCall(0)
-				Expect(svcGuid).To(Equal(serviceGuid))
-				Expect(token).To(Equal("bearer " + authToken))
+				messages = []*events.LogMessage{&lm1, &lm2, &lm3}
 			})
 
 			It("should return normally", func() {
@@ -182,37 +266,214 @@ var _ = Describe("Logclient", func() {
 			It("should return correctly formatted messages sorted by order received", func() {
 				Expect(len(result)).To(Equal(3))
 
-				Expect(result[0]).Should(Equal(fmt.Sprintf("%s [ST-RECEIVED-FIRST/SI-RECEIVED-FIRST] OUT MESSAGE-RECEIVED-FIRST",
+				Expect(result[0].String()).Should(Equal(fmt.Sprintf("%s [ST-RECEIVED-FIRST/SI-RECEIVED-FIRST] OUT MESSAGE-RECEIVED-FIRST",
 					formatUnixTimestamp(currentTimestamp))))
-				Expect(result[1]).Should(Equal(fmt.Sprintf("%s [ST-RECEIVED-SECOND/SI-RECEIVED-SECOND] OUT MESSAGE-RECEIVED-SECOND",
+				Expect(result[1].String()).Should(Equal(fmt.Sprintf("%s [ST-RECEIVED-SECOND/SI-RECEIVED-SECOND] OUT MESSAGE-RECEIVED-SECOND",
 					formatUnixTimestamp(currentTimestamp))))
-				Expect(result[2]).Should(Equal(fmt.Sprintf("%s [ST-RECEIVED-THIRD/SI-RECEIVED-THIRD] ERR MESSAGE-RECEIVED-THIRD",
+				Expect(result[2].String()).Should(Equal(fmt.Sprintf("%s [ST-RECEIVED-THIRD/SI-RECEIVED-THIRD] ERR MESSAGE-RECEIVED-THIRD",
 					formatUnixTimestamp(currentTimestamp))))
 			})
 		})
+
+		Context("when there are more log messages than are sorted in memory", func() {
+			BeforeEach(func() {
+				runSize = 3
+				currentTimestamp = time.Now().UnixNano()
+				// Enough messages to spill more runs than are merged at once.
+				for i := 0; i < 200; i++ {
+					// Pairs of messages share a timestamp, and timestamps descend.
+					lm := createLogMessage(strconv.Itoa(i), events.LogMessage_OUT, currentTimestamp-int64(i/2)*1e6)
+					messages = append(messages, &lm)
+				}
+			})
+
+			It("should return every message sorted by timestamp, keeping the order received for equal timestamps", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(result).To(HaveLen(200))
+				for i := 0; i < 100; i++ {
+					Expect(result[2*i].Message).To(Equal(fmt.Sprintf("MESSAGE-%d", 198-2*i)))
+					Expect(result[2*i+1].Message).To(Equal(fmt.Sprintf("MESSAGE-%d", 199-2*i)))
+				}
+			})
+
+			It("should remove its temporary files", func() {
+				Expect(os.ReadDir(tempDir)).To(BeEmpty())
+			})
+		})
+	})
+
+	Describe("StreamRecentLogs", func() {
+		var (
+			server  *httptest.Server
+			release chan struct{}
+		)
+
+		BeforeEach(func() {
+			release = make(chan struct{})
+			server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				defer GinkgoRecover()
+				lm1 := createLogMessage("1", events.LogMessage_OUT, 2)
+				lm2 := createLogMessage("2", events.LogMessage_OUT, 1)
+				mw := multipart.NewWriter(w)
+				w.Header().Set("Content-Type", "multipart/x-protobuf; boundary="+mw.Boundary())
+				writeLogMessagePart(mw, &lm1)
+				// The first part ends at the boundary which begins the second.
+				part, _ := mw.CreatePart(nil)
+				w.(http.Flusher).Flush()
+				select {
+				case <-release:
+				case <-r.Context().Done():
+					return
+				}
+				part.Write(marshalLogMessage(&lm2))
+				mw.Close()
+			}))
+		})
+
+		AfterEach(func() {
+			server.Close()
+		})
+
+		Context("when the logs service sends recent logs in timestamp order", func() {
+			BeforeEach(func() {
+				logClient = logclient.NewLogClientBuilder().Endpoint(server.URL).RecentOrdered(true).Build()
+			})
+
+			It("should deliver each record as soon as it is received", func() {
+				var received []string
+				err := logClient.StreamRecentLogs(ctx, serviceGuid, authToken, func(record logclient.LogRecord) error {
+					received = append(received, record.Message)
+					if len(received) == 1 {
+						close(release)
+					}
+					return nil
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(received).To(Equal([]string{"MESSAGE-1", "MESSAGE-2"}))
+			})
+
+			It("should stop at the first error returned by the function", func() {
+				calls := 0
+				err := logClient.StreamRecentLogs(ctx, serviceGuid, authToken, func(record logclient.LogRecord) error {
+					calls++
+					return testError
+				})
+				Expect(err).To(Equal(testError))
+				Expect(calls).To(Equal(1))
+			})
+		})
+
+		Context("when the logs service does not send recent logs in timestamp order", func() {
+			BeforeEach(func() {
+				logClient = logclient.NewLogClientBuilder().Endpoint(server.URL).Build()
+				close(release)
+			})
+
+			It("should deliver the records sorted by timestamp", func() {
+				var received []string
+				err := logClient.StreamRecentLogs(ctx, serviceGuid, authToken, func(record logclient.LogRecord) error {
+					received = append(received, record.Message)
+					return nil
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(received).To(Equal([]string{"MESSAGE-2", "MESSAGE-1"}))
+			})
+		})
+
+		Context("when the response stalls", func() {
+			BeforeEach(func() {
+				logClient = logclient.NewLogClientBuilder().Endpoint(server.URL).RecentOrdered(true).Build()
+				logClient.(logclient.FieldSetter).SetRecentIdleTimeout(50 * time.Millisecond)
+			})
+
+			It("should abandon the response", func() {
+				var received []string
+				err := logClient.StreamRecentLogs(ctx, serviceGuid, authToken, func(record logclient.LogRecord) error {
+					received = append(received, record.Message)
+					return nil
+				})
+				Expect(err).To(MatchError("Error reading recent logs: no data received for 50ms"))
+				Expect(received).To(Equal([]string{"MESSAGE-1"}))
+			})
+
+			It("should not count time spent delivering records", func() {
+				var received []string
+				err := logClient.StreamRecentLogs(ctx, serviceGuid, authToken, func(record logclient.LogRecord) error {
+					received = append(received, record.Message)
+					if len(received) == 1 {
+						time.Sleep(100 * time.Millisecond)
+						close(release)
+					}
+					return nil
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(received).To(Equal([]string{"MESSAGE-1", "MESSAGE-2"}))
+			})
+		})
+
+		Context("when the context is cancelled while the response is being received", func() {
+			BeforeEach(func() {
+				logClient = logclient.NewLogClientBuilder().Endpoint(server.URL).Build()
+			})
+
+			It("should return the context's error", func() {
+				go func() {
+					time.Sleep(50 * time.Millisecond)
+					cancel()
+				}()
+				err := logClient.StreamRecentLogs(ctx, serviceGuid, authToken, func(logclient.LogRecord) error {
+					return nil
+				})
+				Expect(err).To(Equal(context.Canceled))
+			})
+		})
+	})
+
+	Describe("classifyError", func() {
+		It("should leave nil unchanged", func() {
+			Expect(logclient.ClassifyError(nil)).To(BeNil())
+		})
+	})
+
+	Describe("TokenSource", func() {
+		It("should supply tokens to the consumer with a bearer prefix", func() {
+			source := logclient.TokenSource(func() (string, error) { return "some-token", nil })
+			Expect(source.RefreshAuthToken()).To(Equal("bearer some-token"))
+		})
+
+		It("should pass on failures", func() {
+			source := logclient.TokenSource(func() (string, error) { return "", errors.New("no dice") })
+			_, err := source.RefreshAuthToken()
+			Expect(err).To(MatchError("no dice"))
+		})
 	})
 
 	Describe("TailingLogs", func() {
 		var (
 			logMsgsChan    chan *events.LogMessage
 			logErrChan     chan error
-			logStringsChan <-chan string
+			logStringsChan <-chan logclient.LogRecord
 			errChan        <-chan error
 		)
 
+		var closeUpstream func()
+
 		BeforeEach(func() {
 			logMsgsChan = make(chan *events.LogMessage, 3)
 			logErrChan = make(chan error, 3)
 			fakeConsumer.TailingLogsReturns(logMsgsChan, logErrChan)
+			closeUpstream = sync.OnceFunc(func() {
+				close(logMsgsChan)
+				close(logErrChan)
+			})
 		})
 
 		AfterEach(func() {
-			close(logMsgsChan)
-			close(logErrChan)
+			closeUpstream()
 		})
 
 		JustBeforeEach(func() {
-			logStringsChan, errChan = logClient.TailingLogs(serviceGuid, authToken)
+			logStringsChan, errChan = logClient.TailingLogs(ctx, serviceGuid, authToken)
 		})
 
 		Context("in the normal case", func() {
@@ -238,18 +499,18 @@ var _ = Describe("Logclient", func() {
 				Expect(token).To(Equal("bearer " + authToken))
 			})
 
-			It("should send expected string messages in correct sequence to returned message channel", func() {
-				var receivedMsg string
+			It("should send expected log records in correct sequence to returned message channel", func() {
+				var receivedMsg logclient.LogRecord
 				Eventually(logStringsChan).Should(Receive(&receivedMsg))
-				Expect(receivedMsg).Should(Equal(fmt.Sprintf("%s [ST-1/SI-1] OUT MESSAGE-1",
+				Expect(receivedMsg.String()).Should(Equal(fmt.Sprintf("%s [ST-1/SI-1] OUT MESSAGE-1",
 					formatUnixTimestamp(currentTimestamp))))
 
 				Eventually(logStringsChan).Should(Receive(&receivedMsg))
-				Expect(receivedMsg).Should(Equal(fmt.Sprintf("%s [ST-2/SI-2] ERR MESSAGE-2",
+				Expect(receivedMsg.String()).Should(Equal(fmt.Sprintf("%s [ST-2/SI-2] ERR MESSAGE-2",
 					formatUnixTimestamp(currentTimestamp))))
 
 				Eventually(logStringsChan).Should(Receive(&receivedMsg))
-				Expect(receivedMsg).Should(Equal(fmt.Sprintf("%s [ST-3/SI-3] OUT MESSAGE-3",
+				Expect(receivedMsg.String()).Should(Equal(fmt.Sprintf("%s [ST-3/SI-3] OUT MESSAGE-3",
 					formatUnixTimestamp(currentTimestamp))))
 			})
 
@@ -294,6 +555,94 @@ var _ = Describe("Logclient", func() {
 				Expect(receivedErrorMessage.Error()).Should(Equal("Error 3"))
 			})
 		})
+
+		Context("when the consumer sends a nil error", func() {
+			BeforeEach(func() {
+				logErrChan <- nil
+				logErrChan <- errors.New("Error 1")
+			})
+
+			It("should not forward it", func() {
+				var receivedErrorMessage error
+				Eventually(errChan).Should(Receive(&receivedErrorMessage))
+				Expect(receivedErrorMessage).To(MatchError("Error 1"))
+			})
+		})
+
+		Context("when the consumer reports dial failures", func() {
+			BeforeEach(func() {
+				logErrChan <- errors.New("Error dialing trafficcontroller server: Unauthorized error: invalid token.")
+				logErrChan <- errors.New("Error dialing trafficcontroller server: tls: failed to verify certificate: x509: certificate signed by unknown authority.")
+				logErrChan <- errors.New("Error dialing trafficcontroller server: dial tcp 127.0.0.1:1: connect: connection refused.")
+			})
+
+			It("should classify the errors", func() {
+				var receivedError error
+				Eventually(errChan).Should(Receive(&receivedError))
+				Expect(receivedError).To(BeAssignableToTypeOf(&logclient.StatusError{}))
+				Expect(receivedError.(*logclient.StatusError).StatusCode).To(Equal(http.StatusUnauthorized))
+
+				Eventually(errChan).Should(Receive(&receivedError))
+				Expect(receivedError).To(BeAssignableToTypeOf(&logclient.TLSError{}))
+
+				Eventually(errChan).Should(Receive(&receivedError))
+				Expect(receivedError).To(BeAssignableToTypeOf(&logclient.ConnectionError{}))
+				Expect(receivedError).To(MatchError(HaveSuffix("connection refused.")))
+			})
+		})
+
+		Context("when the upstream stream ends", func() {
+			BeforeEach(func() {
+				lm := createLogMessage("1", events.LogMessage_OUT, time.Now().UnixNano())
+				logMsgsChan <- &lm
+				closeUpstream()
+			})
+
+			It("should deliver the remaining records and close both channels", func() {
+				Eventually(logStringsChan).Should(Receive())
+				Eventually(logStringsChan).Should(BeClosed())
+				Eventually(errChan).Should(BeClosed())
+			})
+
+			It("should not close the consumer", func() {
+				Eventually(logStringsChan).Should(Receive())
+				Eventually(errChan).Should(BeClosed())
+				Consistently(fakeConsumer.CloseCallCount).Should(Equal(0))
+			})
+		})
+
+		Context("when the context is cancelled", func() {
+			BeforeEach(func() {
+				lm := createLogMessage("1", events.LogMessage_OUT, time.Now().UnixNano())
+				logMsgsChan <- &lm
+				fakeConsumer.CloseStub = func() error {
+					closeUpstream()
+					return nil
+				}
+			})
+
+			JustBeforeEach(func() {
+				cancel()
+			})
+
+			It("should close the consumer", func() {
+				Eventually(fakeConsumer.CloseCallCount).Should(Equal(1))
+			})
+
+			It("should deliver records already received and then close both channels", func() {
+				Eventually(logStringsChan).Should(Receive())
+				Eventually(logStringsChan).Should(BeClosed())
+				Eventually(errChan).Should(BeClosed())
+			})
+		})
+	})
+
+	Describe("RecentLogs when the context is cancelled", func() {
+		It("should return the context's error without making the request", func() {
+			cancel()
+			_, err := logClient.RecentLogs(ctx, serviceGuid, authToken)
+			Expect(err).To(Equal(context.Canceled))
+		})
 	})
 })
 
@@ -313,6 +662,40 @@ func createLogMessage(fieldSuffix string, messageType events.LogMessage_MessageT
 	}
 }
 
+// writeRecentLogs writes the given log messages as a multipart recent logs response, preceded by a part which is not
+// a log message envelope. An empty content type is replaced by the multipart content type.
+func writeRecentLogs(w http.ResponseWriter, contentType string, messages []*events.LogMessage) {
+	mw := multipart.NewWriter(w)
+	if contentType == "" {
+		contentType = "multipart/x-protobuf; boundary=" + mw.Boundary()
+	}
+	w.Header().Set("Content-Type", contentType)
+	part, err := mw.CreatePart(nil)
+	Expect(err).NotTo(HaveOccurred())
+	part.Write([]byte("not an envelope"))
+	for _, message := range messages {
+		writeLogMessagePart(mw, message)
+	}
+	Expect(mw.Close()).To(Succeed())
+}
+
+func writeLogMessagePart(mw *multipart.Writer, message *events.LogMessage) {
+	part, err := mw.CreatePart(nil)
+	Expect(err).NotTo(HaveOccurred())
+	_, err = part.Write(marshalLogMessage(message))
+	Expect(err).NotTo(HaveOccurred())
+}
+
+func marshalLogMessage(message *events.LogMessage) []byte {
+	data, err := proto.Marshal(&events.Envelope{
+		Origin:     proto.String("origin"),
+		EventType:  events.Envelope_LogMessage.Enum(),
+		LogMessage: message,
+	})
+	Expect(err).NotTo(HaveOccurred())
+	return data
+}
+
 func formatUnixTimestamp(nanosSinceEpoch int64) string {
 	secs := nanosSinceEpoch / 1000000000
 	nanosecs := nanosSinceEpoch - (secs * 1000000000)
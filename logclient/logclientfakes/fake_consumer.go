@@ -10,11 +10,6 @@ import (
 )
 
 type FakeConsumer struct {
-	SetRecentPathBuilderStub        func(b consumer.RecentPathBuilder)
-	setRecentPathBuilderMutex       sync.RWMutex
-	setRecentPathBuilderArgsForCall []struct {
-		b consumer.RecentPathBuilder
-	}
 	SetStreamPathBuilderStub        func(b consumer.StreamPathBuilder)
 	setStreamPathBuilderMutex       sync.RWMutex
 	setStreamPathBuilderArgsForCall []struct {
@@ -25,20 +20,6 @@ type FakeConsumer struct {
 	setDebugPrinterArgsForCall []struct {
 		debugPrinter consumer.DebugPrinter
 	}
-	RecentLogsStub        func(appGuid string, authToken string) ([]*events.LogMessage, error)
-	recentLogsMutex       sync.RWMutex
-	recentLogsArgsForCall []struct {
-		appGuid   string
-		authToken string
-	}
-	recentLogsReturns struct {
-		result1 []*events.LogMessage
-		result2 error
-	}
-	recentLogsReturnsOnCall map[int]struct {
-		result1 []*events.LogMessage
-		result2 error
-	}
 	TailingLogsStub        func(appGuid, authToken string) (<-chan *events.LogMessage, <-chan error)
 	tailingLogsMutex       sync.RWMutex
 	tailingLogsArgsForCall []struct {
@@ -53,34 +34,19 @@ type FakeConsumer struct {
 		result1 <-chan *events.LogMessage
 		result2 <-chan error
 	}
+	CloseStub        func() error
+	closeMutex       sync.RWMutex
+	closeArgsForCall []struct{}
+	closeReturns     struct {
+		result1 error
+	}
+	closeReturnsOnCall map[int]struct {
+		result1 error
+	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeConsumer) SetRecentPathBuilder(b consumer.RecentPathBuilder) {
-	fake.setRecentPathBuilderMutex.Lock()
-	fake.setRecentPathBuilderArgsForCall = append(fake.setRecentPathBuilderArgsForCall, struct {
-		b consumer.RecentPathBuilder
-	}{b})
-	fake.recordInvocation("SetRecentPathBuilder", []interface{}{b})
-	fake.setRecentPathBuilderMutex.Unlock()
-	if fake.SetRecentPathBuilderStub != nil {
-		fake.SetRecentPathBuilderStub(b)
-	}
-}
-
-func (fake *FakeConsumer) SetRecentPathBuilderCallCount() int {
-	fake.setRecentPathBuilderMutex.RLock()
-	defer fake.setRecentPathBuilderMutex.RUnlock()
-	return len(fake.setRecentPathBuilderArgsForCall)
-}
-
-func (fake *FakeConsumer) SetRecentPathBuilderArgsForCall(i int) consumer.RecentPathBuilder {
-	fake.setRecentPathBuilderMutex.RLock()
-	defer fake.setRecentPathBuilderMutex.RUnlock()
-	return fake.setRecentPathBuilderArgsForCall[i].b
-}
-
 func (fake *FakeConsumer) SetStreamPathBuilder(b consumer.StreamPathBuilder) {
 	fake.setStreamPathBuilderMutex.Lock()
 	fake.setStreamPathBuilderArgsForCall = append(fake.setStreamPathBuilderArgsForCall, struct {
@@ -129,58 +95,6 @@ func (fake *FakeConsumer) SetDebugPrinterArgsForCall(i int) consumer.DebugPrinte
 	return fake.setDebugPrinterArgsForCall[i].debugPrinter
 }
 
-func (fake *FakeConsumer) RecentLogs(appGuid string, authToken string) ([]*events.LogMessage, error) {
-	fake.recentLogsMutex.Lock()
-	ret, specificReturn := fake.recentLogsReturnsOnCall[len(fake.recentLogsArgsForCall)]
-	fake.recentLogsArgsForCall = append(fake.recentLogsArgsForCall, struct {
-		appGuid   string
-		authToken string
-	}{appGuid, authToken})
-	fake.recordInvocation("RecentLogs", []interface{}{appGuid, authToken})
-	fake.recentLogsMutex.Unlock()
-	if fake.RecentLogsStub != nil {
-		return fake.RecentLogsStub(appGuid, authToken)
-	}
-	if specificReturn {
-		return ret.result1, ret.result2
-	}
-	return fake.recentLogsReturns.result1, fake.recentLogsReturns.result2
-}
-
-func (fake *FakeConsumer) RecentLogsCallCount() int {
-	fake.recentLogsMutex.RLock()
-	defer fake.recentLogsMutex.RUnlock()
-	return len(fake.recentLogsArgsForCall)
-}
-
-func (fake *FakeConsumer) RecentLogsArgsForCall(i int) (string, string) {
-	fake.recentLogsMutex.RLock()
-	defer fake.recentLogsMutex.RUnlock()
-	return fake.recentLogsArgsForCall[i].appGuid, fake.recentLogsArgsForCall[i].authToken
-}
-
-func (fake *FakeConsumer) RecentLogsReturns(result1 []*events.LogMessage, result2 error) {
-	fake.RecentLogsStub = nil
-	fake.recentLogsReturns = struct {
-		result1 []*events.LogMessage
-		result2 error
-	}{result1, result2}
-}
-
-func (fake *FakeConsumer) RecentLogsReturnsOnCall(i int, result1 []*events.LogMessage, result2 error) {
-	fake.RecentLogsStub = nil
-	if fake.recentLogsReturnsOnCall == nil {
-		fake.recentLogsReturnsOnCall = make(map[int]struct {
-			result1 []*events.LogMessage
-			result2 error
-		})
-	}
-	fake.recentLogsReturnsOnCall[i] = struct {
-		result1 []*events.LogMessage
-		result2 error
-	}{result1, result2}
-}
-
 func (fake *FakeConsumer) TailingLogs(appGuid string, authToken string) (<-chan *events.LogMessage, <-chan error) {
 	fake.tailingLogsMutex.Lock()
 	ret, specificReturn := fake.tailingLogsReturnsOnCall[len(fake.tailingLogsArgsForCall)]
@@ -233,19 +147,57 @@ func (fake *FakeConsumer) TailingLogsReturnsOnCall(i int, result1 <-chan *events
 	}{result1, result2}
 }
 
+func (fake *FakeConsumer) Close() error {
+	fake.closeMutex.Lock()
+	ret, specificReturn := fake.closeReturnsOnCall[len(fake.closeArgsForCall)]
+	fake.closeArgsForCall = append(fake.closeArgsForCall, struct{}{})
+	fake.recordInvocation("Close", []interface{}{})
+	fake.closeMutex.Unlock()
+	if fake.CloseStub != nil {
+		return fake.CloseStub()
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.closeReturns.result1
+}
+
+func (fake *FakeConsumer) CloseCallCount() int {
+	fake.closeMutex.RLock()
+	defer fake.closeMutex.RUnlock()
+	return len(fake.closeArgsForCall)
+}
+
+func (fake *FakeConsumer) CloseReturns(result1 error) {
+	fake.CloseStub = nil
+	fake.closeReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeConsumer) CloseReturnsOnCall(i int, result1 error) {
+	fake.CloseStub = nil
+	if fake.closeReturnsOnCall == nil {
+		fake.closeReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.closeReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
 func (fake *FakeConsumer) Invocations() map[string][][]interface{} {
 	fake.invocationsMutex.RLock()
 	defer fake.invocationsMutex.RUnlock()
-	fake.setRecentPathBuilderMutex.RLock()
-	defer fake.setRecentPathBuilderMutex.RUnlock()
 	fake.setStreamPathBuilderMutex.RLock()
 	defer fake.setStreamPathBuilderMutex.RUnlock()
 	fake.setDebugPrinterMutex.RLock()
 	defer fake.setDebugPrinterMutex.RUnlock()
-	fake.recentLogsMutex.RLock()
-	defer fake.recentLogsMutex.RUnlock()
 	fake.tailingLogsMutex.RLock()
 	defer fake.tailingLogsMutex.RUnlock()
+	fake.closeMutex.RLock()
+	defer fake.closeMutex.RUnlock()
 	copiedInvocations := map[string][][]interface{}{}
 	for key, value := range fake.invocations {
 		copiedInvocations[key] = value
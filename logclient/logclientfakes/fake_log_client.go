@@ -2,55 +2,73 @@
 package logclientfakes
 
 import (
+	"context"
 	"sync"
 
 	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
 )
 
 type FakeLogClient struct {
-	RecentLogsStub        func(serviceGUID string, authToken string) ([]string, error)
+	RecentLogsStub        func(ctx context.Context, serviceGUID string, authToken string) ([]logclient.LogRecord, error)
 	recentLogsMutex       sync.RWMutex
 	recentLogsArgsForCall []struct {
+		ctx         context.Context
 		serviceGUID string
 		authToken   string
 	}
 	recentLogsReturns struct {
-		result1 []string
+		result1 []logclient.LogRecord
 		result2 error
 	}
 	recentLogsReturnsOnCall map[int]struct {
-		result1 []string
+		result1 []logclient.LogRecord
 		result2 error
 	}
-	TailingLogsStub        func(serviceGUID string, authToken string) (<-chan string, <-chan error)
+	StreamRecentLogsStub        func(ctx context.Context, serviceGUID string, authToken string, each func(logclient.LogRecord) error) error
+	streamRecentLogsMutex       sync.RWMutex
+	streamRecentLogsArgsForCall []struct {
+		ctx         context.Context
+		serviceGUID string
+		authToken   string
+		each        func(logclient.LogRecord) error
+	}
+	streamRecentLogsReturns struct {
+		result1 error
+	}
+	streamRecentLogsReturnsOnCall map[int]struct {
+		result1 error
+	}
+	TailingLogsStub        func(ctx context.Context, serviceGUID string, authToken string) (<-chan logclient.LogRecord, <-chan error)
 	tailingLogsMutex       sync.RWMutex
 	tailingLogsArgsForCall []struct {
+		ctx         context.Context
 		serviceGUID string
 		authToken   string
 	}
 	tailingLogsReturns struct {
-		result1 <-chan string
+		result1 <-chan logclient.LogRecord
 		result2 <-chan error
 	}
 	tailingLogsReturnsOnCall map[int]struct {
-		result1 <-chan string
+		result1 <-chan logclient.LogRecord
 		result2 <-chan error
 	}
 	invocations      map[string][][]interface{}
 	invocationsMutex sync.RWMutex
 }
 
-func (fake *FakeLogClient) RecentLogs(serviceGUID string, authToken string) ([]string, error) {
+func (fake *FakeLogClient) RecentLogs(ctx context.Context, serviceGUID string, authToken string) ([]logclient.LogRecord, error) {
 	fake.recentLogsMutex.Lock()
 	ret, specificReturn := fake.recentLogsReturnsOnCall[len(fake.recentLogsArgsForCall)]
 	fake.recentLogsArgsForCall = append(fake.recentLogsArgsForCall, struct {
+		ctx         context.Context
 		serviceGUID string
 		authToken   string
-	}{serviceGUID, authToken})
-	fake.recordInvocation("RecentLogs", []interface{}{serviceGUID, authToken})
+	}{ctx, serviceGUID, authToken})
+	fake.recordInvocation("RecentLogs", []interface{}{ctx, serviceGUID, authToken})
 	fake.recentLogsMutex.Unlock()
 	if fake.RecentLogsStub != nil {
-		return fake.RecentLogsStub(serviceGUID, authToken)
+		return fake.RecentLogsStub(ctx, serviceGUID, authToken)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -64,45 +82,97 @@ func (fake *FakeLogClient) RecentLogsCallCount() int {
 	return len(fake.recentLogsArgsForCall)
 }
 
-func (fake *FakeLogClient) RecentLogsArgsForCall(i int) (string, string) {
+func (fake *FakeLogClient) RecentLogsArgsForCall(i int) (context.Context, string, string) {
 	fake.recentLogsMutex.RLock()
 	defer fake.recentLogsMutex.RUnlock()
-	return fake.recentLogsArgsForCall[i].serviceGUID, fake.recentLogsArgsForCall[i].authToken
+	return fake.recentLogsArgsForCall[i].ctx, fake.recentLogsArgsForCall[i].serviceGUID, fake.recentLogsArgsForCall[i].authToken
 }
 
-func (fake *FakeLogClient) RecentLogsReturns(result1 []string, result2 error) {
+func (fake *FakeLogClient) RecentLogsReturns(result1 []logclient.LogRecord, result2 error) {
 	fake.RecentLogsStub = nil
 	fake.recentLogsReturns = struct {
-		result1 []string
+		result1 []logclient.LogRecord
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *FakeLogClient) RecentLogsReturnsOnCall(i int, result1 []string, result2 error) {
+func (fake *FakeLogClient) RecentLogsReturnsOnCall(i int, result1 []logclient.LogRecord, result2 error) {
 	fake.RecentLogsStub = nil
 	if fake.recentLogsReturnsOnCall == nil {
 		fake.recentLogsReturnsOnCall = make(map[int]struct {
-			result1 []string
+			result1 []logclient.LogRecord
 			result2 error
 		})
 	}
 	fake.recentLogsReturnsOnCall[i] = struct {
-		result1 []string
+		result1 []logclient.LogRecord
 		result2 error
 	}{result1, result2}
 }
 
-func (fake *FakeLogClient) TailingLogs(serviceGUID string, authToken string) (<-chan string, <-chan error) {
+func (fake *FakeLogClient) StreamRecentLogs(ctx context.Context, serviceGUID string, authToken string, each func(logclient.LogRecord) error) error {
+	fake.streamRecentLogsMutex.Lock()
+	ret, specificReturn := fake.streamRecentLogsReturnsOnCall[len(fake.streamRecentLogsArgsForCall)]
+	fake.streamRecentLogsArgsForCall = append(fake.streamRecentLogsArgsForCall, struct {
+		ctx         context.Context
+		serviceGUID string
+		authToken   string
+		each        func(logclient.LogRecord) error
+	}{ctx, serviceGUID, authToken, each})
+	fake.recordInvocation("StreamRecentLogs", []interface{}{ctx, serviceGUID, authToken, each})
+	fake.streamRecentLogsMutex.Unlock()
+	if fake.StreamRecentLogsStub != nil {
+		return fake.StreamRecentLogsStub(ctx, serviceGUID, authToken, each)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.streamRecentLogsReturns.result1
+}
+
+func (fake *FakeLogClient) StreamRecentLogsCallCount() int {
+	fake.streamRecentLogsMutex.RLock()
+	defer fake.streamRecentLogsMutex.RUnlock()
+	return len(fake.streamRecentLogsArgsForCall)
+}
+
+func (fake *FakeLogClient) StreamRecentLogsArgsForCall(i int) (context.Context, string, string, func(logclient.LogRecord) error) {
+	fake.streamRecentLogsMutex.RLock()
+	defer fake.streamRecentLogsMutex.RUnlock()
+	return fake.streamRecentLogsArgsForCall[i].ctx, fake.streamRecentLogsArgsForCall[i].serviceGUID, fake.streamRecentLogsArgsForCall[i].authToken, fake.streamRecentLogsArgsForCall[i].each
+}
+
+func (fake *FakeLogClient) StreamRecentLogsReturns(result1 error) {
+	fake.StreamRecentLogsStub = nil
+	fake.streamRecentLogsReturns = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeLogClient) StreamRecentLogsReturnsOnCall(i int, result1 error) {
+	fake.StreamRecentLogsStub = nil
+	if fake.streamRecentLogsReturnsOnCall == nil {
+		fake.streamRecentLogsReturnsOnCall = make(map[int]struct {
+			result1 error
+		})
+	}
+	fake.streamRecentLogsReturnsOnCall[i] = struct {
+		result1 error
+	}{result1}
+}
+
+func (fake *FakeLogClient) TailingLogs(ctx context.Context, serviceGUID string, authToken string) (<-chan logclient.LogRecord, <-chan error) {
 	fake.tailingLogsMutex.Lock()
 	ret, specificReturn := fake.tailingLogsReturnsOnCall[len(fake.tailingLogsArgsForCall)]
 	fake.tailingLogsArgsForCall = append(fake.tailingLogsArgsForCall, struct {
+		ctx         context.Context
 		serviceGUID string
 		authToken   string
-	}{serviceGUID, authToken})
-	fake.recordInvocation("TailingLogs", []interface{}{serviceGUID, authToken})
+	}{ctx, serviceGUID, authToken})
+	fake.recordInvocation("TailingLogs", []interface{}{ctx, serviceGUID, authToken})
 	fake.tailingLogsMutex.Unlock()
 	if fake.TailingLogsStub != nil {
-		return fake.TailingLogsStub(serviceGUID, authToken)
+		return fake.TailingLogsStub(ctx, serviceGUID, authToken)
 	}
 	if specificReturn {
 		return ret.result1, ret.result2
@@ -116,30 +186,30 @@ func (fake *FakeLogClient) TailingLogsCallCount() int {
 	return len(fake.tailingLogsArgsForCall)
 }
 
-func (fake *FakeLogClient) TailingLogsArgsForCall(i int) (string, string) {
+func (fake *FakeLogClient) TailingLogsArgsForCall(i int) (context.Context, string, string) {
 	fake.tailingLogsMutex.RLock()
 	defer fake.tailingLogsMutex.RUnlock()
-	return fake.tailingLogsArgsForCall[i].serviceGUID, fake.tailingLogsArgsForCall[i].authToken
+	return fake.tailingLogsArgsForCall[i].ctx, fake.tailingLogsArgsForCall[i].serviceGUID, fake.tailingLogsArgsForCall[i].authToken
 }
 
-func (fake *FakeLogClient) TailingLogsReturns(result1 <-chan string, result2 <-chan error) {
+func (fake *FakeLogClient) TailingLogsReturns(result1 <-chan logclient.LogRecord, result2 <-chan error) {
 	fake.TailingLogsStub = nil
 	fake.tailingLogsReturns = struct {
-		result1 <-chan string
+		result1 <-chan logclient.LogRecord
 		result2 <-chan error
 	}{result1, result2}
 }
 
-func (fake *FakeLogClient) TailingLogsReturnsOnCall(i int, result1 <-chan string, result2 <-chan error) {
+func (fake *FakeLogClient) TailingLogsReturnsOnCall(i int, result1 <-chan logclient.LogRecord, result2 <-chan error) {
 	fake.TailingLogsStub = nil
 	if fake.tailingLogsReturnsOnCall == nil {
 		fake.tailingLogsReturnsOnCall = make(map[int]struct {
-			result1 <-chan string
+			result1 <-chan logclient.LogRecord
 			result2 <-chan error
 		})
 	}
 	fake.tailingLogsReturnsOnCall[i] = struct {
-		result1 <-chan string
+		result1 <-chan logclient.LogRecord
 		result2 <-chan error
 	}{result1, result2}
 }
@@ -149,6 +219,8 @@ func (fake *FakeLogClient) Invocations() map[string][][]interface{} {
 	defer fake.invocationsMutex.RUnlock()
 	fake.recentLogsMutex.RLock()
 	defer fake.recentLogsMutex.RUnlock()
+	fake.streamRecentLogsMutex.RLock()
+	defer fake.streamRecentLogsMutex.RUnlock()
 	fake.tailingLogsMutex.RLock()
 	defer fake.tailingLogsMutex.RUnlock()
 	return fake.invocations
@@ -2,6 +2,8 @@
 package logclientfakes
 
 import (
+	"context"
+	"net/url"
 	"sync"
 
 	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
@@ -30,6 +32,53 @@ type FakeLogClientBuilder struct {
 	insecureSkipVerifyReturnsOnCall map[int]struct {
 		result1 logclient.LogClientBuilder
 	}
+	FiltersStub        func(filters url.Values) logclient.LogClientBuilder
+	filtersMutex       sync.RWMutex
+	filtersArgsForCall []struct {
+		filters url.Values
+	}
+	filtersReturns struct {
+		result1 logclient.LogClientBuilder
+	}
+	filtersReturnsOnCall map[int]struct {
+		result1 logclient.LogClientBuilder
+	}
+	TokenSourceStub        func(source logclient.TokenSource) logclient.LogClientBuilder
+	tokenSourceMutex       sync.RWMutex
+	tokenSourceArgsForCall []struct {
+		source logclient.TokenSource
+	}
+	tokenSourceReturns struct {
+		result1 logclient.LogClientBuilder
+	}
+	tokenSourceReturnsOnCall map[int]struct {
+		result1 logclient.LogClientBuilder
+	}
+	RecentOrderedStub        func(ordered bool) logclient.LogClientBuilder
+	recentOrderedMutex       sync.RWMutex
+	recentOrderedArgsForCall []struct {
+		ordered bool
+	}
+	recentOrderedReturns struct {
+		result1 logclient.LogClientBuilder
+	}
+	recentOrderedReturnsOnCall map[int]struct {
+		result1 logclient.LogClientBuilder
+	}
+	InfoStub        func(ctx context.Context, authToken string) (logclient.Info, error)
+	infoMutex       sync.RWMutex
+	infoArgsForCall []struct {
+		ctx       context.Context
+		authToken string
+	}
+	infoReturns struct {
+		result1 logclient.Info
+		result2 error
+	}
+	infoReturnsOnCall map[int]struct {
+		result1 logclient.Info
+		result2 error
+	}
 	BuildStub        func() logclient.LogClient
 	buildMutex       sync.RWMutex
 	buildArgsForCall []struct{}
@@ -139,6 +188,202 @@ func (fake *FakeLogClientBuilder) InsecureSkipVerifyReturnsOnCall(i int, result1
 	}{result1}
 }
 
+func (fake *FakeLogClientBuilder) Filters(filters url.Values) logclient.LogClientBuilder {
+	fake.filtersMutex.Lock()
+	ret, specificReturn := fake.filtersReturnsOnCall[len(fake.filtersArgsForCall)]
+	fake.filtersArgsForCall = append(fake.filtersArgsForCall, struct {
+		filters url.Values
+	}{filters})
+	fake.recordInvocation("Filters", []interface{}{filters})
+	fake.filtersMutex.Unlock()
+	if fake.FiltersStub != nil {
+		return fake.FiltersStub(filters)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.filtersReturns.result1
+}
+
+func (fake *FakeLogClientBuilder) FiltersCallCount() int {
+	fake.filtersMutex.RLock()
+	defer fake.filtersMutex.RUnlock()
+	return len(fake.filtersArgsForCall)
+}
+
+func (fake *FakeLogClientBuilder) FiltersArgsForCall(i int) url.Values {
+	fake.filtersMutex.RLock()
+	defer fake.filtersMutex.RUnlock()
+	return fake.filtersArgsForCall[i].filters
+}
+
+func (fake *FakeLogClientBuilder) FiltersReturns(result1 logclient.LogClientBuilder) {
+	fake.FiltersStub = nil
+	fake.filtersReturns = struct {
+		result1 logclient.LogClientBuilder
+	}{result1}
+}
+
+func (fake *FakeLogClientBuilder) FiltersReturnsOnCall(i int, result1 logclient.LogClientBuilder) {
+	fake.FiltersStub = nil
+	if fake.filtersReturnsOnCall == nil {
+		fake.filtersReturnsOnCall = make(map[int]struct {
+			result1 logclient.LogClientBuilder
+		})
+	}
+	fake.filtersReturnsOnCall[i] = struct {
+		result1 logclient.LogClientBuilder
+	}{result1}
+}
+
+func (fake *FakeLogClientBuilder) TokenSource(source logclient.TokenSource) logclient.LogClientBuilder {
+	fake.tokenSourceMutex.Lock()
+	ret, specificReturn := fake.tokenSourceReturnsOnCall[len(fake.tokenSourceArgsForCall)]
+	fake.tokenSourceArgsForCall = append(fake.tokenSourceArgsForCall, struct {
+		source logclient.TokenSource
+	}{source})
+	fake.recordInvocation("TokenSource", []interface{}{source})
+	fake.tokenSourceMutex.Unlock()
+	if fake.TokenSourceStub != nil {
+		return fake.TokenSourceStub(source)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.tokenSourceReturns.result1
+}
+
+func (fake *FakeLogClientBuilder) TokenSourceCallCount() int {
+	fake.tokenSourceMutex.RLock()
+	defer fake.tokenSourceMutex.RUnlock()
+	return len(fake.tokenSourceArgsForCall)
+}
+
+func (fake *FakeLogClientBuilder) TokenSourceArgsForCall(i int) logclient.TokenSource {
+	fake.tokenSourceMutex.RLock()
+	defer fake.tokenSourceMutex.RUnlock()
+	return fake.tokenSourceArgsForCall[i].source
+}
+
+func (fake *FakeLogClientBuilder) TokenSourceReturns(result1 logclient.LogClientBuilder) {
+	fake.TokenSourceStub = nil
+	fake.tokenSourceReturns = struct {
+		result1 logclient.LogClientBuilder
+	}{result1}
+}
+
+func (fake *FakeLogClientBuilder) TokenSourceReturnsOnCall(i int, result1 logclient.LogClientBuilder) {
+	fake.TokenSourceStub = nil
+	if fake.tokenSourceReturnsOnCall == nil {
+		fake.tokenSourceReturnsOnCall = make(map[int]struct {
+			result1 logclient.LogClientBuilder
+		})
+	}
+	fake.tokenSourceReturnsOnCall[i] = struct {
+		result1 logclient.LogClientBuilder
+	}{result1}
+}
+
+func (fake *FakeLogClientBuilder) RecentOrdered(ordered bool) logclient.LogClientBuilder {
+	fake.recentOrderedMutex.Lock()
+	ret, specificReturn := fake.recentOrderedReturnsOnCall[len(fake.recentOrderedArgsForCall)]
+	fake.recentOrderedArgsForCall = append(fake.recentOrderedArgsForCall, struct {
+		ordered bool
+	}{ordered})
+	fake.recordInvocation("RecentOrdered", []interface{}{ordered})
+	fake.recentOrderedMutex.Unlock()
+	if fake.RecentOrderedStub != nil {
+		return fake.RecentOrderedStub(ordered)
+	}
+	if specificReturn {
+		return ret.result1
+	}
+	return fake.recentOrderedReturns.result1
+}
+
+func (fake *FakeLogClientBuilder) RecentOrderedCallCount() int {
+	fake.recentOrderedMutex.RLock()
+	defer fake.recentOrderedMutex.RUnlock()
+	return len(fake.recentOrderedArgsForCall)
+}
+
+func (fake *FakeLogClientBuilder) RecentOrderedArgsForCall(i int) bool {
+	fake.recentOrderedMutex.RLock()
+	defer fake.recentOrderedMutex.RUnlock()
+	return fake.recentOrderedArgsForCall[i].ordered
+}
+
+func (fake *FakeLogClientBuilder) RecentOrderedReturns(result1 logclient.LogClientBuilder) {
+	fake.RecentOrderedStub = nil
+	fake.recentOrderedReturns = struct {
+		result1 logclient.LogClientBuilder
+	}{result1}
+}
+
+func (fake *FakeLogClientBuilder) RecentOrderedReturnsOnCall(i int, result1 logclient.LogClientBuilder) {
+	fake.RecentOrderedStub = nil
+	if fake.recentOrderedReturnsOnCall == nil {
+		fake.recentOrderedReturnsOnCall = make(map[int]struct {
+			result1 logclient.LogClientBuilder
+		})
+	}
+	fake.recentOrderedReturnsOnCall[i] = struct {
+		result1 logclient.LogClientBuilder
+	}{result1}
+}
+
+func (fake *FakeLogClientBuilder) Info(ctx context.Context, authToken string) (logclient.Info, error) {
+	fake.infoMutex.Lock()
+	ret, specificReturn := fake.infoReturnsOnCall[len(fake.infoArgsForCall)]
+	fake.infoArgsForCall = append(fake.infoArgsForCall, struct {
+		ctx       context.Context
+		authToken string
+	}{ctx, authToken})
+	fake.recordInvocation("Info", []interface{}{ctx, authToken})
+	fake.infoMutex.Unlock()
+	if fake.InfoStub != nil {
+		return fake.InfoStub(ctx, authToken)
+	}
+	if specificReturn {
+		return ret.result1, ret.result2
+	}
+	return fake.infoReturns.result1, fake.infoReturns.result2
+}
+
+func (fake *FakeLogClientBuilder) InfoCallCount() int {
+	fake.infoMutex.RLock()
+	defer fake.infoMutex.RUnlock()
+	return len(fake.infoArgsForCall)
+}
+
+func (fake *FakeLogClientBuilder) InfoArgsForCall(i int) (context.Context, string) {
+	fake.infoMutex.RLock()
+	defer fake.infoMutex.RUnlock()
+	return fake.infoArgsForCall[i].ctx, fake.infoArgsForCall[i].authToken
+}
+
+func (fake *FakeLogClientBuilder) InfoReturns(result1 logclient.Info, result2 error) {
+	fake.InfoStub = nil
+	fake.infoReturns = struct {
+		result1 logclient.Info
+		result2 error
+	}{result1, result2}
+}
+
+func (fake *FakeLogClientBuilder) InfoReturnsOnCall(i int, result1 logclient.Info, result2 error) {
+	fake.InfoStub = nil
+	if fake.infoReturnsOnCall == nil {
+		fake.infoReturnsOnCall = make(map[int]struct {
+			result1 logclient.Info
+			result2 error
+		})
+	}
+	fake.infoReturnsOnCall[i] = struct {
+		result1 logclient.Info
+		result2 error
+	}{result1, result2}
+}
+
 func (fake *FakeLogClientBuilder) Build() logclient.LogClient {
 	fake.buildMutex.Lock()
 	ret, specificReturn := fake.buildReturnsOnCall[len(fake.buildArgsForCall)]
@@ -186,6 +431,14 @@ func (fake *FakeLogClientBuilder) Invocations() map[string][][]interface{} {
 	defer fake.endpointMutex.RUnlock()
 	fake.insecureSkipVerifyMutex.RLock()
 	defer fake.insecureSkipVerifyMutex.RUnlock()
+	fake.filtersMutex.RLock()
+	defer fake.filtersMutex.RUnlock()
+	fake.tokenSourceMutex.RLock()
+	defer fake.tokenSourceMutex.RUnlock()
+	fake.recentOrderedMutex.RLock()
+	defer fake.recentOrderedMutex.RUnlock()
+	fake.infoMutex.RLock()
+	defer fake.infoMutex.RUnlock()
 	fake.buildMutex.RLock()
 	defer fake.buildMutex.RUnlock()
 	return fake.invocations
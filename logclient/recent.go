@@ -0,0 +1,200 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package logclient
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"sync/atomic"
+	"time"
+
+	"github.com/cloudfoundry/noaa/consumer"
+	noaa_errors "github.com/cloudfoundry/noaa/errors"
+	"github.com/cloudfoundry/sonde-go/events"
+	"github.com/gogo/protobuf/proto"
+)
+
+func (lc *logClient) RecentLogs(ctx context.Context, serviceGUID string, authToken string) ([]LogRecord, error) {
+	records := []LogRecord{}
+	err := lc.StreamRecentLogs(ctx, serviceGUID, authToken, func(record LogRecord) error {
+		records = append(records, record)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return records, nil
+}
+
+func (lc *logClient) StreamRecentLogs(ctx context.Context, serviceGUID string, authToken string, each func(LogRecord) error) error {
+	err := lc.streamRecentLogs(ctx, serviceGUID, authToken, each)
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+func (lc *logClient) streamRecentLogs(ctx context.Context, serviceGUID string, authToken string, each func(LogRecord) error) error {
+	endpoint, err := url.ParseRequestURI(lc.endpoint)
+	if err != nil {
+		return err
+	}
+	recentURL := recentPathBuilder(endpoint, serviceGUID, "recentlogs") + lc.query
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, recentURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "bearer "+authToken)
+	if lc.debug {
+		dump, _ := httputil.DumpRequest(req, false)
+		(&debugPrinter{}).Print("HTTP REQUEST", string(dump))
+	}
+	resp, err := lc.httpClient.Do(req)
+	if err != nil {
+		return classifyError(fmt.Errorf("Error connecting to the service instance logs endpoint %s: %w", lc.endpoint, err))
+	}
+	defer resp.Body.Close()
+	if lc.debug {
+		// The body is not dumped since it is decoded as it arrives.
+		dump, _ := httputil.DumpResponse(resp, false)
+		(&debugPrinter{}).Print("HTTP RESPONSE", string(dump))
+	}
+	if err := checkRecentLogsResponse(resp); err != nil {
+		return err
+	}
+	resp.Body = newIdleReader(resp.Body, lc.idleTimeout, cancel)
+
+	reader, err := multipartReader(resp)
+	if err != nil {
+		return err
+	}
+
+	// Records received in timestamp order are delivered as soon as they are decoded. Otherwise every record must be
+	// received before the oldest is known, so the records are sorted as received and only decoded once sorted.
+	if lc.recentOrdered {
+		return decodeRecentLogs(reader, func(message *events.LogMessage, _ []byte) error {
+			return each(NewLogRecord(message))
+		})
+	}
+	sorter := newRecordSorter(lc.runSize, lc.tempDir)
+	defer sorter.Close()
+	err = decodeRecentLogs(reader, func(message *events.LogMessage, envelope []byte) error {
+		return sorter.Add(rawRecord{Timestamp: convertTimestampEpochNanosToTime(message), Envelope: bytes.Clone(envelope)})
+	})
+	if err != nil {
+		return err
+	}
+	return sorter.Each(each)
+}
+
+// checkRecentLogsResponse returns a StatusError describing an unsuccessful response, as the noaa consumer does.
+func checkRecentLogsResponse(resp *http.Response) error {
+	var err error
+	switch resp.StatusCode {
+	case http.StatusOK:
+		return nil
+	case http.StatusUnauthorized:
+		data, _ := io.ReadAll(resp.Body)
+		err = noaa_errors.NewUnauthorizedError(string(data))
+	case http.StatusBadRequest:
+		err = consumer.ErrBadRequest
+	default:
+		err = consumer.ErrNotOK
+	}
+	return &StatusError{StatusCode: resp.StatusCode, Err: err}
+}
+
+func multipartReader(resp *http.Response) (*multipart.Reader, error) {
+	_, params, err := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if err != nil || params["boundary"] == "" {
+		return nil, consumer.ErrBadResponse
+	}
+	return multipart.NewReader(resp.Body, params["boundary"]), nil
+}
+
+// idleReader reads a response body, cancelling the request if a read waits for data for longer than the timeout.
+// Time spent between reads, such as while records are being delivered, does not count.
+type idleReader struct {
+	body    io.ReadCloser
+	timeout time.Duration
+	timer   *time.Timer
+	stalled atomic.Bool
+}
+
+func newIdleReader(body io.ReadCloser, timeout time.Duration, cancel context.CancelFunc) *idleReader {
+	r := &idleReader{body: body, timeout: timeout}
+	r.timer = time.AfterFunc(timeout, func() {
+		r.stalled.Store(true)
+		cancel()
+	})
+	r.timer.Stop()
+	return r
+}
+
+func (r *idleReader) Read(p []byte) (int, error) {
+	r.timer.Reset(r.timeout)
+	n, err := r.body.Read(p)
+	r.timer.Stop()
+	if r.stalled.Load() {
+		return n, fmt.Errorf("no data received for %s", r.timeout)
+	}
+	return n, err
+}
+
+func (r *idleReader) Close() error {
+	r.timer.Stop()
+	return r.body.Close()
+}
+
+// decodeRecentLogs decodes the log messages in a multipart recent logs response one part at a time, calling the
+// given function with each and its encoded envelope, which is only valid until the function returns. Parts which
+// are not log message envelopes are skipped.
+func decodeRecentLogs(reader *multipart.Reader, each func(*events.LogMessage, []byte) error) error {
+	var buffer bytes.Buffer
+	var envelope events.Envelope
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("Error reading recent logs: %s", err)
+		}
+
+		buffer.Reset()
+		if _, err := buffer.ReadFrom(part); err != nil {
+			return fmt.Errorf("Error reading recent logs: %s", err)
+		}
+		envelope.Reset()
+		if err := proto.Unmarshal(buffer.Bytes(), &envelope); err != nil || envelope.GetLogMessage() == nil {
+			continue
+		}
+		if err := each(envelope.GetLogMessage(), buffer.Bytes()); err != nil {
+			return err
+		}
+	}
+}
@@ -0,0 +1,301 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package logclient
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/gob"
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/cloudfoundry/sonde-go/events"
+	"github.com/gogo/protobuf/proto"
+)
+
+// DefaultRecentRunSize is the number of recent log records which are sorted in memory before they are spilled to a
+// temporary file.
+const DefaultRecentRunSize = 50000
+
+// mergeFanIn is the most runs which are kept in temporary files before they are merged into one, which bounds the
+// number of open files.
+const mergeFanIn = 32
+
+// rawRecord is a recent log record as received: its timestamp and the encoded envelope of its log message. Only raw
+// records are sorted and spilled, so a run holds nothing which cannot be encoded, and the log record is decoded
+// once it is delivered in timestamp order.
+type rawRecord struct {
+	Timestamp time.Time
+	Envelope  []byte
+}
+
+// decode returns the log record of the raw record.
+func (r rawRecord) decode() (LogRecord, error) {
+	var envelope events.Envelope
+	if err := proto.Unmarshal(r.Envelope, &envelope); err != nil {
+		return LogRecord{}, err
+	}
+	return NewLogRecord(envelope.GetLogMessage()), nil
+}
+
+// recordSorter sorts log records by timestamp using an external merge sort. Records are held in memory until there
+// are runSize of them, whereupon they are sorted and spilled as a run to a temporary file. The runs are merged once
+// every record has been added. The sort is stable, so records with the same timestamp keep the order in which they
+// were added.
+type recordSorter struct {
+	runSize int
+	dir     string
+	records []rawRecord
+	runs    []*os.File
+}
+
+func newRecordSorter(runSize int, dir string) *recordSorter {
+	if runSize <= 0 {
+		runSize = DefaultRecentRunSize
+	}
+	return &recordSorter{runSize: runSize, dir: dir}
+}
+
+// Add adds a record, spilling a run if enough records are held in memory.
+func (s *recordSorter) Add(record rawRecord) error {
+	s.records = append(s.records, record)
+	if len(s.records) < s.runSize {
+		return nil
+	}
+	if err := s.spill(); err != nil {
+		return err
+	}
+	if len(s.runs) >= mergeFanIn {
+		return s.compact()
+	}
+	return nil
+}
+
+// Each calls the given function with every record added, decoded and in timestamp order, stopping at the first
+// error.
+func (s *recordSorter) Each(each func(LogRecord) error) error {
+	deliver := func(raw rawRecord) error {
+		record, err := raw.decode()
+		if err != nil {
+			return err
+		}
+		return each(record)
+	}
+
+	sortRecords(s.records)
+	if len(s.runs) == 0 {
+		for _, record := range s.records {
+			if err := deliver(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	sources := make([]recordSource, 0, len(s.runs)+1)
+	for _, run := range s.runs {
+		source, err := newRunSource(run)
+		if err != nil {
+			return err
+		}
+		sources = append(sources, source)
+	}
+	// Records in memory were added after every spilled run, so come last when timestamps are equal.
+	sources = append(sources, &sliceSource{records: s.records})
+	return merge(sources, deliver)
+}
+
+// Close removes any temporary files.
+func (s *recordSorter) Close() error {
+	var errs []error
+	for _, run := range s.runs {
+		errs = append(errs, run.Close(), os.Remove(run.Name()))
+	}
+	s.runs = nil
+	s.records = nil
+	return errors.Join(errs...)
+}
+
+func (s *recordSorter) spill() error {
+	sortRecords(s.records)
+	run, err := s.writeRun(func(encode func(rawRecord) error) error {
+		for _, record := range s.records {
+			if err := encode(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	s.runs = append(s.runs, run)
+	clear(s.records)
+	s.records = s.records[:0]
+	return nil
+}
+
+// compact merges the spilled runs into a single run.
+func (s *recordSorter) compact() error {
+	sources := make([]recordSource, len(s.runs))
+	for i, run := range s.runs {
+		source, err := newRunSource(run)
+		if err != nil {
+			return err
+		}
+		sources[i] = source
+	}
+	merged, err := s.writeRun(func(encode func(rawRecord) error) error {
+		return merge(sources, encode)
+	})
+	if err != nil {
+		return err
+	}
+	for _, run := range s.runs {
+		run.Close()
+		os.Remove(run.Name())
+	}
+	s.runs = []*os.File{merged}
+	return nil
+}
+
+// writeRun writes the records produced by the given function to a new temporary file, which is left open.
+func (s *recordSorter) writeRun(produce func(encode func(rawRecord) error) error) (*os.File, error) {
+	run, err := os.CreateTemp(s.dir, "service-logs-recent-*.run")
+	if err != nil {
+		return nil, err
+	}
+	w := bufio.NewWriter(run)
+	encoder := gob.NewEncoder(w)
+	err = produce(func(record rawRecord) error {
+		return encoder.Encode(&record)
+	})
+	if err == nil {
+		err = w.Flush()
+	}
+	if err != nil {
+		run.Close()
+		os.Remove(run.Name())
+		return nil, err
+	}
+	return run, nil
+}
+
+func sortRecords(records []rawRecord) {
+	sort.SliceStable(records, func(i, j int) bool {
+		return records[i].Timestamp.Before(records[j].Timestamp)
+	})
+}
+
+// recordSource produces records in timestamp order. Next returns io.EOF once every record has been produced.
+type recordSource interface {
+	Next() (rawRecord, error)
+}
+
+type sliceSource struct {
+	records []rawRecord
+}
+
+func (s *sliceSource) Next() (rawRecord, error) {
+	if len(s.records) == 0 {
+		return rawRecord{}, io.EOF
+	}
+	record := s.records[0]
+	s.records = s.records[1:]
+	return record, nil
+}
+
+type runSource struct {
+	decoder *gob.Decoder
+}
+
+func newRunSource(run *os.File) (*runSource, error) {
+	if _, err := run.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return &runSource{decoder: gob.NewDecoder(bufio.NewReader(run))}, nil
+}
+
+func (s *runSource) Next() (rawRecord, error) {
+	var record rawRecord
+	err := s.decoder.Decode(&record)
+	return record, err
+}
+
+// merge calls the given function with the records of the given sources in timestamp order. Records with the same
+// timestamp are taken from earlier sources first.
+func merge(sources []recordSource, each func(rawRecord) error) error {
+	h := &mergeHeap{}
+	for i, source := range sources {
+		record, err := source.Next()
+		if err == io.EOF {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		heap.Push(h, mergeHead{record: record, source: i})
+	}
+
+	for h.Len() > 0 {
+		head := (*h)[0]
+		if err := each(head.record); err != nil {
+			return err
+		}
+		record, err := sources[head.source].Next()
+		switch {
+		case err == io.EOF:
+			heap.Pop(h)
+		case err != nil:
+			return err
+		default:
+			(*h)[0].record = record
+			heap.Fix(h, 0)
+		}
+	}
+	return nil
+}
+
+type mergeHead struct {
+	record rawRecord
+	source int
+}
+
+type mergeHeap []mergeHead
+
+func (h mergeHeap) Len() int { return len(h) }
+
+func (h mergeHeap) Less(i, j int) bool {
+	if h[i].record.Timestamp.Equal(h[j].record.Timestamp) {
+		return h[i].source < h[j].source
+	}
+	return h[i].record.Timestamp.Before(h[j].record.Timestamp)
+}
+
+func (h mergeHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *mergeHeap) Push(x interface{}) { *h = append(*h, x.(mergeHead)) }
+
+func (h *mergeHeap) Pop() interface{} {
+	old := *h
+	head := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return head
+}
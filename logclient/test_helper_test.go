@@ -1,20 +1,37 @@
 package logclient
 
+import "time"
+
 // Allow logClient consumer to be modified, but only in tests (since the name of this file ends in "...test.go").
 func (lc *logClient) SetConsumer(consumer Consumer) {
 	lc.consumer = consumer
 }
 
-// Allow logClient sorter to be modified, but only in tests (since the name of this file ends in "...test.go").
-func (lc *logClient) SetSorter(sorter Sorter) {
-	lc.sorter = sorter
+// Allow the number of recent log records sorted in memory to be modified, but only in tests.
+func (lc *logClient) SetRecentRunSize(runSize int) {
+	lc.runSize = runSize
+}
+
+// Allow the directory holding the sorted runs of recent log records to be modified, but only in tests.
+func (lc *logClient) SetTempDir(dir string) {
+	lc.tempDir = dir
+}
+
+// Allow the time for which a recent logs response may stall to be modified, but only in tests.
+func (lc *logClient) SetRecentIdleTimeout(timeout time.Duration) {
+	lc.idleTimeout = timeout
 }
 
 type FieldSetter interface {
 	SetConsumer(consumer Consumer)
-	SetSorter(sorter Sorter)
+	SetRecentRunSize(runSize int)
+	SetTempDir(dir string)
+	SetRecentIdleTimeout(timeout time.Duration)
 }
 
 type BuildWithConsumer interface {
 	BuildFromConsumer(cons Consumer) LogClient
 }
+
+// Expose the classification of errors, but only in tests.
+var ClassifyError = classifyError
@@ -0,0 +1,161 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package logging
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+)
+
+// OverflowPolicy determines what happens to tailed log records which arrive when the buffer is full.
+type OverflowPolicy string
+
+const (
+	// Block stops reading log records until there is room in the buffer, which may cause the logs endpoint to drop
+	// the connection.
+	Block OverflowPolicy = "block"
+	// DropOldest discards the oldest buffered log record to make room for the new one.
+	DropOldest OverflowPolicy = "drop-oldest"
+	// DropNewest discards the new log record.
+	DropNewest OverflowPolicy = "drop-newest"
+)
+
+const (
+	// DefaultBufferSize is the number of tailed log records buffered if no size is specified.
+	DefaultBufferSize = 10000
+
+	defaultMarkerInterval = time.Second
+
+	// markerSourceType is the source type of the records which report dropped log records.
+	markerSourceType = "service-logs"
+)
+
+// Buffer holds tailed log records between the log clients, which read them from the logs endpoint, and the sink,
+// so that a burst of logs or a slow sink does not stall the reading of logs. When log records are dropped, a record
+// reporting the number dropped is delivered at most once per marker interval so that users know the logs are
+// incomplete.
+type Buffer struct {
+	size           int
+	policy         OverflowPolicy
+	markerInterval time.Duration
+	now            func() time.Time
+	received       int64
+	dropped        int64
+}
+
+// NewBuffer returns a buffer of the number of log records given by the options, which handles overflow according
+// to the given policy.
+func NewBuffer(options Options) *Buffer {
+	size := options.BufferSize
+	if size <= 0 {
+		size = DefaultBufferSize
+	}
+	policy := options.Overflow
+	if policy == "" {
+		policy = Block
+	}
+	return &Buffer{
+		size:           size,
+		policy:         policy,
+		markerInterval: defaultMarkerInterval,
+		now:            time.Now,
+	}
+}
+
+// Start buffers the log records received from the given channel and returns a channel from which they, and any
+// records reporting dropped log records, may be received. The returned channel is closed once the given channel
+// has closed and every buffered record has been delivered.
+func (b *Buffer) Start(in <-chan logclient.LogRecord) <-chan logclient.LogRecord {
+	out := make(chan logclient.LogRecord)
+	go func() {
+		defer close(out)
+		ticker := time.NewTicker(b.markerInterval)
+		defer ticker.Stop()
+
+		var queue []logclient.LogRecord
+		unreported := 0
+		markerDue := false
+		for in != nil || len(queue) > 0 || markerDue {
+			receive := in
+			if b.policy == Block && len(queue) >= b.size {
+				receive = nil
+			}
+
+			// A marker is delivered ahead of the buffered records, which is where drop-oldest leaves a gap.
+			var send chan<- logclient.LogRecord
+			var next logclient.LogRecord
+			if markerDue {
+				send, next = out, b.marker(unreported)
+			} else if len(queue) > 0 {
+				send, next = out, queue[0]
+			}
+
+			select {
+			case record, ok := <-receive:
+				if !ok {
+					in = nil
+					markerDue = unreported > 0
+					continue
+				}
+				atomic.AddInt64(&b.received, 1)
+				if len(queue) >= b.size {
+					atomic.AddInt64(&b.dropped, 1)
+					unreported++
+					if b.policy == DropNewest {
+						continue
+					}
+					queue[0] = logclient.LogRecord{}
+					queue = queue[1:]
+				}
+				queue = append(queue, record)
+			case send <- next:
+				if markerDue {
+					markerDue = false
+					unreported = 0
+					continue
+				}
+				queue[0] = logclient.LogRecord{}
+				queue = queue[1:]
+			case <-ticker.C:
+				markerDue = unreported > 0
+			}
+		}
+	}()
+	return out
+}
+
+// Received returns the number of log records received by the buffer.
+func (b *Buffer) Received() int {
+	return int(atomic.LoadInt64(&b.received))
+}
+
+// Dropped returns the number of log records dropped because the buffer was full.
+func (b *Buffer) Dropped() int {
+	return int(atomic.LoadInt64(&b.dropped))
+}
+
+func (b *Buffer) marker(dropped int) logclient.LogRecord {
+	return logclient.LogRecord{
+		Timestamp:   b.now(),
+		SourceType:  markerSourceType,
+		MessageType: "ERR",
+		Message:     fmt.Sprintf("%d log message(s) dropped because output could not keep up", dropped),
+	}
+}
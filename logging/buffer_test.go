@@ -0,0 +1,144 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package logging_test
+
+import (
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logging"
+)
+
+var _ = Describe("Buffer", func() {
+	var (
+		options logging.Options
+		buffer  *logging.Buffer
+		in      chan logclient.LogRecord
+		out     <-chan logclient.LogRecord
+		now     time.Time
+
+		markerInterval time.Duration
+	)
+
+	record := func(i int) logclient.LogRecord {
+		return logclient.LogRecord{Message: strconv.Itoa(i)}
+	}
+
+	// send sends the given number of records, which the buffer must accept without blocking.
+	send := func(n int) {
+		for i := 1; i <= n; i++ {
+			Eventually(in).Should(BeSent(record(i)))
+		}
+		Eventually(buffer.Received).Should(Equal(n))
+	}
+
+	receiveAll := func() []string {
+		messages := []string{}
+		for r := range out {
+			messages = append(messages, r.Message)
+		}
+		return messages
+	}
+
+	BeforeEach(func() {
+		options = logging.Options{BufferSize: 2}
+		in = make(chan logclient.LogRecord)
+		now = time.Unix(1000, 0)
+		markerInterval = time.Hour
+	})
+
+	JustBeforeEach(func() {
+		buffer = logging.NewBuffer(options)
+		setter := interface{}(buffer).(logging.BufferFieldSetter)
+		setter.SetMarkerInterval(markerInterval)
+		setter.SetClock(func() time.Time { return now })
+		out = buffer.Start(in)
+	})
+
+	It("should deliver records in order and close once the input has closed", func() {
+		send(2)
+		close(in)
+		Expect(receiveAll()).To(Equal([]string{"1", "2"}))
+		Expect(buffer.Dropped()).To(Equal(0))
+	})
+
+	Context("when the policy is block", func() {
+		It("should stop accepting records while the buffer is full", func() {
+			send(2)
+			Consistently(in, "50ms").ShouldNot(BeSent(record(3)))
+
+			Eventually(out).Should(Receive(Equal(record(1))))
+			Eventually(in).Should(BeSent(record(3)))
+			close(in)
+			Expect(receiveAll()).To(Equal([]string{"2", "3"}))
+			Expect(buffer.Dropped()).To(Equal(0))
+		})
+	})
+
+	Context("when the policy is drop-oldest", func() {
+		BeforeEach(func() {
+			options.Overflow = logging.DropOldest
+		})
+
+		It("should keep the newest records and report the number dropped", func() {
+			send(5)
+			close(in)
+			Expect(receiveAll()).To(Equal([]string{"3 log message(s) dropped because output could not keep up", "4", "5"}))
+			Expect(buffer.Dropped()).To(Equal(3))
+		})
+	})
+
+	Context("when the policy is drop-newest", func() {
+		BeforeEach(func() {
+			options.Overflow = logging.DropNewest
+		})
+
+		It("should keep the oldest records and report the number dropped", func() {
+			send(5)
+			close(in)
+			Expect(receiveAll()).To(Equal([]string{"3 log message(s) dropped because output could not keep up", "1", "2"}))
+			Expect(buffer.Dropped()).To(Equal(3))
+		})
+
+		It("should report dropped records in a marker record", func() {
+			send(3)
+			close(in)
+			var marker logclient.LogRecord
+			Eventually(out).Should(Receive(&marker))
+			Expect(marker.Timestamp).To(Equal(now))
+			Expect(marker.SourceType).To(Equal("service-logs"))
+			Expect(marker.MessageType).To(Equal("ERR"))
+		})
+	})
+
+	Context("when records are dropped while tailing continues", func() {
+		BeforeEach(func() {
+			options = logging.Options{BufferSize: 1, Overflow: logging.DropNewest}
+			markerInterval = 10 * time.Millisecond
+		})
+
+		It("should periodically report the number dropped", func() {
+			send(3)
+			Eventually(out).Should(Receive(HaveField("Message", "2 log message(s) dropped because output could not keep up")))
+			close(in)
+			Expect(receiveAll()).NotTo(ContainElement(ContainSubstring("dropped")))
+		})
+	})
+})
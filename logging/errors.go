@@ -0,0 +1,81 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package logging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrEndpointNotAdvertised is returned when the catalog metadata of a service offering does not advertise a service
+// instance logs endpoint, typically because the service broker predates service instance logs.
+var ErrEndpointNotAdvertised = errors.New("/v2/services did not contain a service instance logs endpoint: maybe the broker version is too old")
+
+// EndpointNotAdvertisedError reports that the catalog metadata of the given service offering does not advertise a
+// service instance logs endpoint. It matches ErrEndpointNotAdvertised.
+type EndpointNotAdvertisedError struct {
+	ServiceGuid string
+}
+
+func (e *EndpointNotAdvertisedError) Error() string {
+	return ErrEndpointNotAdvertised.Error()
+}
+
+func (e *EndpointNotAdvertisedError) Is(target error) bool {
+	return target == ErrEndpointNotAdvertised
+}
+
+// ServiceInstanceError reports an error from the logs service of a service instance. It identifies the service
+// instance and its service offering, so that the error can be diagnosed, but its message is that of the error.
+type ServiceInstanceError struct {
+	ServiceInstance string
+	ServiceGuid     string
+	Err             error
+}
+
+func (e *ServiceInstanceError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ServiceInstanceError) Unwrap() error {
+	return e.Err
+}
+
+// serviceInstanceError wraps an error from the logs service of the given service instance in a ServiceInstanceError.
+// Nil and context errors are returned unchanged.
+func serviceInstanceError(instance serviceInstance, err error) error {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return &ServiceInstanceError{ServiceInstance: instance.name, ServiceGuid: instance.serviceGuid, Err: err}
+}
+
+// UnsupportedError reports that the logs service of a service instance cannot serve the logs requested, as described
+// by its info document.
+type UnsupportedError struct {
+	ServiceInstance string
+	Message         string
+}
+
+func (e *UnsupportedError) Error() string {
+	return e.Message
+}
+
+func unsupported(instance serviceInstance, format string, a ...interface{}) error {
+	return &UnsupportedError{ServiceInstance: instance.name, Message: fmt.Sprintf(format, a...)}
+}
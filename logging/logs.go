@@ -1,130 +1,732 @@
 package logging
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
 	"sync"
+	"time"
 
+	"net/http"
 	"net/url"
 
-	"errors"
-
-	"code.cloudfoundry.org/cli/plugin"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/cache"
 	"github.com/pivotal-cf/service-instance-logs-cli-plugin/cfutil"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/format"
 	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/sink"
 )
 
-func dumpRecentLogs(logClient logclient.LogClient, serviceGUID string, accessToken string, w io.Writer) error {
-	messages, err := logClient.RecentLogs(serviceGUID, accessToken)
+// Options configures the processing of log records. The zero value buffers DefaultBufferSize tailed records, blocks
+// when the buffer is full, delivers tailed records in arrival order and does not join multi-line log entries. A
+// positive ReorderWindow delivers tailed records in timestamp order, as described by Reorder. A non-nil Join joins
+// the lines of multi-line log entries, such as stack traces, into single records, as described by Joiner.
+//
+// Filters, named as in logclient.Info, are passed to the logs services which advertise support for them, so that
+// fewer log records are sent. Other logs services ignore them, so log records must still be filtered by the caller.
+// SkipCapabilityCheck assumes every logs service supports this plugin rather than obtaining, or using cached, info
+// documents, for logs services whose info documents are slow or unavailable. Warn, if not nil, is called with warnings about the capabilities of logs services, such as when a service instance
+// is skipped because its logs service cannot serve the logs requested.
+type Options struct {
+	BufferSize          int
+	Overflow            OverflowPolicy
+	ReorderWindow       time.Duration
+	Join                *JoinOptions
+	Filters             url.Values
+	SkipCapabilityCheck bool
+	Warn                func(message string)
+}
+
+// summary counts the log records received and dropped while obtaining logs.
+type summary struct {
+	received int
+	dropped  int
+}
+
+// dumpRecentLogs writes the recent logs of the given service instances to the given sink as they are received,
+// joining multi-line log entries according to the given options.
+func dumpRecentLogs(ctx context.Context, logClients []logclient.LogClient, instances []serviceInstance, accessToken string, s sink.Sink, options Options) (summary, error) {
+	var dumped summary
+	var joiner *orderedJoiner
+	if options.Join != nil {
+		joiner = newOrderedJoiner(*options.Join)
+	}
+	write := func(records []logclient.LogRecord) error {
+		for _, record := range records {
+			if err := s.Write(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	err := streamRecentLogs(ctx, logClients, instances, accessToken, func(record logclient.LogRecord) error {
+		dumped.received++
+		if joiner == nil {
+			return s.Write(record)
+		}
+		return write(joiner.Add(record))
+	})
 	if err != nil {
-		return err
+		return dumped, err
+	}
+	if joiner != nil {
+		if err := write(joiner.Flush()); err != nil {
+			return dumped, err
+		}
 	}
+	return dumped, s.Flush()
+}
 
-	for _, msg := range messages {
-		fmt.Fprintln(w, msg)
+// recentLogs returns the recent logs of the given service instances, as streamed by streamRecentLogs.
+func recentLogs(ctx context.Context, logClients []logclient.LogClient, instances []serviceInstance, accessToken string) ([]logclient.LogRecord, error) {
+	records := []logclient.LogRecord{}
+	err := streamRecentLogs(ctx, logClients, instances, accessToken, func(record logclient.LogRecord) error {
+		records = append(records, record)
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
+	return records, nil
+}
 
-	return nil
+// recentStreamBufferSize is the number of recent log records of each service instance which may be received ahead
+// of the merge.
+const recentStreamBufferSize = 256
+
+// recentStream carries the recent log records of one service instance to the merge. err is set before records is
+// closed.
+type recentStream struct {
+	name    string
+	records chan logclient.LogRecord
+	err     error
 }
 
-func tailLogs(logClient logclient.LogClient, serviceGUID string, accessToken string, w io.Writer) error {
-	msgChan, errorChan := logClient.TailingLogs(serviceGUID, accessToken)
+// streamRecentLogs calls the given function with the recent logs of the given service instances in timestamp order,
+// stopping at the first error it returns. The logs of several service instances are obtained concurrently, labelled
+// with the service instance name and merged, with records of equal timestamps taken from earlier service instances
+// first.
+func streamRecentLogs(ctx context.Context, logClients []logclient.LogClient, instances []serviceInstance, accessToken string, each func(logclient.LogRecord) error) error {
+	if len(instances) == 1 {
+		return serviceInstanceError(instances[0], logClients[0].StreamRecentLogs(ctx, instances[0].guid, accessToken, each))
+	}
 
-	var wg sync.WaitGroup
-	wg.Add(1)
+	// Cancelling stops the other service instances once the merge stops.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	streams := make([]*recentStream, len(instances))
+	for i, instance := range instances {
+		stream := &recentStream{name: instance.name, records: make(chan logclient.LogRecord, recentStreamBufferSize)}
+		streams[i] = stream
+		logClient := logClients[i]
+		go func() {
+			defer close(stream.records)
+			stream.err = serviceInstanceError(instance, logClient.StreamRecentLogs(ctx, instance.guid, accessToken, func(record logclient.LogRecord) error {
+				record.ServiceInstance = stream.name
+				select {
+				case stream.records <- record:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			}))
+		}()
+	}
 
-	go func() {
-		defer wg.Done()
-		for {
-			msg, ok := <-msgChan
-			if !ok {
-				break
+	// Each service instance's logs are in timestamp order, so the oldest of the next records is the oldest overall.
+	heads := make([]*logclient.LogRecord, len(streams))
+	next := func(i int) error {
+		record, ok := <-streams[i].records
+		if ok {
+			heads[i] = &record
+			return nil
+		}
+		heads[i] = nil
+		if err := streams[i].err; err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
 			}
-			fmt.Fprintf(w, "%s\n", msg)
+			return fmt.Errorf("service instance %s: %w", streams[i].name, err)
 		}
-	}()
+		return nil
+	}
+	for i := range streams {
+		if err := next(i); err != nil {
+			return err
+		}
+	}
+	for {
+		oldest := -1
+		for i, head := range heads {
+			if head != nil && (oldest < 0 || head.Timestamp.Before(heads[oldest].Timestamp)) {
+				oldest = i
+			}
+		}
+		if oldest < 0 {
+			return nil
+		}
+		if err := each(*heads[oldest]); err != nil {
+			return err
+		}
+		if err := next(oldest); err != nil {
+			return err
+		}
+	}
+}
 
-	exitChan := make(chan error, 1)
-	errorChanNotOK := errors.New("errorChan not OK")
-	go func() {
-		for {
-			err, ok := <-errorChan
+// tailLogs writes the tailed logs of the given service instances to the given sink until the logs end, an error
+// occurs or the context is cancelled. Log records are joined, reordered and buffered according to the given
+// options. On an error, tailing is cancelled. In every case, the log and error channels are drained so that the log
+// clients can finish and, unless an error occurred, records received before tailing stopped are written.
+func tailLogs(ctx context.Context, logClients []logclient.LogClient, instances []serviceInstance, accessToken string, s sink.Sink, options Options) (summary, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	buffer, msgChan, errorChan := startTailing(ctx, logClients, instances, accessToken, options)
+
+	var exitErr error
+	for msgChan != nil || errorChan != nil {
+		select {
+		case msg, ok := <-msgChan:
 			if !ok {
-				exitChan <- errorChanNotOK
-				break
+				msgChan = nil
+				continue
 			}
-
-			if !strings.Contains(err.Error(), "1006") {
-				exitChan <- err
-				break
+			if exitErr != nil {
+				continue
+			}
+			if err := s.Write(msg); err != nil {
+				exitErr = err
+				cancel()
+			}
+		case err, ok := <-errorChan:
+			if !ok {
+				errorChan = nil
+				continue
+			}
+			if exitErr == nil && !isAbnormalClosure(err) {
+				// The errors of several service instances identify the service instance already.
+				if len(instances) == 1 {
+					err = serviceInstanceError(instances[0], err)
+				}
+				exitErr = err
+				cancel()
 			}
 		}
-	}()
+	}
 
-	exitErr := <-exitChan
-	if exitErr != errorChanNotOK {
-		return exitErr
+	tailed := summary{received: buffer.Received(), dropped: buffer.Dropped()}
+	if exitErr != nil {
+		return tailed, exitErr
 	}
+	return tailed, s.Flush()
+}
 
-	wg.Wait()
+// startTailing starts tailing the logs of the given service instances and processing the log records according to
+// the given options. It returns the buffer through which the records pass, so that they can be counted, along with
+// the buffered records and the errors.
+func startTailing(ctx context.Context, logClients []logclient.LogClient, instances []serviceInstance, accessToken string, options Options) (*Buffer, <-chan logclient.LogRecord, <-chan error) {
+	buffer := NewBuffer(options)
+	recordChan, errorChan := tailingLogs(ctx, logClients, instances, accessToken)
+	if options.Join != nil {
+		recordChan = Join(recordChan, *options.Join)
+	}
+	if options.ReorderWindow > 0 {
+		recordChan = Reorder(recordChan, options.ReorderWindow)
+	}
+	return buffer, buffer.Start(recordChan), errorChan
+}
 
-	return nil
+// isAbnormalClosure determines whether the given error reports an abnormal closure of a websocket connection.
+// Abnormal closure is reported while the connection is retried, so is not a reason to stop tailing.
+func isAbnormalClosure(err error) bool {
+	return strings.Contains(err.Error(), "1006")
+}
+
+// tailingLogs starts tailing the logs of the given service instances. The logs of several service instances are
+// labelled with the service instance name and merged into a single channel, as are their errors. Each merged
+// channel is closed once all the corresponding channels have closed.
+func tailingLogs(ctx context.Context, logClients []logclient.LogClient, instances []serviceInstance, accessToken string) (<-chan logclient.LogRecord, <-chan error) {
+	if len(instances) == 1 {
+		return logClients[0].TailingLogs(ctx, instances[0].guid, accessToken)
+	}
+
+	recordChan := make(chan logclient.LogRecord)
+	errorChan := make(chan error)
+	var records, errs sync.WaitGroup
+	for i, instance := range instances {
+		instanceRecordChan, instanceErrorChan := logClients[i].TailingLogs(ctx, instance.guid, accessToken)
+		name := instance.name
+		records.Add(1)
+		go func() {
+			defer records.Done()
+			for msg := range instanceRecordChan {
+				msg.ServiceInstance = name
+				recordChan <- msg
+			}
+		}()
+		errs.Add(1)
+		go func() {
+			defer errs.Done()
+			for err := range instanceErrorChan {
+				if err == nil {
+					continue
+				}
+				errorChan <- fmt.Errorf("service instance %s: %w", name, serviceInstanceError(instance, err))
+			}
+		}()
+	}
+	go func() {
+		records.Wait()
+		close(recordChan)
+	}()
+	go func() {
+		errs.Wait()
+		close(errorChan)
+	}()
+	return recordChan, errorChan
 }
 
-func Logs(cliConnection plugin.CliConnection, w io.Writer, serviceInstanceName string, recent bool, logClientBuilder logclient.LogClientBuilder) error {
-	// get service GUID from service instance name
-	model, err := cliConnection.GetService(serviceInstanceName)
+// Logs writes the recent or tailed logs of the service instances identified by the given selector to the given
+// sink. Progress output is written to the given writer. Service instances are discovered using the given cache.
+// Logs are processed according to the given options.
+//
+// If the context is cancelled, such as when the user interrupts the plugin, log records already received are
+// written, a summary is written to the given writer and nil is returned.
+func Logs(ctx context.Context, cliConnection cfutil.Connection, w io.Writer, s sink.Sink, selector cfutil.Selector, discoveryCache cache.Cache, recent bool, options Options, logClientBuilder logclient.LogClientBuilder) error {
+	instances, accessToken, err := resolveServiceInstances(cliConnection, selector, discoveryCache)
 	if err != nil {
 		return err
 	}
 
-	serviceInstanceGUID := model.Guid
+	warn := func(message string) {
+		fmt.Fprintf(w, "%s %s\n", format.Bold("Warning:"), message)
+	}
+	if !recent {
+		logClientBuilder = logClientBuilder.TokenSource(tokenSource(cliConnection))
+	}
+	options.Warn = warn
+	resolved := instances
+	instances, logClients, err := newLogClients(ctx, instances, accessToken, recent, options, discoveryCache, logClientBuilder)
+	if err != nil {
+		invalidate(resolved, discoveryCache)
+		return err
+	}
+
+	if len(instances) > 1 {
+		names := make([]string, len(instances))
+		for i, instance := range instances {
+			names[i] = format.Bold(format.Cyan(instance.name))
+		}
+		fmt.Fprintf(w, "Service instances: %s\n", strings.Join(names, ", "))
+	}
+
+	for _, instance := range instances {
+		if instance.sharedFrom != nil {
+			fmt.Fprintf(w, "Service instance %s is shared from org %s / space %s\n", format.Bold(format.Cyan(instance.name)),
+				format.Bold(format.Cyan(instance.sharedFrom.OrgName)), format.Bold(format.Cyan(instance.sharedFrom.SpaceName)))
+		}
+		if recent && instance.info.MaxRecentWindow > 0 {
+			fmt.Fprintf(w, "Recent logs of service instance %s cover at most %s\n", format.Bold(format.Cyan(instance.name)), instance.info.MaxRecentWindow)
+		}
+	}
+
+	// Print a blank line.
+	fmt.Fprintln(w)
+
+	start := time.Now()
+	var obtained summary
+	if recent {
+		obtained, err = dumpRecentLogs(ctx, logClients, instances, accessToken, s, options)
+	} else {
+		obtained, err = tailLogs(ctx, logClients, instances, accessToken, s, options)
+	}
+	if ctx.Err() != nil {
+		s.Flush()
+		dropped := ""
+		if obtained.dropped > 0 {
+			dropped = fmt.Sprintf(", %d dropped", obtained.dropped)
+		}
+		fmt.Fprintf(w, "\nInterrupted after %s: received %d log message(s)%s.\n", time.Since(start).Round(time.Second), obtained.received, dropped)
+		return nil
+	}
+	if endpointFailed(err) {
+		invalidate(instances, discoveryCache)
+	}
+	return err
+}
+
+// RecentLogRecords returns the recent logs of the service instances identified by the given selector, oldest first.
+// Service instances are discovered using the given cache. If the context is cancelled before the logs are received,
+// the context's error is returned.
+func RecentLogRecords(ctx context.Context, cliConnection cfutil.Connection, selector cfutil.Selector, discoveryCache cache.Cache, logClientBuilder logclient.LogClientBuilder) ([]logclient.LogRecord, error) {
+	instances, accessToken, err := resolveServiceInstances(cliConnection, selector, discoveryCache)
+	if err != nil {
+		return nil, err
+	}
 
-	// obtain the service plan for the specific instance. This is needed when multiple service brokers provide the same service with the same label
-	output, err := cliConnection.CliCommandWithoutTerminalOutput("curl", fmt.Sprintf("/v2/service_plans/%s", model.ServicePlan.Guid))
+	resolved := instances
+	instances, logClients, err := newLogClients(ctx, instances, accessToken, true, Options{}, discoveryCache, logClientBuilder)
+	if err != nil {
+		invalidate(resolved, discoveryCache)
+		return nil, err
+	}
+	records, err := recentLogs(ctx, logClients, instances, accessToken)
+	if ctx.Err() == nil && endpointFailed(err) {
+		invalidate(instances, discoveryCache)
+	}
+	return records, err
+}
 
+// TailLogRecords starts tailing the logs of the service instances identified by the given selector. Service
+// instances are discovered using the given cache. Log records are processed according to the given options.
+//
+// Tailing continues until the context is cancelled, whereupon the records already received are delivered and both
+// channels are closed, so callers must receive from both until they are closed. Errors do not stop tailing and
+// abnormal closures, which are reported while the connection is retried, are not delivered.
+func TailLogRecords(ctx context.Context, cliConnection cfutil.Connection, selector cfutil.Selector, discoveryCache cache.Cache, options Options, logClientBuilder logclient.LogClientBuilder) (<-chan logclient.LogRecord, <-chan error, error) {
+	instances, accessToken, err := resolveServiceInstances(cliConnection, selector, discoveryCache)
 	if err != nil {
-		return fmt.Errorf("/v2/service_plans failed: %w", err)
+		return nil, nil, err
 	}
 
-	var servicePlan ServicePlanStructure
-	err = json.Unmarshal([]byte(strings.Join(output, "\n")), &servicePlan)
+	logClientBuilder = logClientBuilder.TokenSource(tokenSource(cliConnection))
+	resolved := instances
+	instances, logClients, err := newLogClients(ctx, instances, accessToken, false, options, discoveryCache, logClientBuilder)
 	if err != nil {
-		return fmt.Errorf("/v2/service_plan returned invalid JSON: %s", err)
+		invalidate(resolved, discoveryCache)
+		return nil, nil, err
+	}
+
+	_, recordChan, errorChan := startTailing(ctx, logClients, instances, accessToken, options)
+	errChan := make(chan error)
+	go func() {
+		defer close(errChan)
+		for err := range errorChan {
+			if !isAbnormalClosure(err) {
+				errChan <- err
+			}
+		}
+	}()
+	return recordChan, errChan, nil
+}
+
+// newLogClients builds a log client for the logs endpoint of each of the given service instances, either for
+// obtaining recent logs or for tailing. The capabilities of each logs service are first obtained from its info
+// document, unless they are in the given cache or the options skip the check, and recorded in the returned service
+// instances. The filters in the options which a logs service supports are passed to it, and recent logs which a logs
+// service sends in timestamp order are not sorted again.
+//
+// A service instance whose logs service cannot serve the logs requested is skipped with a warning if other service
+// instances remain, and otherwise causes an error describing what the logs service lacks. Warnings are passed to the
+// Warn function of the options, unless it is nil.
+func newLogClients(ctx context.Context, instances []serviceInstance, accessToken string, recent bool, options Options, discoveryCache cache.Cache, logClientBuilder logclient.LogClientBuilder) ([]serviceInstance, []logclient.LogClient, error) {
+	warn := options.Warn
+	if warn == nil {
+		warn = func(string) {}
+	}
+	recorded := false
+	defer func() {
+		if recorded {
+			discoveryCache.Save()
+		}
+	}()
+
+	var (
+		usable     []serviceInstance
+		logClients []logclient.LogClient
+		skipped    []string
+		firstErr   error
+	)
+	for _, instance := range instances {
+		serviceInstanceLogsEndpoint := instance.logsEndpoint
+		if !recent {
+			var err error
+			serviceInstanceLogsEndpoint, err = ConvertServiceInstanceLogsEndpoint(serviceInstanceLogsEndpoint)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		builder := logClientBuilder.Endpoint(serviceInstanceLogsEndpoint)
+
+		var info logclient.Info
+		switch {
+		case options.SkipCapabilityCheck:
+		case instance.cachedInfo != nil:
+			info = *instance.cachedInfo
+		default:
+			var err error
+			info, err = builder.Info(ctx, accessToken)
+			if err != nil {
+				warn(fmt.Sprintf("Could not obtain the capabilities of the logs service of service instance %s, so assuming it supports this plugin: %s", instance.name, err))
+				info = logclient.Info{}
+				break
+			}
+			instance.cachedInfo = &info
+			for _, key := range instance.cacheKeys {
+				discoveryCache.Put(key, instance.entry())
+			}
+			recorded = true
+		}
+		if err := checkCapabilities(instance, info, recent); err != nil {
+			skipped = append(skipped, fmt.Sprintf("Skipping service instance %s: %s", instance.name, err))
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+
+		if supported := supportedFilters(info, options.Filters); len(supported) > 0 {
+			builder = builder.Filters(supported)
+		}
+		if recent && info.RecentOrdered {
+			builder = builder.RecentOrdered(true)
+		}
+		instance.info = info
+		usable = append(usable, instance)
+		logClients = append(logClients, builder.Build())
+	}
+	if len(usable) == 0 {
+		return nil, nil, firstErr
+	}
+	for _, message := range skipped {
+		warn(message)
+	}
+	return usable, logClients, nil
+}
+
+// checkCapabilities returns an error if the logs service described by the given info cannot serve the logs of the
+// given service instance which are requested.
+func checkCapabilities(instance serviceInstance, info logclient.Info, recent bool) error {
+	if !info.SupportsProtocol(logclient.ProtocolVersion) {
+		versions := make([]string, len(info.ProtocolVersions))
+		for i, v := range info.ProtocolVersions {
+			versions[i] = fmt.Sprint(v)
+		}
+		return unsupported(instance, "The logs service of service instance %s implements protocol versions %s, but this plugin implements version %d. Check for a newer version of the plugin.",
+			instance.name, strings.Join(versions, ", "), logclient.ProtocolVersion)
+	}
+	if recent && !info.SupportsBackend(logclient.BackendRecent) {
+		return unsupported(instance, "The logs service of service instance %s does not serve recent logs, so --recent is not supported. Omit --recent to tail its logs.", instance.name)
 	}
+	if !recent && !info.SupportsBackend(logclient.BackendStream) {
+		return unsupported(instance, "The logs service of service instance %s does not support tailing logs. Use --recent to show its recent logs.", instance.name)
+	}
+	return nil
+}
 
-	serviceGuid := servicePlan.Entity.ServiceGuid
+// supportedFilters returns those of the given filters which the logs service described by the given info supports.
+func supportedFilters(info logclient.Info, filters url.Values) url.Values {
+	supported := url.Values{}
+	for name, values := range filters {
+		if info.SupportsFilter(name) {
+			supported[name] = values
+		}
+	}
+	return supported
+}
+
+// serviceInstance identifies a service instance and the endpoint which serves its logs. cacheKeys are the keys
+// of the cache entries from which, or into which, the instance was discovered. cachedInfo is the info document of
+// the logs service recorded in the cache, if any, and info describes the capabilities of the logs service once they
+// have been obtained.
+type serviceInstance struct {
+	name            string
+	guid            string
+	servicePlanGuid string
+	serviceGuid     string
+	logsEndpoint    string
+	sharedFrom      *cfutil.SharedFrom
+	cacheKeys       []string
+	cachedInfo      *logclient.Info
+	info            logclient.Info
+}
+
+// resolveServiceInstances looks up the service instances identified by the given selector and obtains an access
+// token for their logs endpoints. Discovery results are taken from, and recorded in, the given cache.
+func resolveServiceInstances(cliConnection cfutil.Connection, selector cfutil.Selector, discoveryCache cache.Cache) ([]serviceInstance, string, error) {
+	instances, err := discoverServiceInstances(cliConnection, selector, discoveryCache)
+	if err != nil {
+		return nil, "", err
+	}
 
 	// get auth token
 	accessToken, err := cfutil.GetToken(cliConnection)
 	if err != nil {
-		return err
+		return nil, "", err
 	}
 
-	serviceInstanceLogsEndpoint, err := obtainServiceInstanceLogsEndpoint(cliConnection, serviceGuid)
+	return instances, accessToken, nil
+}
+
+// tokenSource returns a source of current access tokens from the given connection, so that tailing can reconnect
+// after the access token it started with has expired.
+func tokenSource(cliConnection cfutil.Connection) logclient.TokenSource {
+	return func() (string, error) {
+		return cfutil.GetToken(cliConnection)
+	}
+}
+
+func discoverServiceInstances(cliConnection cfutil.Connection, selector cfutil.Selector, discoveryCache cache.Cache) ([]serviceInstance, error) {
+	key, err := cacheKey(cliConnection, selector)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if key != "" {
+		if entry, ok := discoveryCache.Get(key); ok {
+			return []serviceInstance{newServiceInstance(entry, key)}, nil
+		}
 	}
 
-	if !recent {
-		serviceInstanceLogsEndpoint, err = convertServiceInstanceLogsEndpoint(serviceInstanceLogsEndpoint)
+	instances, err := discover(cliConnection, selector, discoveryCache)
+	if err != nil {
+		if key != "" {
+			discoveryCache.Delete(key)
+		}
+		discoveryCache.Save()
+		return nil, err
+	}
+
+	for i, instance := range instances {
+		entry := instance.entry()
+		if key != "" {
+			discoveryCache.Put(key, entry)
+			instances[i].cacheKeys = append(instances[i].cacheKeys, key)
+		}
+		discoveryCache.Put(guidCacheKey(instance.guid), entry)
+	}
+	// Failing to save the cache only costs a later invocation time, so should not prevent access to logs.
+	discoveryCache.Save()
+
+	return instances, nil
+}
+
+// discover looks up the service instances identified by the given selector and the logs endpoints of their
+// service offerings. Cached logs endpoints are used for instances whose service plan has not changed.
+func discover(cliConnection cfutil.Connection, selector cfutil.Selector, discoveryCache cache.Cache) ([]serviceInstance, error) {
+	models, err := cfutil.GetServiceInstances(cliConnection, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	instances := make([]serviceInstance, len(models))
+	for i, model := range models {
+		guidKey := guidCacheKey(model.Guid)
+		if entry, ok := discoveryCache.Get(guidKey); ok && entry.ServicePlanGuid == model.ServicePlanGuid {
+			entry.Name = model.Name
+			entry.SharedFrom = model.SharedFrom
+			instances[i] = newServiceInstance(entry, guidKey)
+			continue
+		}
+
+		serviceGuid, err := ObtainServiceGuid(cliConnection, model.ServicePlanGuid)
 		if err != nil {
-			return err
+			discoveryCache.Delete(guidKey)
+			return nil, err
+		}
+
+		serviceInstanceLogsEndpoint, err := ObtainServiceInstanceLogsEndpoint(cliConnection, serviceGuid)
+		if err != nil {
+			discoveryCache.Delete(guidKey)
+			return nil, err
+		}
+
+		instances[i] = serviceInstance{
+			name:            model.Name,
+			guid:            model.Guid,
+			servicePlanGuid: model.ServicePlanGuid,
+			serviceGuid:     serviceGuid,
+			logsEndpoint:    serviceInstanceLogsEndpoint,
+			sharedFrom:      model.SharedFrom,
+			cacheKeys:       []string{guidKey},
 		}
 	}
+	return instances, nil
+}
 
-	logClient := logClientBuilder.Endpoint(serviceInstanceLogsEndpoint).Build()
+// cacheKey returns the key under which the discovery result for the given selector is cached, or an empty string
+// if the result should not be cached. The instances matching a label selector change too readily to be cached.
+func cacheKey(cliConnection cfutil.Connection, selector cfutil.Selector) (string, error) {
+	switch {
+	case selector.Guid != "":
+		return guidCacheKey(selector.Guid), nil
+	case selector.LabelSelector != "":
+		return "", nil
+	case selector.Target.Space != "":
+		org := selector.Target.Org
+		if org == "" {
+			model, err := cliConnection.GetCurrentOrg()
+			if err != nil {
+				return "", err
+			}
+			org = model.Name
+		}
+		return fmt.Sprintf("org:%s/space:%s/name:%s", org, selector.Target.Space, selector.Name), nil
+	default:
+		space, err := cliConnection.GetCurrentSpace()
+		if err != nil {
+			return "", err
+		}
+		if space.Guid == "" {
+			return "", nil
+		}
+		return fmt.Sprintf("space-guid:%s/name:%s", space.Guid, selector.Name), nil
+	}
+}
 
-	// Print a blank line.
-	fmt.Fprintln(w)
+func guidCacheKey(guid string) string {
+	return "guid:" + guid
+}
 
-	if recent {
-		return dumpRecentLogs(logClient, serviceInstanceGUID, accessToken, w)
+func newServiceInstance(entry cache.Entry, key string) serviceInstance {
+	return serviceInstance{
+		name:            entry.Name,
+		guid:            entry.Guid,
+		servicePlanGuid: entry.ServicePlanGuid,
+		serviceGuid:     entry.ServiceGuid,
+		logsEndpoint:    entry.LogsEndpoint,
+		sharedFrom:      entry.SharedFrom,
+		cacheKeys:       []string{key},
+		cachedInfo:      entry.Info,
 	}
+}
 
-	return tailLogs(logClient, serviceInstanceGUID, accessToken, w)
+func (instance serviceInstance) entry() cache.Entry {
+	return cache.Entry{
+		Name:            instance.name,
+		Guid:            instance.guid,
+		ServicePlanGuid: instance.servicePlanGuid,
+		ServiceGuid:     instance.serviceGuid,
+		LogsEndpoint:    instance.logsEndpoint,
+		SharedFrom:      instance.sharedFrom,
+		Info:            instance.cachedInfo,
+	}
+}
+
+// endpointFailed reports whether the given error shows that a logs endpoint could not be found or reached, which
+// suggests it has moved, as opposed to a failure elsewhere, such as in a sink.
+func endpointFailed(err error) bool {
+	var statusErr *logclient.StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode == http.StatusNotFound
+	}
+	return errors.As(err, new(*logclient.ConnectionError)) || errors.As(err, new(*logclient.TLSError))
+}
+
+// invalidate removes the cached discovery results for the given service instances, such as when their logs
+// endpoint fails, perhaps because it has moved.
+func invalidate(instances []serviceInstance, discoveryCache cache.Cache) {
+	for _, instance := range instances {
+		for _, key := range instance.cacheKeys {
+			discoveryCache.Delete(key)
+		}
+	}
+	discoveryCache.Save()
 }
 
 type ServiceStructure struct {
@@ -136,6 +738,7 @@ type ServicePlanStructure struct {
 }
 
 type ServicePlanEntity struct {
+	Name        string `json:"name"`
 	ServiceGuid string `json:"service_guid"`
 }
 
@@ -144,6 +747,7 @@ type ResourceStructure struct {
 }
 
 type EntityStructure struct {
+	Label string
 	Extra string
 }
 
@@ -151,11 +755,31 @@ type ExtraStructure struct {
 	ServiceInstanceLogsEndpoint string
 }
 
-func obtainServiceInstanceLogsEndpoint(cliConnection plugin.CliConnection, serviceGuid string) (string, error) {
+// ObtainServiceGuid returns the GUID of the service offering to which the given service plan belongs. The plan
+// rather than the offering label is used since several service brokers may provide offerings with the same label.
+func ObtainServiceGuid(cliConnection cfutil.Connection, servicePlanGuid string) (string, error) {
+	output, err := cliConnection.CliCommandWithoutTerminalOutput("curl", fmt.Sprintf("/v2/service_plans/%s", servicePlanGuid))
+
+	if err != nil {
+		return "", fmt.Errorf("/v2/service_plans failed: %w", err)
+	}
+
+	var servicePlan ServicePlanStructure
+	err = json.Unmarshal([]byte(strings.Join(output, "\n")), &servicePlan)
+	if err != nil {
+		return "", fmt.Errorf("/v2/service_plan returned invalid JSON: %s", err)
+	}
+
+	return servicePlan.Entity.ServiceGuid, nil
+}
+
+// ObtainServiceInstanceLogsEndpoint returns the service instance logs endpoint advertised in the catalog
+// metadata of the given service offering.
+func ObtainServiceInstanceLogsEndpoint(cliConnection cfutil.Connection, serviceGuid string) (string, error) {
 	output, err := cliConnection.CliCommandWithoutTerminalOutput("curl", fmt.Sprintf("/v2/services/%s", serviceGuid))
 
 	if err != nil {
-		return "", fmt.Errorf("/v2/services failed: %s", err)
+		return "", fmt.Errorf("/v2/services failed: %w", err)
 	}
 
 	var service ServiceStructure
@@ -164,20 +788,32 @@ func obtainServiceInstanceLogsEndpoint(cliConnection plugin.CliConnection, servi
 		return "", fmt.Errorf("/v2/services returned invalid JSON: %s", err)
 	}
 
+	endpoint, err := service.ServiceInstanceLogsEndpoint()
+	if err == ErrEndpointNotAdvertised {
+		return "", &EndpointNotAdvertisedError{ServiceGuid: serviceGuid}
+	}
+	return endpoint, err
+}
+
+// ServiceInstanceLogsEndpoint returns the service instance logs endpoint advertised in the 'extra' field of the
+// service offering.
+func (service ServiceStructure) ServiceInstanceLogsEndpoint() (string, error) {
 	var extra ExtraStructure
-	err = json.Unmarshal([]byte(service.Entity.Extra), &extra)
+	err := json.Unmarshal([]byte(service.Entity.Extra), &extra)
 	if err != nil {
 		return "", fmt.Errorf("/v2/services 'extra' field contained invalid JSON: %s", err)
 	}
 
 	if extra.ServiceInstanceLogsEndpoint == "" {
-		return "", fmt.Errorf("/v2/services did not contain a service instance logs endpoint: maybe the broker version is too old")
+		return "", ErrEndpointNotAdvertised
 	}
 
 	return extra.ServiceInstanceLogsEndpoint, nil
 }
 
-func convertServiceInstanceLogsEndpoint(endpoint string) (string, error) {
+// ConvertServiceInstanceLogsEndpoint converts a service instance logs endpoint into the websocket URL used for
+// tailing logs.
+func ConvertServiceInstanceLogsEndpoint(endpoint string) (string, error) {
 	u, err := url.Parse(endpoint)
 	if err != nil {
 		return "", err
@@ -1,7 +1,11 @@
 package logging_test
 
 import (
+	"bytes"
+	"context"
 	"errors"
+	"net/http"
+	"net/url"
 	"sync"
 	"time"
 
@@ -10,8 +14,12 @@ import (
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/onsi/gomega/gbytes"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/cache"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/cfutil"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
 	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient/logclientfakes"
 	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logging"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/sink"
 )
 
 var _ = Describe("Logs", func() {
@@ -34,6 +42,10 @@ var _ = Describe("Logs", func() {
 		abnormalCloseTestError error
 		output                 *gbytes.Buffer
 		servicePlanOutput      []string
+		target                 cfutil.Target
+		discoveryCache         cache.Cache
+		ctx                    context.Context
+		options                logging.Options
 	)
 
 	BeforeEach(func() {
@@ -43,8 +55,13 @@ var _ = Describe("Logs", func() {
 		fakeLogClientBuilder = &logclientfakes.FakeLogClientBuilder{}
 		fakeLogClient = &logclientfakes.FakeLogClient{}
 		fakeLogClientBuilder.EndpointReturns(fakeLogClientBuilder)
+		fakeLogClientBuilder.TokenSourceReturns(fakeLogClientBuilder)
 		fakeLogClientBuilder.BuildReturns(fakeLogClient)
 		recent = true
+		target = cfutil.Target{}
+		discoveryCache = cache.None()
+		ctx = context.Background()
+		options = logging.Options{}
 		testError = errors.New(errMessage)
 		abnormalCloseTestError = errors.New(abnormalCloseErrMessage)
 		output = gbytes.NewBuffer()
@@ -78,7 +95,7 @@ var _ = Describe("Logs", func() {
 	})
 
 	JustBeforeEach(func() {
-		err = logging.Logs(fakeCliConnection, output, serviceInstanceName, recent, fakeLogClientBuilder)
+		err = logging.Logs(ctx, fakeCliConnection, output, sink.NewWriterSink(output), cfutil.Selector{Target: target, Name: serviceInstanceName}, discoveryCache, recent, options, fakeLogClientBuilder)
 	})
 
 	Context("when obtaining the service instance GUID returns an error", func() {
@@ -87,7 +104,7 @@ var _ = Describe("Logs", func() {
 		})
 
 		It("should propagate the error", func() {
-			Expect(err).To(Equal(testError))
+			Expect(err).To(MatchError(testError))
 		})
 	})
 
@@ -103,7 +120,12 @@ var _ = Describe("Logs", func() {
 
 	Context("when obtaining service plans returns an error", func() {
 		BeforeEach(func() {
-			fakeCliConnection.CliCommandWithoutTerminalOutputReturns([]string{}, testError)
+			fakeCliConnection.CliCommandWithoutTerminalOutputStub = func(args ...string) ([]string, error) {
+				if args[1] == "/v2/service_plans/"+servicePlanGuid {
+					return []string{}, testError
+				}
+				return nil, nil
+			}
 		})
 
 		It("should propagate the error", func() {
@@ -113,8 +135,7 @@ var _ = Describe("Logs", func() {
 
 	Context("when service plans output is malformed JSON", func() {
 		BeforeEach(func() {
-			output := []string{`{`}
-			fakeCliConnection.CliCommandWithoutTerminalOutputReturns(output, nil)
+			servicePlanOutput = []string{`{`}
 		})
 
 		It("should return a suitable error", func() {
@@ -210,6 +231,8 @@ var _ = Describe("Logs", func() {
 		})
 
 		It("should return a suitable error", func() {
+			Expect(err).To(MatchError(logging.ErrEndpointNotAdvertised))
+			Expect(err).To(Equal(&logging.EndpointNotAdvertisedError{ServiceGuid: "aaaa-bbbb-cccc-dddd"}))
 			Expect(err).To(MatchError("/v2/services did not contain a service instance logs endpoint: maybe the broker version is too old"))
 		})
 	})
@@ -220,27 +243,193 @@ var _ = Describe("Logs", func() {
 		})
 	})
 
+	Context("when an org and space are given", func() {
+		BeforeEach(func() {
+			target = cfutil.Target{Org: "other-org", Space: "other-space"}
+			servicesStub := fakeCliConnection.CliCommandWithoutTerminalOutputStub
+			fakeCliConnection.CliCommandWithoutTerminalOutputStub = func(args ...string) ([]string, error) {
+				switch args[1] {
+				case "/v2/organizations?q=name%3Aother-org":
+					return []string{`{"resources": [{"metadata": {"guid": "org-guid"}, "entity": {}}]}`}, nil
+				case "/v2/organizations/org-guid/spaces?q=name%3Aother-space":
+					return []string{`{"resources": [{"metadata": {"guid": "space-guid"}, "entity": {}}]}`}, nil
+				case "/v2/spaces/space-guid/service_instances?q=name%3Asiname":
+					return []string{`{"resources": [{"metadata": {"guid": "other-instance-guid"}, "entity": {"service_plan_guid": "` + servicePlanGuid + `"}}]}`}, nil
+				default:
+					return servicesStub(args...)
+				}
+			}
+		})
+
+		It("should look up the service instance in the given space", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeCliConnection.GetServiceCallCount()).To(Equal(0))
+			_, guid, _, _ := fakeLogClient.StreamRecentLogsArgsForCall(0)
+			Expect(guid).To(Equal("other-instance-guid"))
+		})
+	})
+
+	Context("when the service instance is shared from another space", func() {
+		BeforeEach(func() {
+			fakeCliConnection.GetCurrentSpaceReturns(plugin_models.Space{SpaceFields: plugin_models.SpaceFields{Guid: "current-space-guid"}}, nil)
+			servicesStub := fakeCliConnection.CliCommandWithoutTerminalOutputStub
+			fakeCliConnection.CliCommandWithoutTerminalOutputStub = func(args ...string) ([]string, error) {
+				switch args[1] {
+				case "/v2/service_instances/" + serviceGUID + "/shared_from":
+					return []string{`{"space_guid": "owner-space-guid", "space_name": "platform", "organization_name": "platform-org"}`}, nil
+				case "/v2/spaces/owner-space-guid":
+					return []string{`{"entity": {}}`}, nil
+				case "/v2/service_instances/" + serviceGUID:
+					return []string{`{"entity": {"service_plan_guid": "` + servicePlanGuid + `"}}`}, nil
+				default:
+					return servicesStub(args...)
+				}
+			}
+		})
+
+		It("should report the owning space", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output).To(gbytes.Say("Service instance .*siname.* is shared from org .*platform-org.* / space .*platform"))
+		})
+	})
+
+	Context("when discovery results are cached", func() {
+		var cacheDir string
+
+		BeforeEach(func() {
+			cacheDir = GinkgoT().TempDir()
+			discoveryCache = cache.Open(cacheDir, "https://api.example.com", time.Hour)
+			fakeCliConnection.GetCurrentSpaceReturns(plugin_models.Space{SpaceFields: plugin_models.SpaceFields{Guid: "space-guid"}}, nil)
+		})
+
+		It("should record the discovered logs endpoint", func() {
+			Expect(err).NotTo(HaveOccurred())
+			entry, ok := cache.Open(cacheDir, "https://api.example.com", time.Hour).Get("space-guid:space-guid/name:" + serviceInstanceName)
+			Expect(ok).To(BeTrue())
+			Expect(entry.Guid).To(Equal(serviceGUID))
+			Expect(entry.ServicePlanGuid).To(Equal(servicePlanGuid))
+			Expect(entry.ServiceGuid).To(Equal("aaaa-bbbb-cccc-dddd"))
+			Expect(entry.LogsEndpoint).To(Equal("https://service-instance-logs/logs/"))
+		})
+
+		Context("when the service instance has been discovered before", func() {
+			BeforeEach(func() {
+				discoveryCache.Put("space-guid:space-guid/name:"+serviceInstanceName, cache.Entry{
+					Name:         serviceInstanceName,
+					Guid:         "cached-guid",
+					LogsEndpoint: "https://cached-logs/logs/",
+				})
+			})
+
+			It("should record the info document of the logs service", func() {
+				Expect(err).NotTo(HaveOccurred())
+				entry, ok := discoveryCache.Get("space-guid:space-guid/name:" + serviceInstanceName)
+				Expect(ok).To(BeTrue())
+				Expect(entry.Info).To(Equal(&logclient.Info{}))
+			})
+
+			Context("when the info document has been recorded", func() {
+				BeforeEach(func() {
+					discoveryCache.Put("space-guid:space-guid/name:"+serviceInstanceName, cache.Entry{
+						Name:         serviceInstanceName,
+						Guid:         "cached-guid",
+						LogsEndpoint: "https://cached-logs/logs/",
+						Info:         &logclient.Info{RecentOrdered: true},
+					})
+					fakeLogClientBuilder.RecentOrderedReturns(fakeLogClientBuilder)
+				})
+
+				It("should not request it again", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(fakeLogClientBuilder.InfoCallCount()).To(Equal(0))
+					Expect(fakeLogClientBuilder.RecentOrderedArgsForCall(0)).To(BeTrue())
+				})
+			})
+
+			It("should not query the Cloud Controller", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeCliConnection.GetServiceCallCount()).To(Equal(0))
+				Expect(fakeCliConnection.CliCommandWithoutTerminalOutputCallCount()).To(Equal(0))
+				Expect(fakeLogClientBuilder.EndpointArgsForCall(0)).To(Equal("https://cached-logs/logs/"))
+				_, guid, _, _ := fakeLogClient.StreamRecentLogsArgsForCall(0)
+				Expect(guid).To(Equal("cached-guid"))
+			})
+
+			Context("when the logs endpoint is not found", func() {
+				BeforeEach(func() {
+					fakeLogClient.StreamRecentLogsStub = streamRecords(nil, &logclient.StatusError{StatusCode: http.StatusNotFound, Err: testError})
+				})
+
+				It("should invalidate the cache entry", func() {
+					Expect(err).To(MatchError(testError))
+					_, ok := discoveryCache.Get("space-guid:space-guid/name:" + serviceInstanceName)
+					Expect(ok).To(BeFalse())
+				})
+			})
+
+			Context("when the logs endpoint cannot be reached", func() {
+				BeforeEach(func() {
+					fakeLogClient.StreamRecentLogsStub = streamRecords(nil, &logclient.ConnectionError{Err: testError})
+				})
+
+				It("should invalidate the cache entry", func() {
+					Expect(err).To(MatchError(testError))
+					_, ok := discoveryCache.Get("space-guid:space-guid/name:" + serviceInstanceName)
+					Expect(ok).To(BeFalse())
+				})
+			})
+
+			Context("when something other than the logs endpoint fails", func() {
+				BeforeEach(func() {
+					fakeLogClient.StreamRecentLogsStub = streamRecords(nil, testError)
+				})
+
+				It("should keep the cache entry", func() {
+					Expect(err).To(MatchError(testError))
+					_, ok := discoveryCache.Get("space-guid:space-guid/name:" + serviceInstanceName)
+					Expect(ok).To(BeTrue())
+				})
+			})
+		})
+
+		Context("when discovery fails", func() {
+			BeforeEach(func() {
+				servicePlanOutput = []string{`{`}
+			})
+
+			It("should not record anything", func() {
+				Expect(err).To(HaveOccurred())
+				_, ok := cache.Open(cacheDir, "https://api.example.com", time.Hour).Get("space-guid:space-guid/name:" + serviceInstanceName)
+				Expect(ok).To(BeFalse())
+			})
+		})
+	})
+
 	Context("when dumping recent logs", func() {
 		It("should call log client recent logs with the correct parameters", func() {
-			Expect(fakeLogClient.RecentLogsCallCount()).To(Equal(1))
-			guid, tok := fakeLogClient.RecentLogsArgsForCall(0)
+			Expect(fakeLogClient.StreamRecentLogsCallCount()).To(Equal(1))
+			_, guid, tok, _ := fakeLogClient.StreamRecentLogsArgsForCall(0)
 			Expect(guid).To(Equal(serviceGUID))
 			Expect(tok).To(Equal(testToken))
 		})
 
 		Context("when log client recent logs return an error", func() {
 			BeforeEach(func() {
-				fakeLogClient.RecentLogsReturns([]string{}, testError)
+				fakeLogClient.StreamRecentLogsStub = streamRecords([]logclient.LogRecord{}, testError)
 			})
 
 			It("should propagate the error", func() {
-				Expect(err).To(Equal(testError))
+				Expect(err).To(MatchError(testError))
+			})
+
+			It("should identify the service instance and its service offering", func() {
+				Expect(err).To(Equal(&logging.ServiceInstanceError{ServiceInstance: serviceInstanceName, ServiceGuid: "aaaa-bbbb-cccc-dddd", Err: testError}))
 			})
 		})
 
 		Context("when log client recent logs returns normally", func() {
 			BeforeEach(func() {
-				fakeLogClient.RecentLogsReturns([]string{"hello", "goodbye"}, nil)
+				fakeLogClient.StreamRecentLogsStub = streamRecords([]logclient.LogRecord{{Message: "hello"}, {Message: "goodbye"}}, nil)
 			})
 
 			It("should return normally", func() {
@@ -252,19 +441,202 @@ var _ = Describe("Logs", func() {
 				Expect(output).To(gbytes.Say("goodbye"))
 			})
 		})
+
+		Context("when joining multi-line log entries", func() {
+			BeforeEach(func() {
+				options.Join = &logging.JoinOptions{}
+				fakeLogClient.StreamRecentLogsStub = streamRecords([]logclient.LogRecord{{Message: "boom"}, {Message: "\tat here"}, {Message: "goodbye"}}, nil)
+			})
+
+			It("should print each entry as a single record", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(output).To(gbytes.Say(`\[/\]  boom\n\tat here\n`))
+				Expect(output).To(gbytes.Say(`\[/\]  goodbye\n`))
+			})
+		})
+
+		Context("when the logs are received gradually", func() {
+			var printedBeforeLast string
+
+			BeforeEach(func() {
+				options.Join = &logging.JoinOptions{}
+				fakeLogClient.StreamRecentLogsStub = func(_ context.Context, _ string, _ string, each func(logclient.LogRecord) error) error {
+					records := []logclient.LogRecord{
+						{Timestamp: time.Unix(1, 0), Message: "boom"},
+						{Timestamp: time.Unix(1, 1), Message: "\tat here"},
+						{Timestamp: time.Unix(2, 0), Message: "next"},
+						{Timestamp: time.Unix(3, 0), Message: "last"},
+					}
+					for i, record := range records {
+						if i == len(records)-1 {
+							printedBeforeLast = string(output.Contents())
+						}
+						if err := each(record); err != nil {
+							return err
+						}
+					}
+					return nil
+				}
+			})
+
+			It("should print each record once no earlier record can follow it", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(printedBeforeLast).To(ContainSubstring("boom\n\tat here\n"))
+				Expect(printedBeforeLast).NotTo(ContainSubstring("next"))
+				Expect(output).To(gbytes.Say(`next\n`))
+				Expect(output).To(gbytes.Say(`last\n`))
+			})
+		})
+	})
+
+	Context("when negotiating with the logs service", func() {
+		It("should request its info document with the access token", func() {
+			Expect(fakeLogClientBuilder.InfoCallCount()).To(Equal(1))
+			_, tok := fakeLogClientBuilder.InfoArgsForCall(0)
+			Expect(tok).To(Equal(testToken))
+		})
+
+		Context("when the capability check is skipped", func() {
+			BeforeEach(func() {
+				options.SkipCapabilityCheck = true
+			})
+
+			It("should obtain the logs without requesting the info document", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeLogClientBuilder.InfoCallCount()).To(Equal(0))
+				Expect(fakeLogClient.StreamRecentLogsCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when the info document cannot be obtained", func() {
+			BeforeEach(func() {
+				fakeLogClientBuilder.InfoReturns(logclient.Info{}, testError)
+			})
+
+			It("should warn and obtain the logs anyway", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(output).To(gbytes.Say("Warning: Could not obtain the capabilities of the logs service of service instance " + serviceInstanceName + ", so assuming it supports this plugin: " + errMessage))
+				Expect(fakeLogClient.StreamRecentLogsCallCount()).To(Equal(1))
+			})
+		})
+
+		Context("when the logs service implements another protocol version", func() {
+			BeforeEach(func() {
+				fakeLogClientBuilder.InfoReturns(logclient.Info{ProtocolVersions: []int{2, 3}}, nil)
+			})
+
+			It("should explain the mismatch", func() {
+				Expect(err).To(MatchError("The logs service of service instance " + serviceInstanceName + " implements protocol versions 2, 3, but this plugin implements version 1. Check for a newer version of the plugin."))
+				Expect(fakeLogClient.StreamRecentLogsCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the logs service does not serve recent logs", func() {
+			BeforeEach(func() {
+				fakeLogClientBuilder.InfoReturns(logclient.Info{Backends: []string{logclient.BackendStream}}, nil)
+			})
+
+			It("should explain that --recent is not supported", func() {
+				Expect(err).To(BeAssignableToTypeOf(&logging.UnsupportedError{}))
+				Expect(err).To(MatchError("The logs service of service instance " + serviceInstanceName + " does not serve recent logs, so --recent is not supported. Omit --recent to tail its logs."))
+			})
+		})
+
+		Context("when the logs service does not support tailing", func() {
+			BeforeEach(func() {
+				recent = false
+				fakeLogClientBuilder.InfoReturns(logclient.Info{Backends: []string{logclient.BackendRecent}}, nil)
+			})
+
+			It("should suggest --recent", func() {
+				Expect(err).To(MatchError("The logs service of service instance " + serviceInstanceName + " does not support tailing logs. Use --recent to show its recent logs."))
+				Expect(fakeLogClient.TailingLogsCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when the logs service sends recent logs in timestamp order", func() {
+			BeforeEach(func() {
+				fakeLogClientBuilder.InfoReturns(logclient.Info{RecentOrdered: true}, nil)
+				fakeLogClientBuilder.RecentOrderedReturns(fakeLogClientBuilder)
+			})
+
+			It("should not sort them again", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeLogClientBuilder.RecentOrderedCallCount()).To(Equal(1))
+				Expect(fakeLogClientBuilder.RecentOrderedArgsForCall(0)).To(BeTrue())
+			})
+
+			Context("when tailing logs", func() {
+				BeforeEach(func() {
+					recent = false
+				})
+
+				It("should not pass the ordering on", func() {
+					Expect(fakeLogClientBuilder.RecentOrderedCallCount()).To(Equal(0))
+				})
+			})
+		})
+
+		Context("when the logs service states how long recent logs are kept", func() {
+			BeforeEach(func() {
+				fakeLogClientBuilder.InfoReturns(logclient.Info{MaxRecentWindow: time.Hour}, nil)
+			})
+
+			It("should tell the user", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(output).To(gbytes.Say("Recent logs of service instance .*" + serviceInstanceName + ".* cover at most 1h0m0s"))
+			})
+		})
+
+		Context("when filters are requested", func() {
+			BeforeEach(func() {
+				options.Filters = url.Values{logclient.FilterField: []string{"level=error"}, "other": []string{"x"}}
+				fakeLogClientBuilder.FiltersReturns(fakeLogClientBuilder)
+			})
+
+			It("should not pass them to a logs service which does not support them", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(fakeLogClientBuilder.FiltersCallCount()).To(Equal(0))
+			})
+
+			Context("when the logs service supports some of them", func() {
+				BeforeEach(func() {
+					fakeLogClientBuilder.InfoReturns(logclient.Info{Filters: []string{logclient.FilterField}}, nil)
+				})
+
+				It("should pass the supported filters to the logs service", func() {
+					Expect(err).NotTo(HaveOccurred())
+					Expect(fakeLogClientBuilder.FiltersCallCount()).To(Equal(1))
+					Expect(fakeLogClientBuilder.FiltersArgsForCall(0)).To(Equal(url.Values{logclient.FilterField: []string{"level=error"}}))
+				})
+			})
+		})
 	})
 
 	Context("when tailing logs", func() {
 		var (
-			messageChan chan string
+			messageChan chan logclient.LogRecord
 			errChan     chan error
+			closeChans  func()
 		)
 
 		BeforeEach(func() {
 			recent = false
-			messageChan = make(chan string)
+			messageChan = make(chan logclient.LogRecord)
 			errChan = make(chan error, 1)
-			fakeLogClient.TailingLogsReturns(messageChan, errChan)
+			closeChans = sync.OnceFunc(func() {
+				close(messageChan)
+				close(errChan)
+			})
+			// Like the real log client, close the channels once tailing is cancelled.
+			fakeLogClient.TailingLogsStub = func(ctx context.Context, guid string, token string) (<-chan logclient.LogRecord, <-chan error) {
+				closeOnCancel := closeChans
+				go func() {
+					<-ctx.Done()
+					closeOnCancel()
+				}()
+				return messageChan, errChan
+			}
 		})
 
 		Context("in the normal case", func() {
@@ -273,8 +645,7 @@ var _ = Describe("Logs", func() {
 			})
 
 			AfterEach(func() {
-				close(messageChan)
-				close(errChan)
+				closeChans()
 			})
 
 			It("should correctly transform the endpoint passed to the LogClientBuilder", func() {
@@ -342,7 +713,7 @@ var _ = Describe("Logs", func() {
 			})
 
 			It("should pass the access token to the log client", func() {
-				_, tok := fakeLogClient.TailingLogsArgsForCall(0)
+				_, _, tok := fakeLogClient.TailingLogsArgsForCall(0)
 				Expect(tok).To(Equal(testToken))
 			})
 		})
@@ -353,12 +724,11 @@ var _ = Describe("Logs", func() {
 			})
 
 			AfterEach(func() {
-				close(messageChan)
-				close(errChan)
+				closeChans()
 			})
 
 			It("should return the error", func() {
-				Expect(err).To(Equal(testError))
+				Expect(err).To(MatchError(testError))
 			})
 		})
 
@@ -380,12 +750,11 @@ var _ = Describe("Logs", func() {
 
 			AfterEach(func() {
 				wg.Wait()
-				close(messageChan)
-				close(errChan)
+				closeChans()
 			})
 
 			It("should ignore the abnormal close error and return the subsequent test error", func() {
-				Expect(err).To(Equal(testError))
+				Expect(err).To(MatchError(testError))
 			})
 		})
 
@@ -399,8 +768,7 @@ var _ = Describe("Logs", func() {
 				go func() {
 					defer wg.Done()
 					time.Sleep(50 * time.Millisecond)
-					close(messageChan)
-					close(errChan)
+					closeChans()
 				}()
 			})
 
@@ -424,12 +792,11 @@ var _ = Describe("Logs", func() {
 				go func() {
 					defer wg.Done()
 
-					messageChan <- "hello"
+					messageChan <- logclient.LogRecord{Message: "hello"}
 
 					time.Sleep(50 * time.Millisecond)
 
-					close(messageChan)
-					close(errChan)
+					closeChans()
 				}()
 			})
 
@@ -445,5 +812,354 @@ var _ = Describe("Logs", func() {
 				Expect(err).NotTo(HaveOccurred())
 			})
 		})
+
+		Context("when tailing is interrupted", func() {
+			var wg sync.WaitGroup
+
+			BeforeEach(func() {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithCancel(context.Background())
+
+				wg = sync.WaitGroup{}
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					messageChan <- logclient.LogRecord{Message: "hello"}
+					cancel()
+				}()
+			})
+
+			AfterEach(func() {
+				wg.Wait()
+			})
+
+			It("should print the messages received and a summary", func() {
+				Expect(output).To(gbytes.Say("hello"))
+				Expect(output).To(gbytes.Say(`Interrupted after 0s: received 1 log message\(s\)\.`))
+			})
+
+			It("should return normally", func() {
+				Expect(err).NotTo(HaveOccurred())
+			})
+		})
+	})
+
+	Context("when retrieving recent logs is interrupted", func() {
+		BeforeEach(func() {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithCancel(context.Background())
+			fakeLogClient.StreamRecentLogsStub = func(ctx context.Context, guid string, token string, each func(logclient.LogRecord) error) error {
+				cancel()
+				return ctx.Err()
+			}
+		})
+
+		It("should print a summary and return normally", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output).To(gbytes.Say(`Interrupted after 0s: received 0 log message\(s\)\.`))
+		})
+	})
+})
+
+var _ = Describe("Logs of several service instances", func() {
+	var (
+		fakeCliConnection    *pluginfakes.FakeCliConnection
+		fakeLogClientBuilder *logclientfakes.FakeLogClientBuilder
+		fakeLogClient        *logclientfakes.FakeLogClient
+		recent               bool
+		output               *bytes.Buffer
+		err                  error
+	)
+
+	BeforeEach(func() {
+		fakeCliConnection = &pluginfakes.FakeCliConnection{}
+		fakeCliConnection.AccessTokenReturns("bearer some-token", nil)
+		fakeCliConnection.GetCurrentSpaceReturns(plugin_models.Space{SpaceFields: plugin_models.SpaceFields{Guid: "space-guid", Name: "space"}}, nil)
+		fakeCliConnection.CliCommandWithoutTerminalOutputStub = func(args ...string) ([]string, error) {
+			switch args[1] {
+			case "/v3/service_instances?label_selector=team%3Dpayments&space_guids=space-guid&type=managed":
+				return []string{`{"pagination": {}, "resources": [
+					{"guid": "guid-a", "name": "db", "relationships": {"service_plan": {"data": {"guid": "plan"}}}},
+					{"guid": "guid-b", "name": "cache", "relationships": {"service_plan": {"data": {"guid": "plan"}}}}
+				]}`}, nil
+			case "/v2/service_plans/plan":
+				return []string{`{"entity": {"service_guid": "service"}}`}, nil
+			case "/v2/services/service":
+				return []string{`{"entity": {"extra": "{\"serviceInstanceLogsEndpoint\":\"https://service-instance-logs/logs/\"}"}}`}, nil
+			default:
+				return nil, nil
+			}
+		}
+
+		fakeLogClientBuilder = &logclientfakes.FakeLogClientBuilder{}
+		fakeLogClient = &logclientfakes.FakeLogClient{}
+		fakeLogClientBuilder.EndpointReturns(fakeLogClientBuilder)
+		fakeLogClientBuilder.TokenSourceReturns(fakeLogClientBuilder)
+		fakeLogClientBuilder.BuildReturns(fakeLogClient)
+		recent = true
+		output = &bytes.Buffer{}
+	})
+
+	JustBeforeEach(func() {
+		err = logging.Logs(context.Background(), fakeCliConnection, output, sink.NewWriterSink(output), cfutil.Selector{LabelSelector: "team=payments"}, cache.None(), recent, logging.Options{}, fakeLogClientBuilder)
+	})
+
+	Context("when dumping recent logs", func() {
+		BeforeEach(func() {
+			fakeLogClient.StreamRecentLogsStub = func(ctx context.Context, guid string, token string, each func(logclient.LogRecord) error) error {
+				if guid == "guid-a" {
+					return streamRecords([]logclient.LogRecord{{Timestamp: time.Unix(1, 0), Message: "first"}, {Timestamp: time.Unix(3, 0), Message: "third"}}, nil)(ctx, guid, token, each)
+				}
+				return streamRecords([]logclient.LogRecord{{Timestamp: time.Unix(2, 0), Message: "second"}}, nil)(ctx, guid, token, each)
+			}
+		})
+
+		It("should interleave the logs in timestamp order, labelled with the service instance name", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(output.String()).To(MatchRegexp(`(?s)Service instances: .*db.*, .*cache.*\n\n.* db \[/\]  first\n.* cache \[/\]  second\n.* db \[/\]  third\n$`))
+		})
+
+		Context("when the logs of one of the service instances cannot be obtained", func() {
+			BeforeEach(func() {
+				fakeLogClient.StreamRecentLogsStub = func(ctx context.Context, guid string, token string, each func(logclient.LogRecord) error) error {
+					if guid == "guid-b" {
+						return errors.New("no dice")
+					}
+					return streamRecords([]logclient.LogRecord{{Timestamp: time.Unix(1, 0), Message: "first"}}, nil)(ctx, guid, token, each)
+				}
+			})
+
+			It("should name the service instance in the error", func() {
+				Expect(err).To(MatchError("service instance cache: no dice"))
+			})
+		})
+	})
+
+	Context("when tailing logs", func() {
+		BeforeEach(func() {
+			recent = false
+			fakeLogClient.TailingLogsStub = func(_ context.Context, guid string, token string) (<-chan logclient.LogRecord, <-chan error) {
+				messageChan := make(chan logclient.LogRecord, 1)
+				errChan := make(chan error)
+				messageChan <- logclient.LogRecord{Message: "hello from " + guid}
+				close(messageChan)
+				close(errChan)
+				return messageChan, errChan
+			}
+		})
+
+		It("should tail every service instance", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeLogClient.TailingLogsCallCount()).To(Equal(2))
+			Expect(output.String()).To(ContainSubstring(" db [/]  hello from guid-a\n"))
+			Expect(output.String()).To(ContainSubstring(" cache [/]  hello from guid-b\n"))
+		})
+
+		Context("when the logs service of one of the service instances does not support tailing", func() {
+			BeforeEach(func() {
+				fakeLogClientBuilder.InfoReturnsOnCall(1, logclient.Info{Backends: []string{logclient.BackendRecent}}, nil)
+			})
+
+			It("should skip that service instance with a warning", func() {
+				Expect(err).NotTo(HaveOccurred())
+				Expect(output.String()).To(ContainSubstring("Warning: Skipping service instance cache: The logs service of service instance cache does not support tailing logs."))
+				Expect(fakeLogClient.TailingLogsCallCount()).To(Equal(1))
+				Expect(output.String()).To(ContainSubstring("hello from guid-a"))
+				Expect(output.String()).NotTo(ContainSubstring("hello from guid-b"))
+			})
+		})
+
+		Context("when no logs service supports tailing", func() {
+			BeforeEach(func() {
+				fakeLogClientBuilder.InfoReturns(logclient.Info{Backends: []string{logclient.BackendRecent}}, nil)
+			})
+
+			It("should fail", func() {
+				Expect(err).To(MatchError("The logs service of service instance db does not support tailing logs. Use --recent to show its recent logs."))
+				Expect(fakeLogClient.TailingLogsCallCount()).To(Equal(0))
+			})
+		})
+
+		Context("when tailing one of the service instances fails", func() {
+			BeforeEach(func() {
+				fakeLogClient.TailingLogsStub = func(ctx context.Context, guid string, token string) (<-chan logclient.LogRecord, <-chan error) {
+					messageChan := make(chan logclient.LogRecord)
+					errChan := make(chan error, 1)
+					switch guid {
+					case "guid-a":
+						// Log clients may send nil errors when tailing stops; these are not failures.
+						errChan <- nil
+					case "guid-b":
+						errChan <- errors.New("no dice")
+					}
+					go func() {
+						<-ctx.Done()
+						close(messageChan)
+						close(errChan)
+					}()
+					return messageChan, errChan
+				}
+			})
+
+			It("should report which service instance failed", func() {
+				Expect(err).To(MatchError("service instance cache: no dice"))
+			})
+
+			It("should cancel tailing of every service instance", func() {
+				for i := 0; i < fakeLogClient.TailingLogsCallCount(); i++ {
+					ctx, _, _ := fakeLogClient.TailingLogsArgsForCall(i)
+					Expect(ctx.Err()).To(Equal(context.Canceled))
+				}
+			})
+		})
 	})
 })
+
+var _ = Describe("RecentLogRecords", func() {
+	var (
+		fakeCliConnection    *pluginfakes.FakeCliConnection
+		fakeLogClientBuilder *logclientfakes.FakeLogClientBuilder
+		fakeLogClient        *logclientfakes.FakeLogClient
+		cacheDir             string
+		err                  error
+	)
+
+	BeforeEach(func() {
+		fakeCliConnection = &pluginfakes.FakeCliConnection{}
+		fakeCliConnection.AccessTokenReturns("bearer some-token", nil)
+		fakeLogClientBuilder = &logclientfakes.FakeLogClientBuilder{}
+		fakeLogClient = &logclientfakes.FakeLogClient{}
+		fakeLogClientBuilder.EndpointReturns(fakeLogClientBuilder)
+		fakeLogClientBuilder.TokenSourceReturns(fakeLogClientBuilder)
+		fakeLogClientBuilder.BuildReturns(fakeLogClient)
+
+		cacheDir = GinkgoT().TempDir()
+		discoveryCache := cache.Open(cacheDir, "https://api.example.com", time.Hour)
+		discoveryCache.Put("guid:guid-a", cache.Entry{Name: "db", Guid: "guid-a", LogsEndpoint: "https://cached-logs/logs/"})
+		Expect(discoveryCache.Save()).To(Succeed())
+	})
+
+	JustBeforeEach(func() {
+		_, err = logging.RecentLogRecords(context.Background(), fakeCliConnection, cfutil.Selector{Guid: "guid-a"},
+			cache.Open(cacheDir, "https://api.example.com", time.Hour), fakeLogClientBuilder)
+	})
+
+	Context("when the cached logs service cannot serve recent logs", func() {
+		BeforeEach(func() {
+			fakeLogClientBuilder.InfoReturns(logclient.Info{Backends: []string{logclient.BackendStream}}, nil)
+		})
+
+		It("should invalidate the cache entry", func() {
+			Expect(err).To(BeAssignableToTypeOf(&logging.UnsupportedError{}))
+			Expect(fakeLogClient.StreamRecentLogsCallCount()).To(Equal(0))
+			_, ok := cache.Open(cacheDir, "https://api.example.com", time.Hour).Get("guid:guid-a")
+			Expect(ok).To(BeFalse())
+		})
+	})
+})
+
+var _ = Describe("TailLogRecords", func() {
+	var (
+		fakeCliConnection    *pluginfakes.FakeCliConnection
+		fakeLogClientBuilder *logclientfakes.FakeLogClientBuilder
+		fakeLogClient        *logclientfakes.FakeLogClient
+		ctx                  context.Context
+		cancel               context.CancelFunc
+		records              <-chan logclient.LogRecord
+		errs                 <-chan error
+		err                  error
+	)
+
+	BeforeEach(func() {
+		fakeCliConnection = &pluginfakes.FakeCliConnection{}
+		fakeCliConnection.AccessTokenReturns("bearer some-token", nil)
+		fakeCliConnection.CliCommandWithoutTerminalOutputStub = func(args ...string) ([]string, error) {
+			switch args[1] {
+			case "/v2/service_instances/guid-a":
+				return []string{`{"entity": {"name": "db", "service_plan_guid": "plan"}}`}, nil
+			case "/v2/service_plans/plan":
+				return []string{`{"entity": {"service_guid": "service"}}`}, nil
+			case "/v2/services/service":
+				return []string{`{"entity": {"extra": "{\"serviceInstanceLogsEndpoint\":\"https://service-instance-logs/logs/\"}"}}`}, nil
+			default:
+				return nil, nil
+			}
+		}
+
+		fakeLogClientBuilder = &logclientfakes.FakeLogClientBuilder{}
+		fakeLogClient = &logclientfakes.FakeLogClient{}
+		fakeLogClientBuilder.EndpointReturns(fakeLogClientBuilder)
+		fakeLogClientBuilder.TokenSourceReturns(fakeLogClientBuilder)
+		fakeLogClientBuilder.BuildReturns(fakeLogClient)
+		fakeLogClient.TailingLogsStub = func(ctx context.Context, guid string, token string) (<-chan logclient.LogRecord, <-chan error) {
+			messageChan := make(chan logclient.LogRecord, 1)
+			errChan := make(chan error, 2)
+			messageChan <- logclient.LogRecord{Message: "hello"}
+			errChan <- errors.New("close 1006 (abnormal closure)")
+			errChan <- errors.New("no dice")
+			go func() {
+				<-ctx.Done()
+				close(messageChan)
+				close(errChan)
+			}()
+			return messageChan, errChan
+		}
+		ctx, cancel = context.WithCancel(context.Background())
+	})
+
+	JustBeforeEach(func() {
+		records, errs, err = logging.TailLogRecords(ctx, fakeCliConnection, cfutil.Selector{Guid: "guid-a"}, cache.None(), logging.Options{}, fakeLogClientBuilder)
+	})
+
+	AfterEach(func() {
+		cancel()
+	})
+
+	It("should tail the websocket endpoint", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fakeLogClientBuilder.EndpointArgsForCall(0)).To(Equal("wss://service-instance-logs"))
+		_, guid, token := fakeLogClient.TailingLogsArgsForCall(0)
+		Expect(guid).To(Equal("guid-a"))
+		Expect(token).To(Equal("some-token"))
+	})
+
+	It("should let the log client obtain a current access token when it reconnects", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fakeLogClientBuilder.TokenSourceCallCount()).To(Equal(1))
+		fakeCliConnection.AccessTokenReturns("bearer refreshed-token", nil)
+		Expect(fakeLogClientBuilder.TokenSourceArgsForCall(0)()).To(Equal("refreshed-token"))
+	})
+
+	It("should deliver records and errors other than abnormal closures until the context is cancelled", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Eventually(records).Should(Receive(Equal(logclient.LogRecord{Message: "hello"})))
+		Eventually(errs).Should(Receive(MatchError("no dice")))
+		cancel()
+		Eventually(records).Should(BeClosed())
+		Eventually(errs).Should(BeClosed())
+	})
+
+	Context("when discovery fails", func() {
+		BeforeEach(func() {
+			fakeCliConnection.AccessTokenReturns("", errors.New("no dice"))
+		})
+
+		It("should return the error without tailing", func() {
+			Expect(err).To(MatchError("Access token not available: no dice"))
+			Expect(fakeLogClient.TailingLogsCallCount()).To(Equal(0))
+		})
+	})
+})
+
+// streamRecords returns a StreamRecentLogs stub which passes the given records to its function and then returns the
+// given error.
+func streamRecords(records []logclient.LogRecord, err error) func(context.Context, string, string, func(logclient.LogRecord) error) error {
+	return func(_ context.Context, _ string, _ string, each func(logclient.LogRecord) error) error {
+		for _, record := range records {
+			if err := each(record); err != nil {
+				return err
+			}
+		}
+		return err
+	}
+}
@@ -0,0 +1,274 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package logging
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+)
+
+// DefaultJoinTimeout is how long a multi-line log entry is held waiting for further lines if no timeout is
+// specified.
+const DefaultJoinTimeout = time.Second
+
+// DefaultContinuationPatterns match the lines of a Java stack trace which continue the preceding log line: indented
+// lines, such as "\tat com.example.Main.main(Main.java:5)", and "Caused by:" lines.
+var DefaultContinuationPatterns = []*regexp.Regexp{
+	regexp.MustCompile(`^\s`),
+	regexp.MustCompile(`^at `),
+	regexp.MustCompile(`^Caused by:`),
+	regexp.MustCompile(`^\.\.\. \d+ more`),
+}
+
+// JoinOptions configures the joining of multi-line log entries. Empty Continuation denotes
+// DefaultContinuationPatterns and a zero Timeout denotes DefaultJoinTimeout.
+type JoinOptions struct {
+	Continuation []*regexp.Regexp
+	Timeout      time.Duration
+}
+
+// Joiner joins the lines of multi-line log entries, such as stack traces, which arrive as separate log records.
+// A record whose message matches a continuation pattern is appended, on a new line, to the preceding record from
+// the same source, that is with the same service instance, source type and source instance. Each joined record has
+// the timestamp of its first line.
+//
+// A multi-line entry is complete when the next line from its source does not match a continuation pattern or when
+// no line from its source arrives within the timeout, measured both by record timestamps and, when tailing, by the
+// time since the last line arrived.
+type Joiner struct {
+	continuation []*regexp.Regexp
+	timeout      time.Duration
+	pending      map[joinKey]*pendingEntry
+	order        []joinKey
+}
+
+type joinKey struct {
+	serviceInstance string
+	sourceType      string
+	sourceInstance  string
+}
+
+type pendingEntry struct {
+	record  logclient.LogRecord
+	lines   []string
+	last    time.Time
+	arrived time.Time
+}
+
+// NewJoiner returns a joiner configured by the given options.
+func NewJoiner(options JoinOptions) *Joiner {
+	continuation := options.Continuation
+	if len(continuation) == 0 {
+		continuation = DefaultContinuationPatterns
+	}
+	timeout := options.Timeout
+	if timeout <= 0 {
+		timeout = DefaultJoinTimeout
+	}
+	return &Joiner{
+		continuation: continuation,
+		timeout:      timeout,
+		pending:      map[joinKey]*pendingEntry{},
+	}
+}
+
+// Add adds the given record, which arrived at the given time, and returns any records which are now complete.
+func (j *Joiner) Add(record logclient.LogRecord, arrived time.Time) []logclient.LogRecord {
+	key := joinKey{serviceInstance: record.ServiceInstance, sourceType: record.SourceType, sourceInstance: record.SourceInstance}
+	entry, ok := j.pending[key]
+	if ok && j.continues(record.Message) && record.Timestamp.Sub(entry.last) <= j.timeout {
+		entry.lines = append(entry.lines, record.Message)
+		entry.last = record.Timestamp
+		entry.arrived = arrived
+		return nil
+	}
+
+	var complete []logclient.LogRecord
+	if ok {
+		complete = append(complete, j.remove(key))
+	}
+	j.pending[key] = &pendingEntry{record: record, lines: []string{record.Message}, last: record.Timestamp, arrived: arrived}
+	j.order = append(j.order, key)
+	return complete
+}
+
+// Expire returns the records which have received no further lines within the timeout before the given time.
+func (j *Joiner) Expire(now time.Time) []logclient.LogRecord {
+	var complete []logclient.LogRecord
+	for _, key := range append([]joinKey(nil), j.order...) {
+		if now.Sub(j.pending[key].arrived) >= j.timeout {
+			complete = append(complete, j.remove(key))
+		}
+	}
+	return complete
+}
+
+// Flush returns every pending record, in the order in which their first lines were added.
+func (j *Joiner) Flush() []logclient.LogRecord {
+	var complete []logclient.LogRecord
+	for len(j.order) > 0 {
+		complete = append(complete, j.remove(j.order[0]))
+	}
+	return complete
+}
+
+// Oldest returns the timestamp of the oldest pending record and whether there is a pending record.
+func (j *Joiner) Oldest() (time.Time, bool) {
+	var oldest time.Time
+	for i, key := range j.order {
+		if timestamp := j.pending[key].record.Timestamp; i == 0 || timestamp.Before(oldest) {
+			oldest = timestamp
+		}
+	}
+	return oldest, len(j.order) > 0
+}
+
+func (j *Joiner) continues(message string) bool {
+	for _, pattern := range j.continuation {
+		if pattern.MatchString(message) {
+			return true
+		}
+	}
+	return false
+}
+
+func (j *Joiner) remove(key joinKey) logclient.LogRecord {
+	entry := j.pending[key]
+	delete(j.pending, key)
+	for i, k := range j.order {
+		if k == key {
+			j.order = append(j.order[:i], j.order[i+1:]...)
+			break
+		}
+	}
+	record := entry.record
+	record.Message = strings.Join(entry.lines, "\n")
+	return record
+}
+
+// JoinRecords joins the lines of multi-line log entries in the given records, which must be in timestamp order,
+// and returns the resultant records in timestamp order.
+func JoinRecords(records []logclient.LogRecord, options JoinOptions) []logclient.LogRecord {
+	joiner := newOrderedJoiner(options)
+	joined := []logclient.LogRecord{}
+	for _, record := range records {
+		joined = append(joined, joiner.Add(record)...)
+	}
+	return append(joined, joiner.Flush()...)
+}
+
+// orderedJoiner joins the lines of multi-line log entries in records which are added in timestamp order, such as
+// recent logs, and releases the joined records in timestamp order as soon as no record still to be joined can
+// precede them. Record timestamps stand in for arrival times, so an entry from one source completes once a record
+// from any source is more than the timeout later than its last line.
+type orderedJoiner struct {
+	joiner *Joiner
+	ready  []logclient.LogRecord
+}
+
+func newOrderedJoiner(options JoinOptions) *orderedJoiner {
+	return &orderedJoiner{joiner: NewJoiner(options)}
+}
+
+// Add adds the given record and returns any joined records which can now be released.
+func (o *orderedJoiner) Add(record logclient.LogRecord) []logclient.LogRecord {
+	// Joiner.Add joins a line no more than the timeout after the last, so expire only entries which are older.
+	o.enqueue(o.joiner.Expire(record.Timestamp.Add(-time.Nanosecond)))
+	o.enqueue(o.joiner.Add(record, record.Timestamp))
+
+	oldest, pending := o.joiner.Oldest()
+	n := len(o.ready)
+	if pending {
+		n = sort.Search(len(o.ready), func(i int) bool {
+			return o.ready[i].Timestamp.After(oldest)
+		})
+	}
+	released := o.ready[:n:n]
+	o.ready = o.ready[n:]
+	return released
+}
+
+// Flush returns every remaining record.
+func (o *orderedJoiner) Flush() []logclient.LogRecord {
+	o.enqueue(o.joiner.Flush())
+	released := o.ready
+	o.ready = nil
+	return released
+}
+
+// enqueue inserts the given complete records into the ready records, keeping them in timestamp order with records
+// of equal timestamps in the order in which they completed.
+func (o *orderedJoiner) enqueue(complete []logclient.LogRecord) {
+	for _, record := range complete {
+		i := sort.Search(len(o.ready), func(i int) bool {
+			return o.ready[i].Timestamp.After(record.Timestamp)
+		})
+		o.ready = append(o.ready, logclient.LogRecord{})
+		copy(o.ready[i+1:], o.ready[i:])
+		o.ready[i] = record
+	}
+}
+
+// Join joins the lines of multi-line log entries in the log records received from the given channel and delivers
+// the resultant records on the returned channel, which is closed once the given channel has closed and every
+// pending record has been delivered.
+func Join(in <-chan logclient.LogRecord, options JoinOptions) <-chan logclient.LogRecord {
+	joiner := NewJoiner(options)
+	out := make(chan logclient.LogRecord)
+	go func() {
+		defer close(out)
+
+		tick := joiner.timeout / 4
+		if tick < minTick {
+			tick = minTick
+		}
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+
+		var ready []logclient.LogRecord
+		for in != nil || len(ready) > 0 {
+			// As in Reorder, only receive once complete records have been delivered.
+			receive := in
+			var send chan<- logclient.LogRecord
+			var next logclient.LogRecord
+			if len(ready) > 0 {
+				receive = nil
+				send, next = out, ready[0]
+			}
+
+			select {
+			case record, ok := <-receive:
+				if !ok {
+					in = nil
+					ready = append(ready, joiner.Flush()...)
+					continue
+				}
+				ready = append(ready, joiner.Add(record, time.Now())...)
+			case send <- next:
+				ready[0] = logclient.LogRecord{}
+				ready = ready[1:]
+			case now := <-ticker.C:
+				ready = append(ready, joiner.Expire(now)...)
+			}
+		}
+	}()
+	return out
+}
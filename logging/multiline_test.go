@@ -0,0 +1,138 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package logging_test
+
+import (
+	"regexp"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logging"
+)
+
+var _ = Describe("Multi-line joining", func() {
+	var (
+		options logging.JoinOptions
+		start   time.Time
+	)
+
+	line := func(instance string, offset time.Duration, message string) logclient.LogRecord {
+		return logclient.LogRecord{Timestamp: start.Add(offset), SourceType: "APP", SourceInstance: instance, MessageType: "ERR", Message: message}
+	}
+
+	messages := func(records []logclient.LogRecord) []string {
+		result := []string{}
+		for _, r := range records {
+			result = append(result, r.Message)
+		}
+		return result
+	}
+
+	BeforeEach(func() {
+		options = logging.JoinOptions{}
+		start = time.Unix(1500000000, 0)
+	})
+
+	Describe("JoinRecords", func() {
+		It("should join a stack trace into a single record with the timestamp of its first line", func() {
+			joined := logging.JoinRecords([]logclient.LogRecord{
+				line("0", 0, "java.lang.IllegalStateException: boom"),
+				line("0", time.Millisecond, "\tat com.example.Main.run(Main.java:10)"),
+				line("0", 2*time.Millisecond, "Caused by: java.io.IOException: disk full"),
+				line("0", 3*time.Millisecond, "\t... 3 more"),
+				line("0", 4*time.Millisecond, "Recovered"),
+			}, options)
+
+			Expect(messages(joined)).To(Equal([]string{
+				"java.lang.IllegalStateException: boom\n\tat com.example.Main.run(Main.java:10)\nCaused by: java.io.IOException: disk full\n\t... 3 more",
+				"Recovered",
+			}))
+			Expect(joined[0].Timestamp).To(Equal(start))
+		})
+
+		It("should join lines from each source separately", func() {
+			joined := logging.JoinRecords([]logclient.LogRecord{
+				line("0", 0, "error on 0"),
+				line("1", time.Millisecond, "error on 1"),
+				line("0", 2*time.Millisecond, "\tat zero"),
+				line("1", 3*time.Millisecond, "\tat one"),
+			}, options)
+
+			Expect(messages(joined)).To(Equal([]string{"error on 0\n\tat zero", "error on 1\n\tat one"}))
+		})
+
+		It("should not join lines separated by more than the timeout", func() {
+			joined := logging.JoinRecords([]logclient.LogRecord{
+				line("0", 0, "first"),
+				line("0", 2*time.Second, "  indented"),
+			}, options)
+
+			Expect(messages(joined)).To(Equal([]string{"first", "  indented"}))
+		})
+
+		Context("when continuation patterns are given", func() {
+			BeforeEach(func() {
+				options.Continuation = []*regexp.Regexp{regexp.MustCompile(`^\+`)}
+			})
+
+			It("should use them instead of the defaults", func() {
+				joined := logging.JoinRecords([]logclient.LogRecord{
+					line("0", 0, "first"),
+					line("0", time.Millisecond, "+ more"),
+					line("0", 2*time.Millisecond, "\tat somewhere"),
+				}, options)
+
+				Expect(messages(joined)).To(Equal([]string{"first\n+ more", "\tat somewhere"}))
+			})
+		})
+	})
+
+	Describe("Join", func() {
+		var (
+			in  chan logclient.LogRecord
+			out <-chan logclient.LogRecord
+		)
+
+		BeforeEach(func() {
+			options.Timeout = 20 * time.Millisecond
+			in = make(chan logclient.LogRecord)
+		})
+
+		JustBeforeEach(func() {
+			out = logging.Join(in, options)
+		})
+
+		It("should deliver a joined record once the next line from its source arrives", func() {
+			in <- line("0", 0, "boom")
+			in <- line("0", 0, "\tat here")
+			in <- line("0", 0, "next")
+
+			Eventually(out).Should(Receive(HaveField("Message", "boom\n\tat here")))
+			close(in)
+			Eventually(out).Should(Receive(HaveField("Message", "next")))
+			Eventually(out).Should(BeClosed())
+		})
+
+		It("should deliver a pending record once no further lines arrive within the timeout", func() {
+			in <- line("0", 0, "boom")
+			Eventually(out).Should(Receive(HaveField("Message", "boom")))
+			close(in)
+		})
+	})
+})
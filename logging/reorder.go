@@ -0,0 +1,148 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package logging
+
+import (
+	"container/heap"
+	"time"
+
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+)
+
+// minTick bounds how often held log records are checked for release.
+const minTick = 10 * time.Millisecond
+
+// Reorder holds the log records received from the given channel for the given latency window and delivers them
+// in timestamp order on the returned channel.
+//
+// Records are released using a watermark: the latest timestamp of the records which have been held for the whole
+// window. Every held record with a timestamp no later than the watermark is delivered, oldest first. A record which
+// arrives with a timestamp earlier than one already delivered cannot be put in order, so it is delivered
+// immediately and marked as late. The returned channel is closed, after the remaining records have been delivered
+// in timestamp order, once the given channel has closed.
+func Reorder(in <-chan logclient.LogRecord, window time.Duration) <-chan logclient.LogRecord {
+	out := make(chan logclient.LogRecord)
+	go func() {
+		defer close(out)
+
+		tick := window / 4
+		if tick < minTick {
+			tick = minTick
+		}
+		ticker := time.NewTicker(tick)
+		defer ticker.Stop()
+
+		var (
+			held      heldRecords
+			arrivals  []arrival
+			watermark time.Time
+			delivered time.Time
+			sequence  int
+			ready     []logclient.LogRecord
+		)
+
+		// release moves the held records which are no later than the watermark to the ready queue.
+		release := func(now time.Time) {
+			for len(arrivals) > 0 && !now.Before(arrivals[0].at.Add(window)) {
+				if arrivals[0].timestamp.After(watermark) {
+					watermark = arrivals[0].timestamp
+				}
+				arrivals = arrivals[1:]
+			}
+			for held.Len() > 0 && !held[0].record.Timestamp.After(watermark) {
+				record := heap.Pop(&held).(heldRecord).record
+				delivered = record.Timestamp
+				ready = append(ready, record)
+			}
+		}
+
+		for in != nil || len(ready) > 0 || held.Len() > 0 {
+			// Only receive once the released records have been delivered, so that a slow receiver applies
+			// backpressure rather than the released records accumulating.
+			receive := in
+			var send chan<- logclient.LogRecord
+			var next logclient.LogRecord
+			if len(ready) > 0 {
+				receive = nil
+				send, next = out, ready[0]
+			}
+
+			select {
+			case record, ok := <-receive:
+				if !ok {
+					in = nil
+					// Deliver every remaining record in timestamp order.
+					arrivals = nil
+					for held.Len() > 0 {
+						ready = append(ready, heap.Pop(&held).(heldRecord).record)
+					}
+					continue
+				}
+				if record.Timestamp.Before(delivered) {
+					record.Late = true
+					ready = append(ready, record)
+					continue
+				}
+				heap.Push(&held, heldRecord{record: record, sequence: sequence})
+				sequence++
+				arrivals = append(arrivals, arrival{at: time.Now(), timestamp: record.Timestamp})
+				release(time.Now())
+			case send <- next:
+				ready[0] = logclient.LogRecord{}
+				ready = ready[1:]
+			case now := <-ticker.C:
+				release(now)
+			}
+		}
+	}()
+	return out
+}
+
+// arrival records when a held log record arrived.
+type arrival struct {
+	at        time.Time
+	timestamp time.Time
+}
+
+type heldRecord struct {
+	record   logclient.LogRecord
+	sequence int
+}
+
+// heldRecords is a heap of log records ordered by timestamp and then by arrival.
+type heldRecords []heldRecord
+
+func (h heldRecords) Len() int { return len(h) }
+
+func (h heldRecords) Less(i, j int) bool {
+	if h[i].record.Timestamp.Equal(h[j].record.Timestamp) {
+		return h[i].sequence < h[j].sequence
+	}
+	return h[i].record.Timestamp.Before(h[j].record.Timestamp)
+}
+
+func (h heldRecords) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *heldRecords) Push(x interface{}) { *h = append(*h, x.(heldRecord)) }
+
+func (h *heldRecords) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
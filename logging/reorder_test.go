@@ -0,0 +1,100 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package logging_test
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logging"
+)
+
+var _ = Describe("Reorder", func() {
+	const window = 50 * time.Millisecond
+
+	var (
+		in  chan logclient.LogRecord
+		out <-chan logclient.LogRecord
+	)
+
+	record := func(message string, secs int64) logclient.LogRecord {
+		return logclient.LogRecord{Timestamp: time.Unix(secs, 0), Message: message}
+	}
+
+	messages := func(records ...logclient.LogRecord) []string {
+		result := []string{}
+		for _, r := range records {
+			result = append(result, r.Message)
+		}
+		return result
+	}
+
+	BeforeEach(func() {
+		in = make(chan logclient.LogRecord)
+		out = logging.Reorder(in, window)
+	})
+
+	It("should deliver records received within the window in timestamp order", func() {
+		in <- record("third", 3)
+		in <- record("first", 1)
+		in <- record("second", 2)
+
+		var received []logclient.LogRecord
+		for i := 0; i < 3; i++ {
+			var r logclient.LogRecord
+			Eventually(out).Should(Receive(&r))
+			received = append(received, r)
+		}
+		Expect(messages(received...)).To(Equal([]string{"first", "second", "third"}))
+		close(in)
+		Eventually(out).Should(BeClosed())
+	})
+
+	It("should hold records for the window", func() {
+		in <- record("first", 1)
+		Consistently(out, window/2).ShouldNot(Receive())
+		Eventually(out).Should(Receive(Equal(record("first", 1))))
+		close(in)
+	})
+
+	It("should mark records which arrive after later records have been delivered as late", func() {
+		in <- record("second", 2)
+		Eventually(out).Should(Receive(Equal(record("second", 2))))
+
+		in <- record("first", 1)
+		var late logclient.LogRecord
+		Eventually(out).Should(Receive(&late))
+		Expect(late.Message).To(Equal("first"))
+		Expect(late.Late).To(BeTrue())
+		close(in)
+	})
+
+	It("should deliver held records in timestamp order when the input closes", func() {
+		in <- record("second", 2)
+		in <- record("first", 1)
+		close(in)
+
+		var received []logclient.LogRecord
+		for r := range out {
+			received = append(received, r)
+		}
+		Expect(messages(received...)).To(Equal([]string{"first", "second"}))
+		Expect(received[0].Late || received[1].Late).To(BeFalse())
+	})
+})
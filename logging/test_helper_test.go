@@ -0,0 +1,17 @@
+package logging
+
+import "time"
+
+// Allow the buffer's marker interval and clock to be replaced, but only in tests (since the name of this file ends in "...test.go").
+func (b *Buffer) SetMarkerInterval(interval time.Duration) {
+	b.markerInterval = interval
+}
+
+func (b *Buffer) SetClock(now func() time.Time) {
+	b.now = now
+}
+
+type BufferFieldSetter interface {
+	SetMarkerInterval(interval time.Duration)
+	SetClock(now func() time.Time)
+}
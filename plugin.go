@@ -16,47 +16,167 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"net/url"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"regexp"
 
 	"code.cloudfoundry.org/cli/plugin"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/alert"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/cache"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/cfutil"
 	"github.com/pivotal-cf/service-instance-logs-cli-plugin/cli"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/config"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/doctor"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/failure"
 	"github.com/pivotal-cf/service-instance-logs-cli-plugin/format"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/instances"
 	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
 	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logging"
 	"github.com/pivotal-cf/service-instance-logs-cli-plugin/pluginutil"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/redact"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/sink"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/stats"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/structured"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/template"
 )
 
-// Plugin version. Substitute "<major>.<minor>.<build>" at build time, e.g. using -ldflags='-X main.pluginVersion=1.2.3'
+// Plugin version. Substitute a semantic version such as "<major>.<minor>.<patch>" or "<major>.<minor>.<patch>-<pre-release>+<build>"
+// at build time, e.g. using -ldflags='-X main.pluginVersion=1.2.3'
 var pluginVersion = "invalid version - plugin was not built correctly"
 
-const serivceLogsCommand = "service-logs"
+// Commit and build date of the plugin. Substitute these at build time, e.g. using
+// -ldflags='-X main.pluginCommit=abc123 -X main.buildDate=2017-11-30T12:00:00Z'. If they are not substituted, they are
+// taken from the version control information recorded by the Go toolchain, if any.
+var (
+	pluginCommit string
+	buildDate    string
+)
+
+const (
+	serivceLogsCommand          = "service-logs"
+	serviceLogsAlias            = "sil"
+	serviceLogsStatsCommand     = "service-logs-stats"
+	serviceLogsDoctorCommand    = "service-logs-doctor"
+	serviceLogsInstancesCommand = "service-logs-instances"
+)
 
 // Plugin is a struct implementing the Plugin interface, defined by the core CLI, which can
 // be found in "code.cloudfoundry.org/cli/plugin/plugin.go".
 type Plugin struct{}
 
 func (c *Plugin) Run(cliConnection plugin.CliConnection, args []string) {
-	recent, skipSslValidation, positionalArgs, err := cli.ParseFlags(args)
+	flags, positionalArgs, err := cli.ParseFlags(args)
 	if err != nil {
-		format.Diagnose(string(err.Error()), os.Stderr, func() {
-			os.Exit(1)
-		})
+		fail(failure.New(failure.Usage, err.Error()), os.Stderr, outputJSON(args))
+	}
+
+	if flags.Version && args[0] == serivceLogsCommand {
+		printVersion()
+		return
 	}
 
 	switch args[0] {
 
 	case serivceLogsCommand:
-		serviceInstanceName := getServiceInstanceName(positionalArgs, args[0])
+		selector, description := getServiceInstanceSelector(positionalArgs, args[0], flags)
 		var behaviour string
-		if recent {
+		if flags.Recent {
 			behaviour = "Retrieving"
 		} else {
 			behaviour = "Connected, tailing"
 		}
-		runAction(cliConnection, fmt.Sprintf("%s logs for service instance %s", behaviour, format.Bold(format.Cyan(serviceInstanceName))), func() error {
-			logClientBuilder := logclient.NewLogClientBuilder().InsecureSkipVerify(skipSslValidation)
-			return logging.Logs(cliConnection, os.Stdout, serviceInstanceName, recent, logClientBuilder)
+		// Keep standard output to log records when they are written as JSON.
+		var progress io.Writer = os.Stdout
+		if flags.Output == cli.OutputJSON {
+			progress = os.Stderr
+		}
+		action := func() error {
+			logSink, err := buildSink(flags)
+			if err != nil {
+				return err
+			}
+			ctx, stop := interruptibleContext()
+			defer stop()
+			logClientBuilder := logclient.NewLogClientBuilder().InsecureSkipVerify(flags.SkipSslValidation)
+			err = logging.Logs(ctx, cliConnection, progress, logSink, selector, openCache(cliConnection, flags), flags.Recent, buildOptions(flags), logClientBuilder)
+			if closeErr := logSink.Close(); err == nil {
+				err = closeErr
+			}
+			return err
+		}
+		if flags.Output == cli.OutputJSON {
+			runActionQuietly(cliConnection, flags, action)
+		} else {
+			runActionInTarget(cliConnection, flags, fmt.Sprintf("%s logs for %s", behaviour, description), action)
+		}
+
+	case serviceLogsStatsCommand:
+		selector, description := getServiceInstanceSelector(positionalArgs, args[0], flags)
+		action := func() error {
+			pluginConfig, err := loadConfig()
+			if err != nil {
+				return err
+			}
+			redactor, err := buildRedactor(flags, pluginConfig)
+			if err != nil {
+				return err
+			}
+			ctx, stop := interruptibleContext()
+			defer stop()
+			logClientBuilder := logclient.NewLogClientBuilder().InsecureSkipVerify(flags.SkipSslValidation)
+			records, err := logging.RecentLogRecords(ctx, cliConnection, selector, openCache(cliConnection, flags), logClientBuilder)
+			if err != nil {
+				return err
+			}
+			if redactor != nil {
+				for i, record := range records {
+					records[i] = redactor.Record(record)
+				}
+			}
+			summary := stats.Summarise(records, flags.Top)
+			if flags.Output == cli.OutputJSON {
+				return summary.WriteJSON(os.Stdout)
+			}
+			fmt.Println()
+			return summary.WriteText(os.Stdout)
+		}
+		if flags.Output == cli.OutputJSON {
+			runActionQuietly(cliConnection, flags, action)
+		} else {
+			runActionInTarget(cliConnection, flags, fmt.Sprintf("Summarising recent logs for %s", description), action)
+		}
+
+	case serviceLogsDoctorCommand:
+		selector, description := getServiceInstanceSelector(positionalArgs, args[0], flags)
+		runActionInTarget(cliConnection, flags, fmt.Sprintf("Diagnosing access to logs for %s", description), func() error {
+			report := doctor.Run(cliConnection, selector, flags.SkipSslValidation)
+			fmt.Println()
+			report.Write(os.Stdout)
+			fmt.Println()
+			if !report.Passed() {
+				return errors.New("Service instance logs are not accessible. Follow the hint above to fix the failed check.")
+			}
+			return nil
+		})
+
+	case serviceLogsInstancesCommand:
+		message := "Listing service instances"
+		if flags.AllSpaces {
+			message = "Listing service instances in all spaces"
+		}
+		runAction(cliConnection, message, func() error {
+			list, err := instances.List(cliConnection, flags.AllSpaces)
+			if err != nil {
+				return err
+			}
+			fmt.Println()
+			return instances.Write(os.Stdout, list, flags.AllSpaces)
 		})
 
 	default:
@@ -65,29 +185,236 @@ func (c *Plugin) Run(cliConnection plugin.CliConnection, args []string) {
 	}
 }
 
-func getServiceInstanceName(args []string, operation string) string {
-	if len(args) < 2 || args[1] == "" {
-		diagnoseWithHelp("Service instance name not specified.", operation)
+// buildSink creates the destination for log records, including the handling of JSON log messages and the
+// evaluation of any alerting rules.
+func buildSink(flags cli.Flags) (sink.Sink, error) {
+	var rules []alert.Rule
+	if flags.RulesFile != "" {
+		var err error
+		rules, err = alert.LoadRules(flags.RulesFile)
+		if err != nil {
+			return nil, err
+		}
 	}
-	return args[1]
 
+	structuredOptions := structured.Options{Pretty: flags.JSONPretty, Columns: flags.Columns}
+	for _, spec := range flags.Fields {
+		// The filters have already been validated.
+		filter, _ := structured.ParseFilter(spec)
+		structuredOptions.Filters = append(structuredOptions.Filters, filter)
+	}
+
+	pluginConfig, err := loadConfig()
+	if err != nil {
+		return nil, err
+	}
+	redactor, err := buildRedactor(flags, pluginConfig)
+	if err != nil {
+		return nil, err
+	}
+	lineFormat, err := buildFormat(flags, pluginConfig)
+	if err != nil {
+		return nil, err
+	}
+	logSink, err := sink.New(flags.Sinks, os.Stdout, os.Stderr, nil, lineFormat)
+	if err != nil {
+		return nil, err
+	}
+	if flags.Output == cli.OutputJSON || flags.Format != "" || flags.JSONPretty || len(flags.Columns) > 0 || len(flags.Fields) > 0 {
+		logSink = structured.NewSink(logSink, structuredOptions)
+	}
+
+	if len(rules) > 0 {
+		var action alert.Action = alert.NewPrintAction(os.Stderr)
+		if flags.OnMatchExec != "" {
+			action = alert.NewExecAction(flags.OnMatchExec)
+		}
+		logSink = sink.NewTee(logSink, alert.NewSink(rules, action, os.Stderr))
+	}
+
+	// Redact messages before they are parsed, so that fields, columns and alerts never contain secrets.
+	if redactor != nil {
+		logSink = redact.NewSink(logSink, redactor)
+	}
+	return logSink, nil
+}
+
+// loadConfig reads the plugin configuration, if there is any.
+func loadConfig() (config.Config, error) {
+	path, err := config.DefaultPath()
+	if err != nil {
+		return config.Config{}, nil
+	}
+	return config.Load(path)
+}
+
+// buildRedactor returns the redactor for secrets in log messages, using the built-in rules and those in the plugin
+// configuration, or nil if --no-redact was specified.
+func buildRedactor(flags cli.Flags, pluginConfig config.Config) (*redact.Redactor, error) {
+	if flags.NoRedact {
+		return nil, nil
+	}
+	rules := []redact.Rule{}
+	for _, redaction := range pluginConfig.Redactions {
+		rule, err := redact.NewRule(redaction.Name, redaction.Pattern)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return redact.New(rules...), nil
+}
+
+// buildFormat determines how log records are rendered: as JSON, using the template given by --format, which may
+// name a format in the plugin configuration, or, by default, as text. Text is denoted by a nil format, which sinks
+// render without an intermediate string.
+func buildFormat(flags cli.Flags, pluginConfig config.Config) (sink.Format, error) {
+	if flags.Output == cli.OutputJSON {
+		return sink.JSONFormat, nil
+	}
+	if flags.Format == "" {
+		return nil, nil
+	}
+
+	text := flags.Format
+	if named, ok := pluginConfig.Format(flags.Format); ok {
+		text = named
+	}
+	return template.Parse(text)
+}
+
+// buildOptions determines how log records are processed.
+func buildOptions(flags cli.Flags) logging.Options {
+	options := logging.Options{
+		BufferSize:          flags.BufferSize,
+		Overflow:            logging.OverflowPolicy(flags.Overflow),
+		ReorderWindow:       flags.ReorderWindow,
+		SkipCapabilityCheck: flags.NoCapabilityCheck,
+	}
+	if len(flags.Fields) > 0 {
+		// Logs services which support it apply the field filter too, so that fewer log records are sent.
+		options.Filters = url.Values{logclient.FilterField: flags.Fields}
+	}
+	if flags.JoinMultiline {
+		options.Join = &logging.JoinOptions{Timeout: flags.JoinTimeout}
+		for _, pattern := range flags.Continuation {
+			// The patterns have already been validated.
+			options.Join.Continuation = append(options.Join.Continuation, regexp.MustCompile(pattern))
+		}
+	}
+	return options
+}
+
+// interruptibleContext returns a context which is cancelled when the user interrupts the plugin, so that logs already
+// received can be written before exiting. A second interrupt terminates the plugin immediately.
+func interruptibleContext() (context.Context, context.CancelFunc) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return ctx, stop
+}
+
+// openCache returns the cache of service instance discovery results for the targeted Cloud Controller, honouring
+// the --no-cache and --refresh flags.
+func openCache(cliConnection plugin.CliConnection, flags cli.Flags) cache.Cache {
+	if flags.NoCache {
+		return cache.None()
+	}
+	apiEndpoint, err := cliConnection.ApiEndpoint()
+	if err != nil || apiEndpoint == "" {
+		return cache.None()
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return cache.None()
+	}
+	discoveryCache := cache.Open(filepath.Join(dir, "service-instance-logs-cli-plugin"), apiEndpoint, cache.DefaultTTL)
+	if flags.Refresh {
+		return cache.Refreshing(discoveryCache)
+	}
+	return discoveryCache
+}
+
+// getServiceInstanceSelector identifies the service instances given by name, --guid or --selector, together with
+// any -o and -s flags, and describes them for use in progress messages.
+func getServiceInstanceSelector(args []string, operation string, flags cli.Flags) (cfutil.Selector, string) {
+	selector := cfutil.Selector{
+		Target:        cfutil.Target{Org: flags.Org, Space: flags.Space},
+		Guid:          flags.Guid,
+		LabelSelector: flags.Selector,
+		AllSpaces:     flags.AllSpaces,
+	}
+	named := len(args) >= 2 && args[1] != ""
+
+	switch {
+	case named && (flags.Guid != "" || flags.Selector != ""):
+		diagnoseWithHelp("Specify a service instance name, --guid or --selector, but not more than one.", operation, flags)
+	case flags.AllSpaces && flags.Selector == "":
+		diagnoseWithHelp("--all-spaces can only be used with --selector.", operation, flags)
+	case flags.Guid != "":
+		return selector, "service instance with guid " + format.Bold(format.Cyan(flags.Guid))
+	case flags.Selector != "":
+		if flags.AllSpaces {
+			return selector, "service instances matching " + format.Bold(format.Cyan(flags.Selector)) + " in all spaces"
+		}
+		return selector, "service instances matching " + format.Bold(format.Cyan(flags.Selector))
+	case !named:
+		diagnoseWithHelp("Service instance name not specified.", operation, flags)
+	}
+
+	selector.Name = args[1]
+	return selector, "service instance " + format.Bold(format.Cyan(args[1]))
 }
 
 func runAction(cliConnection plugin.CliConnection, message string, action func() error) {
-	format.RunAction(cliConnection, message, action, os.Stdout, func() {
-		os.Exit(1)
+	format.RunAction(cliConnection, message, action, os.Stdout, func(err error) {
+		fail(failure.Classify(err), os.Stdout, false)
+	})
+}
+
+// runActionInTarget runs an action, reporting the org and space given by any -o and -s flags in the progress message.
+func runActionInTarget(cliConnection plugin.CliConnection, flags cli.Flags, message string, action func() error) {
+	format.RunActionInSpace(cliConnection, flags.Org, flags.Space, message, action, os.Stdout, func(err error) {
+		fail(failure.Classify(err), os.Stdout, flags.Output == cli.OutputJSON)
 	})
 }
 
-func runActionQuietly(cliConnection plugin.CliConnection, action func() error) {
-	format.RunActionQuietly(cliConnection, action, os.Stdout, func() {
-		os.Exit(1)
+func runActionQuietly(cliConnection plugin.CliConnection, flags cli.Flags, action func() error) {
+	format.RunActionQuietly(cliConnection, action, func(err error) {
+		fail(failure.Classify(err), os.Stdout, flags.Output == cli.OutputJSON)
 	})
 }
 
-func diagnoseWithHelp(message string, operation string) {
-	fmt.Printf("%s See 'cf help %s.'\n", message, operation)
-	os.Exit(1)
+func diagnoseWithHelp(message string, operation string, flags cli.Flags) {
+	message = fmt.Sprintf("%s See 'cf help %s.'", message, operation)
+	if flags.Output == cli.OutputJSON {
+		fail(failure.New(failure.Usage, message), os.Stderr, true)
+	}
+	fmt.Println(message)
+	os.Exit(failure.Usage.ExitCode())
+}
+
+// fail reports a failure and exits with its exit code. The failure is written to the given writer, or, when JSON
+// output is requested, to standard error as a JSON object so that scripts can tell failures apart.
+func fail(f failure.Failure, writer io.Writer, jsonOutput bool) {
+	if jsonOutput {
+		f.WriteJSON(os.Stderr)
+	} else {
+		format.Diagnose(f.Message, f.Hint, writer)
+	}
+	os.Exit(f.Code)
+}
+
+// outputJSON determines whether the given arguments request JSON output, for use when they cannot be parsed.
+func outputJSON(args []string) bool {
+	for i, arg := range args {
+		if arg == "--output="+cli.OutputJSON || (arg == "--output" && i+1 < len(args) && args[i+1] == cli.OutputJSON) {
+			return true
+		}
+	}
+	return false
 }
 
 func failInstallation(format string, inserts ...interface{}) {
@@ -112,23 +439,99 @@ func (c *Plugin) GetMetadata() plugin.PluginMetadata {
 			{
 				Name:     serivceLogsCommand,
 				HelpText: "Tail or show recent logs for a service instance",
-				Alias:    "sil",
+				Alias:    serviceLogsAlias,
 				UsageDetails: plugin.Usage{
-					Usage: "   cf " + serivceLogsCommand + " SERVICE_INSTANCE_NAME",
+					Usage: "   cf " + serivceLogsCommand + " (SERVICE_INSTANCE_NAME | --guid GUID | --selector SELECTOR)\n   cf " + serivceLogsCommand + " --version",
 					Options: map[string]string{"--skip-ssl-validation": cli.SkipSslValidationUsage,
-						"--recent": cli.RecentUsage},
+						"-o":                    cli.OrgUsage,
+						"-s":                    cli.SpaceUsage,
+						"--guid":                cli.GuidUsage,
+						"--selector":            cli.SelectorUsage,
+						"--all-spaces":          cli.AllSpacesUsage,
+						"--no-cache":            cli.NoCacheUsage,
+						"--refresh":             cli.RefreshUsage,
+						"--no-capability-check": cli.NoCapabilityCheckUsage,
+						"--recent":              cli.RecentUsage,
+						"--buffer-size":         cli.BufferSizeUsage,
+						"--overflow":            cli.OverflowUsage,
+						"--reorder-window":      cli.ReorderWindowUsage,
+						"--join-multiline":      cli.JoinMultilineUsage,
+						"--continuation":        cli.ContinuationUsage,
+						"--join-timeout":        cli.JoinTimeoutUsage,
+						"--output":              cli.OutputUsage,
+						"--field":               cli.FieldUsage,
+						"--column":              cli.ColumnUsage,
+						"--json-pretty":         cli.JSONPrettyUsage,
+						"--format":              cli.FormatUsage,
+						"--no-redact":           cli.NoRedactUsage,
+						"--version":             cli.VersionUsage,
+						"--sink":                cli.SinkUsage,
+						"--rules":               cli.RulesUsage,
+						"--on-match-exec":       cli.OnMatchExecUsage},
+				},
+			},
+			{
+				Name:     serviceLogsStatsCommand,
+				HelpText: "Summarise the recent logs of a service instance",
+				UsageDetails: plugin.Usage{
+					Usage: "   cf " + serviceLogsStatsCommand + " (SERVICE_INSTANCE_NAME | --guid GUID | --selector SELECTOR)",
+					Options: map[string]string{"--skip-ssl-validation": cli.SkipSslValidationUsage,
+						"-o":           cli.OrgUsage,
+						"-s":           cli.SpaceUsage,
+						"--guid":       cli.GuidUsage,
+						"--selector":   cli.SelectorUsage,
+						"--all-spaces": cli.AllSpacesUsage,
+						"--no-cache":   cli.NoCacheUsage,
+						"--refresh":    cli.RefreshUsage,
+						"--output":     cli.OutputUsage,
+						"--no-redact":  cli.NoRedactUsage,
+						"--top":        cli.TopUsage},
+				},
+			},
+			{
+				Name:     serviceLogsDoctorCommand,
+				HelpText: "Diagnose problems accessing the logs of a service instance",
+				UsageDetails: plugin.Usage{
+					Usage: "   cf " + serviceLogsDoctorCommand + " (SERVICE_INSTANCE_NAME | --guid GUID | --selector SELECTOR)",
+					Options: map[string]string{"--skip-ssl-validation": cli.SkipSslValidationUsage,
+						"-o":           cli.OrgUsage,
+						"-s":           cli.SpaceUsage,
+						"--guid":       cli.GuidUsage,
+						"--selector":   cli.SelectorUsage,
+						"--all-spaces": cli.AllSpacesUsage},
+				},
+			},
+			{
+				Name:     serviceLogsInstancesCommand,
+				HelpText: "List service instances and whether they support logs",
+				UsageDetails: plugin.Usage{
+					Usage:   "   cf " + serviceLogsInstancesCommand,
+					Options: map[string]string{"--all-spaces": cli.AllSpacesUsage},
 				},
 			},
 		},
 	}
 }
 
+// printVersion prints the plugin version and how the plugin was built.
+func printVersion() {
+	if err := pluginutil.NewBuildInfo(pluginVersion, pluginCommit, buildDate).Write(os.Stdout); err != nil {
+		fail(failure.Classify(err), os.Stderr, false)
+	}
+}
+
 func main() {
+	p := new(Plugin)
 	if len(os.Args) == 1 {
-		fmt.Println("This program is a plugin which expects to be installed into the cf CLI. It is not intended to be run stand-alone.")
-		pv := pluginutil.ParsePluginVersion(pluginVersion, failInstallation)
-		fmt.Printf("Plugin version: %d.%d.%d\n", pv.Major, pv.Minor, pv.Build)
+		fmt.Println("This program is a plugin which expects to be installed into the cf CLI.")
+		fmt.Printf("It may also be run stand-alone, using the cf CLI's configuration or --api with CF_USERNAME and CF_PASSWORD, for example: %s %s SERVICE_INSTANCE_NAME --recent\n", filepath.Base(os.Args[0]), serivceLogsCommand)
+		pluginutil.ParsePluginVersion(pluginVersion, failInstallation)
+		fmt.Printf("Plugin version: %s\n", pluginVersion)
 		os.Exit(0)
 	}
-	plugin.Start(new(Plugin))
+	if command, ok := standaloneCommand(os.Args[1]); ok {
+		p.runStandalone(command, os.Args[2:])
+		return
+	}
+	plugin.Start(p)
 }
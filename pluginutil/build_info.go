@@ -0,0 +1,96 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package pluginutil
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+const unknown = "unknown"
+
+// BuildInfo describes how the plugin was built.
+type BuildInfo struct {
+	Version   string
+	Commit    string
+	BuildDate string
+	GoVersion string
+}
+
+// NewBuildInfo returns the build information for the given plugin version, commit and build date, which
+// are typically set at build time using -ldflags. A missing commit is taken from the version control information
+// recorded by the Go toolchain, if any, and a missing build date from the time of that commit.
+func NewBuildInfo(version, commit, buildDate string) BuildInfo {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if commit == "" {
+					commit = setting.Value
+				}
+			case "vcs.time":
+				if buildDate == "" {
+					buildDate = setting.Value
+				}
+			}
+		}
+	}
+	return BuildInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+	}
+}
+
+// Write writes the build information to the given writer, one item per line.
+func (b BuildInfo) Write(w io.Writer) error {
+	lines := [][2]string{}
+	if v, err := ParseSemVer(b.Version); err != nil {
+		lines = append(lines, [2]string{"Version", fmt.Sprintf("%s (invalid: %s)", b.Version, err)})
+	} else {
+		lines = append(lines, [2]string{"Version", v.String()})
+		if len(v.PreRelease) > 0 {
+			lines = append(lines, [2]string{"Pre-release", strings.Join(v.PreRelease, ".")})
+		}
+		if len(v.Build) > 0 {
+			lines = append(lines, [2]string{"Build metadata", strings.Join(v.Build, ".")})
+		}
+	}
+	lines = append(lines,
+		[2]string{"Commit", orUnknown(b.Commit)},
+		[2]string{"Build date", orUnknown(b.BuildDate)},
+		[2]string{"Go version", orUnknown(b.GoVersion)},
+	)
+
+	for _, line := range lines {
+		if _, err := fmt.Fprintf(w, "%-15s %s\n", line[0]+":", line[1]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func orUnknown(s string) string {
+	if s == "" {
+		return unknown
+	}
+	return s
+}
@@ -0,0 +1,85 @@
+package pluginutil_test
+
+import (
+	"bytes"
+	"runtime"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/pluginutil"
+)
+
+var _ = Describe("BuildInfo", func() {
+	var (
+		info   pluginutil.BuildInfo
+		output *bytes.Buffer
+	)
+
+	BeforeEach(func() {
+		output = &bytes.Buffer{}
+	})
+
+	JustBeforeEach(func() {
+		Expect(info.Write(output)).To(Succeed())
+	})
+
+	Context("when the version has pre-release identifiers and build metadata", func() {
+		BeforeEach(func() {
+			info = pluginutil.BuildInfo{
+				Version:   "1.4.0-rc.2+abc123",
+				Commit:    "abc123def456",
+				BuildDate: "2026-10-01T12:00:00Z",
+				GoVersion: "go1.27.0",
+			}
+		})
+
+		It("should write all the build information", func() {
+			Expect(output.String()).To(Equal("" +
+				"Version:        1.4.0-rc.2+abc123\n" +
+				"Pre-release:    rc.2\n" +
+				"Build metadata: abc123\n" +
+				"Commit:         abc123def456\n" +
+				"Build date:     2026-10-01T12:00:00Z\n" +
+				"Go version:     go1.27.0\n"))
+		})
+	})
+
+	Context("when the version is a release and the commit and build date are unknown", func() {
+		BeforeEach(func() {
+			info = pluginutil.BuildInfo{
+				Version:   "1.4.0",
+				GoVersion: "go1.27.0",
+			}
+		})
+
+		It("should omit the pre-release and build metadata and show unknown values", func() {
+			Expect(output.String()).To(Equal("" +
+				"Version:        1.4.0\n" +
+				"Commit:         unknown\n" +
+				"Build date:     unknown\n" +
+				"Go version:     go1.27.0\n"))
+		})
+	})
+
+	Context("when the version is invalid", func() {
+		BeforeEach(func() {
+			info = pluginutil.BuildInfo{Version: "dev"}
+		})
+
+		It("should show the version with the reason it is invalid", func() {
+			Expect(output.String()).To(HavePrefix(`Version:        dev (invalid: "dev" has invalid format. Expected 3 dot-separated integer components.)` + "\n"))
+		})
+	})
+
+	Describe("NewBuildInfo", func() {
+		It("should use the given values and the running Go version", func() {
+			info := pluginutil.NewBuildInfo("1.4.0", "abc123", "2026-10-01")
+			Expect(info).To(Equal(pluginutil.BuildInfo{
+				Version:   "1.4.0",
+				Commit:    "abc123",
+				BuildDate: "2026-10-01",
+				GoVersion: runtime.Version(),
+			}))
+		})
+	})
+})
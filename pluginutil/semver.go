@@ -0,0 +1,122 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package pluginutil
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SemVer is a semantic version as defined by https://semver.org/spec/v2.0.0.html.
+type SemVer struct {
+	Major      int
+	Minor      int
+	Patch      int
+	PreRelease []string
+	Build      []string
+}
+
+// ParseSemVer parses the given semantic version, such as 1.4.0-rc.2+abc123. A leading "v" is not accepted.
+func ParseSemVer(s string) (SemVer, error) {
+	core, build, hasBuild := strings.Cut(s, "+")
+	core, preRelease, hasPreRelease := strings.Cut(core, "-")
+
+	components := strings.Split(core, ".")
+	if len(components) != numComponents {
+		return SemVer{}, fmt.Errorf("%q has invalid format. Expected %d dot-separated integer components.", s, numComponents)
+	}
+	var v SemVer
+	for c, component := range []*int{&v.Major, &v.Minor, &v.Patch} {
+		n, err := parseNumericIdentifier(components[c])
+		if err != nil {
+			return SemVer{}, fmt.Errorf("%q has invalid format. Expected integer components.", s)
+		}
+		*component = n
+	}
+
+	if hasPreRelease {
+		ids, err := parseIdentifiers(preRelease)
+		if err != nil {
+			return SemVer{}, fmt.Errorf("%q has invalid format. Expected pre-release %s.", s, err)
+		}
+		for _, id := range ids {
+			if isNumeric(id) {
+				if _, err := parseNumericIdentifier(id); err != nil {
+					return SemVer{}, fmt.Errorf("%q has invalid format. Expected numeric pre-release identifiers without leading zeros.", s)
+				}
+			}
+		}
+		v.PreRelease = ids
+	}
+
+	if hasBuild {
+		ids, err := parseIdentifiers(build)
+		if err != nil {
+			return SemVer{}, fmt.Errorf("%q has invalid format. Expected build metadata %s.", s, err)
+		}
+		v.Build = ids
+	}
+
+	return v, nil
+}
+
+// String returns the version in semantic version format.
+func (v SemVer) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if len(v.PreRelease) > 0 {
+		s += "-" + strings.Join(v.PreRelease, ".")
+	}
+	if len(v.Build) > 0 {
+		s += "+" + strings.Join(v.Build, ".")
+	}
+	return s
+}
+
+func parseIdentifiers(s string) ([]string, error) {
+	ids := strings.Split(s, ".")
+	for _, id := range ids {
+		if id == "" {
+			return nil, fmt.Errorf("to consist of non-empty dot-separated identifiers")
+		}
+		for _, r := range id {
+			if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r == '-') {
+				return nil, fmt.Errorf("identifiers to contain only ASCII letters, digits and hyphens")
+			}
+		}
+	}
+	return ids, nil
+}
+
+func parseNumericIdentifier(s string) (int, error) {
+	if !isNumeric(s) || (len(s) > 1 && s[0] == '0') {
+		return 0, fmt.Errorf("invalid numeric identifier %q", s)
+	}
+	return strconv.Atoi(s)
+}
+
+func isNumeric(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,39 @@
+package pluginutil_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/pluginutil"
+)
+
+var _ = Describe("ParseSemVer", func() {
+	DescribeTable("valid versions",
+		func(s string, expected pluginutil.SemVer) {
+			v, err := pluginutil.ParseSemVer(s)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(v).To(Equal(expected))
+			Expect(v.String()).To(Equal(s))
+		},
+		Entry("release", "1.4.0", pluginutil.SemVer{Major: 1, Minor: 4, Patch: 0}),
+		Entry("pre-release", "1.4.0-rc.2", pluginutil.SemVer{Major: 1, Minor: 4, PreRelease: []string{"rc", "2"}}),
+		Entry("build metadata", "1.4.0+abc123", pluginutil.SemVer{Major: 1, Minor: 4, Build: []string{"abc123"}}),
+		Entry("pre-release and build metadata", "1.4.0-rc.2+abc123", pluginutil.SemVer{Major: 1, Minor: 4, PreRelease: []string{"rc", "2"}, Build: []string{"abc123"}}),
+		Entry("hyphens in identifiers", "10.20.30-alpha-beta.x-1+build.0007-x", pluginutil.SemVer{Major: 10, Minor: 20, Patch: 30, PreRelease: []string{"alpha-beta", "x-1"}, Build: []string{"build", "0007-x"}}),
+	)
+
+	DescribeTable("invalid versions",
+		func(s string, message string) {
+			_, err := pluginutil.ParseSemVer(s)
+			Expect(err).To(MatchError(message))
+		},
+		Entry("too few components", "1.4", `"1.4" has invalid format. Expected 3 dot-separated integer components.`),
+		Entry("leading v", "v1.4.0", `"v1.4.0" has invalid format. Expected integer components.`),
+		Entry("leading zero", "1.04.0", `"1.04.0" has invalid format. Expected integer components.`),
+		Entry("empty pre-release", "1.4.0-", `"1.4.0-" has invalid format. Expected pre-release to consist of non-empty dot-separated identifiers.`),
+		Entry("empty pre-release identifier", "1.4.0-rc..2", `"1.4.0-rc..2" has invalid format. Expected pre-release to consist of non-empty dot-separated identifiers.`),
+		Entry("numeric pre-release identifier with leading zero", "1.4.0-rc.02", `"1.4.0-rc.02" has invalid format. Expected numeric pre-release identifiers without leading zeros.`),
+		Entry("invalid pre-release character", "1.4.0-rc_2", `"1.4.0-rc_2" has invalid format. Expected pre-release identifiers to contain only ASCII letters, digits and hyphens.`),
+		Entry("empty build metadata", "1.4.0+", `"1.4.0+" has invalid format. Expected build metadata to consist of non-empty dot-separated identifiers.`),
+		Entry("second plus", "1.4.0+abc+def", `"1.4.0+abc+def" has invalid format. Expected build metadata identifiers to contain only ASCII letters, digits and hyphens.`),
+	)
+})
@@ -1,9 +1,6 @@
 package pluginutil
 
 import (
-	"strconv"
-	"strings"
-
 	"code.cloudfoundry.org/cli/plugin"
 )
 
@@ -12,31 +9,20 @@ const numComponents = 3
 // ParsePluginVersion parses the given plugin version and return its parsed form. If the given plugin
 // version is invalid, calls the given fail function with a suitable message. The fail function will
 // typically, except in testing, exit the process or panic.
+//
+// The plugin version may be any semantic version, such as 1.4.0-rc.2+abc123. The cf CLI only records
+// three integer components, so the major, minor and patch versions are mapped to the major, minor and
+// build components of the plugin version and any pre-release identifiers and build metadata are omitted.
 func ParsePluginVersion(pv string, fail func(format string, inserts ...interface{})) plugin.VersionType {
-	v := getPluginVersionComponents(pv, fail)
-
-	return plugin.VersionType{
-		Major: v[0],
-		Minor: v[1],
-		Build: v[2],
+	v, err := ParseSemVer(pv)
+	if err != nil {
+		fail("pluginVersion %s", err)
+		return plugin.VersionType{}
 	}
-}
 
-func getPluginVersionComponents(pv string, fail func(format string, inserts ...interface{})) []int {
-	intComponents := make([]int, numComponents)
-	components := strings.Split(pv, ".")
-	if len(components) != numComponents {
-		fail(`pluginVersion %q has invalid format. Expected %d dot-separated integer components.`, pv, numComponents)
-		return intComponents
-	}
-
-	for c := 0; c < numComponents; c++ {
-		v, err := strconv.Atoi(components[c])
-		if err != nil {
-			fail(`pluginVersion %q has invalid format. Expected integer components.`, pv)
-			return intComponents
-		}
-		intComponents[c] = v
+	return plugin.VersionType{
+		Major: v.Major,
+		Minor: v.Minor,
+		Build: v.Patch,
 	}
-	return intComponents
 }
@@ -52,6 +52,38 @@ var _ = Describe("ParsePluginVersion", func() {
 		})
 	})
 
+	Context("when the input version has pre-release identifiers and build metadata", func() {
+		BeforeEach(func() {
+			pluginVersion = "1.4.0-rc.2+abc123"
+		})
+
+		It("should not fail", func() {
+			Expect(failed).To(BeFalse())
+		})
+
+		It("should map the major, minor and patch versions onto the plugin version", func() {
+			Expect(parsedVersion).To(Equal(plugin.VersionType{
+				Major: 1,
+				Minor: 4,
+				Build: 0,
+			}))
+		})
+	})
+
+	Context("when the input version has an invalid pre-release identifier", func() {
+		BeforeEach(func() {
+			pluginVersion = "1.4.0-rc.02"
+		})
+
+		It("should fail", func() {
+			Expect(failed).To(BeTrue())
+		})
+
+		It("should provide a suitable message", func() {
+			Expect(firstFailure).To(Equal(`pluginVersion "1.4.0-rc.02" has invalid format. Expected numeric pre-release identifiers without leading zeros.`))
+		})
+	})
+
 	Context("when the input version has the wrong number of components", func() {
 		BeforeEach(func() {
 			pluginVersion = "2.0"
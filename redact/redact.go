@@ -0,0 +1,150 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package redact
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+)
+
+// Redacted replaces secrets in log messages.
+const Redacted = "[REDACTED]"
+
+// Rule matches secrets in log messages. If the pattern has capturing groups, the text matched by each group is
+// redacted. Otherwise the text matched by the whole pattern is redacted.
+type Rule struct {
+	Name    string
+	Pattern *regexp.Regexp
+}
+
+// BuiltinRules match commonly logged secrets.
+var BuiltinRules = []Rule{
+	{Name: "uri-credentials", Pattern: regexp.MustCompile(`[A-Za-z][A-Za-z0-9+.-]*://[^/\s:@]+:([^@\s/]+)@`)},
+	{Name: "password", Pattern: regexp.MustCompile(`(?i)\b(?:password|passwd|pwd|secret)\s*[=:]\s*([^\s"'&,;]+)`)},
+	{Name: "json-password", Pattern: regexp.MustCompile(`(?i)"(?:password|passwd|pwd|secret)"\s*:\s*"((?:[^"\\]|\\.)*)"`)},
+	{Name: "bearer-token", Pattern: regexp.MustCompile(`(?i)\bbearer\s+([A-Za-z0-9\-._~+/]+=*)`)},
+	{Name: "jwt", Pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]*`)},
+	{Name: "aws-access-key", Pattern: regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)},
+	{Name: "aws-secret-key", Pattern: regexp.MustCompile(`(?i)aws_secret_access_key\s*[=:]\s*"?([A-Za-z0-9/+=]{40})`)},
+	{Name: "private-key", Pattern: regexp.MustCompile(`(?s)-----BEGIN [A-Z ]*PRIVATE KEY-----(.*?)-----END [A-Z ]*PRIVATE KEY-----`)},
+}
+
+var (
+	privateKeyBegin = regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----`)
+	privateKeyEnd   = regexp.MustCompile(`-----END [A-Z ]*PRIVATE KEY-----`)
+)
+
+// NewRule returns a rule with the given name and regular expression.
+func NewRule(name string, pattern string) (Rule, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return Rule{}, fmt.Errorf("Invalid redaction rule %q: %s", name, err)
+	}
+	return Rule{Name: name, Pattern: re}, nil
+}
+
+// Redactor replaces the secrets matched by its rules in log records.
+//
+// A private key is often logged one line per record, so once a record from a source contains the start of a
+// private key without its end, subsequent records from the same source are redacted until one contains the end.
+type Redactor struct {
+	rules []Rule
+
+	mutex        sync.Mutex
+	inPrivateKey map[source]bool
+}
+
+type source struct {
+	serviceInstance string
+	sourceType      string
+	sourceInstance  string
+}
+
+// New returns a redactor which applies the built-in rules followed by the given rules.
+func New(rules ...Rule) *Redactor {
+	return &Redactor{
+		rules:        append(append([]Rule{}, BuiltinRules...), rules...),
+		inPrivateKey: map[source]bool{},
+	}
+}
+
+// Redact returns the given message with the secrets matched by the redactor's rules replaced.
+func (r *Redactor) Redact(message string) string {
+	for _, rule := range r.rules {
+		message = redact(rule.Pattern, message)
+	}
+	return message
+}
+
+// Record returns the given log record with the secrets in its message replaced.
+func (r *Redactor) Record(record logclient.LogRecord) logclient.LogRecord {
+	key := source{serviceInstance: record.ServiceInstance, sourceType: record.SourceType, sourceInstance: record.SourceInstance}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	if r.inPrivateKey[key] {
+		end := privateKeyEnd.FindStringIndex(record.Message)
+		if end == nil {
+			record.Message = Redacted
+			return record
+		}
+		delete(r.inPrivateKey, key)
+		record.Message = Redacted + record.Message[end[0]:]
+	}
+
+	record.Message = r.Redact(record.Message)
+	if begin := privateKeyBegin.FindStringIndex(record.Message); begin != nil && !privateKeyEnd.MatchString(record.Message[begin[1]:]) {
+		r.inPrivateKey[key] = true
+		record.Message = record.Message[:begin[1]] + Redacted
+	}
+	return record
+}
+
+// redact replaces the text matched by the given pattern's capturing groups, or by the whole pattern if it has no
+// groups, with Redacted.
+func redact(pattern *regexp.Regexp, message string) string {
+	matches := pattern.FindAllStringSubmatchIndex(message, -1)
+	if matches == nil {
+		return message
+	}
+
+	var redacted strings.Builder
+	last := 0
+	for _, match := range matches {
+		if pattern.NumSubexp() == 0 {
+			redacted.WriteString(message[last:match[0]])
+			redacted.WriteString(Redacted)
+			last = match[1]
+			continue
+		}
+		for group := 1; group <= pattern.NumSubexp(); group++ {
+			start, end := match[2*group], match[2*group+1]
+			if start < last || start < 0 {
+				continue
+			}
+			redacted.WriteString(message[last:start])
+			redacted.WriteString(Redacted)
+			last = end
+		}
+	}
+	redacted.WriteString(message[last:])
+	return redacted.String()
+}
@@ -0,0 +1,44 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package redact
+
+import (
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/sink"
+)
+
+type redactingSink struct {
+	next     sink.Sink
+	redactor *Redactor
+}
+
+// NewSink returns a sink which redacts the messages of log records before writing them to the given sink.
+func NewSink(next sink.Sink, redactor *Redactor) sink.Sink {
+	return &redactingSink{next: next, redactor: redactor}
+}
+
+func (rs *redactingSink) Write(record logclient.LogRecord) error {
+	return rs.next.Write(rs.redactor.Record(record))
+}
+
+func (rs *redactingSink) Flush() error {
+	return rs.next.Flush()
+}
+
+func (rs *redactingSink) Close() error {
+	return rs.next.Close()
+}
@@ -0,0 +1,71 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package redact_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/redact"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/sink"
+)
+
+// recordingSink remembers the records written to it.
+type recordingSink struct {
+	records []logclient.LogRecord
+	flushed bool
+	closed  bool
+}
+
+func (rs *recordingSink) Write(record logclient.LogRecord) error {
+	rs.records = append(rs.records, record)
+	return nil
+}
+
+func (rs *recordingSink) Flush() error {
+	rs.flushed = true
+	return nil
+}
+
+func (rs *recordingSink) Close() error {
+	rs.closed = true
+	return nil
+}
+
+var _ = Describe("Sink", func() {
+	var (
+		next *recordingSink
+		s    sink.Sink
+	)
+
+	BeforeEach(func() {
+		next = &recordingSink{}
+		s = redact.NewSink(next, redact.New())
+	})
+
+	It("should redact messages before writing them", func() {
+		Expect(s.Write(logclient.LogRecord{Message: "password=hunter2", SourceType: "postgres"})).To(Succeed())
+		Expect(next.records).To(Equal([]logclient.LogRecord{{Message: "password=[REDACTED]", SourceType: "postgres"}}))
+	})
+
+	It("should pass on flush and close", func() {
+		Expect(s.Flush()).To(Succeed())
+		Expect(s.Close()).To(Succeed())
+		Expect(next.flushed).To(BeTrue())
+		Expect(next.closed).To(BeTrue())
+	})
+})
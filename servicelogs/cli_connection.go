@@ -0,0 +1,54 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package servicelogs
+
+import (
+	"context"
+	"strings"
+
+	"code.cloudfoundry.org/cli/plugin"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/cfutil"
+)
+
+// CliConnectionAdapter is a Cloud Controller and token source which uses a cf CLI plugin connection. When it is
+// passed to NewClient as both, service instances may also be looked up in the targeted org and space.
+type CliConnectionAdapter struct {
+	conn plugin.CliConnection
+}
+
+// NewCliConnectionAdapter returns an adapter for the given cf CLI plugin connection.
+func NewCliConnectionAdapter(conn plugin.CliConnection) *CliConnectionAdapter {
+	return &CliConnectionAdapter{conn: conn}
+}
+
+// Get issues the request with 'cf curl', which cannot be cancelled.
+func (a *CliConnectionAdapter) Get(_ context.Context, path string) ([]byte, error) {
+	output, err := a.conn.CliCommandWithoutTerminalOutput("curl", path)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(strings.Join(output, "\n")), nil
+}
+
+// AccessToken returns the cf CLI's access token, which the cf CLI refreshes if necessary.
+func (a *CliConnectionAdapter) AccessToken(context.Context) (string, error) {
+	return cfutil.GetToken(a.conn)
+}
+
+func (a *CliConnectionAdapter) cliConnection() cfutil.Connection {
+	return a.conn
+}
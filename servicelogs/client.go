@@ -0,0 +1,147 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package servicelogs obtains the logs of Cloud Foundry service instances without the cf CLI plugin runtime, so
+// that they can be retrieved from other Go programs and test harnesses.
+package servicelogs
+
+import (
+	"context"
+	"errors"
+
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/cache"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/cfutil"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logging"
+)
+
+// Record is a log record of a service instance. ServiceInstance is only set when several service instances are
+// selected.
+type Record = logclient.LogRecord
+
+// Selector identifies the service instances whose logs are required: the service instance with a GUID, the managed
+// service instances matching a label selector such as "team=payments,tier=prod", or a named service instance.
+//
+// A named service instance, or the instances matching a label selector, are looked up in the given org and space.
+// If AllSpaces is set, instances matching a label selector are looked up in every space of the given org instead.
+// Service instances can only be looked up in the targeted org and space through a cf CLI connection.
+type Selector struct {
+	Guid          string
+	LabelSelector string
+	Name          string
+	Org           string
+	Space         string
+	AllSpaces     bool
+}
+
+// Client obtains the logs of service instances, looking them up through the Cloud Controller and authenticating to
+// their logs endpoints with tokens from a token source. A Client may be used concurrently.
+type Client struct {
+	cc                CloudController
+	tokens            TokenSource
+	options           logging.Options
+	skipSSLValidation bool
+	logClientBuilder  func() logclient.LogClientBuilder
+}
+
+// NewClient returns a client which uses the given Cloud Controller and token source. A CliConnectionAdapter
+// provides both from a cf CLI plugin connection.
+func NewClient(cc CloudController, tokens TokenSource) *Client {
+	return &Client{
+		cc:     cc,
+		tokens: tokens,
+		logClientBuilder: func() logclient.LogClientBuilder {
+			return logclient.NewLogClientBuilder()
+		},
+	}
+}
+
+// SkipSSLValidation determines whether the certificates of logs endpoints are verified. Not recommended!
+func (c *Client) SkipSSLValidation(skip bool) *Client {
+	c.skipSSLValidation = skip
+	return c
+}
+
+// Options determines how log records are processed. Only joining of multi-line log entries applies to recent logs.
+func (c *Client) Options(options logging.Options) *Client {
+	c.options = options
+	return c
+}
+
+// Recent returns the recent logs of the selected service instances, oldest first. If the context is cancelled
+// before the logs are received, the context's error is returned.
+func (c *Client) Recent(ctx context.Context, selector Selector) ([]Record, error) {
+	cfSelector, err := selector.cfutilSelector()
+	if err != nil {
+		return nil, err
+	}
+	records, err := logging.RecentLogRecords(ctx, c.connection(ctx), cfSelector, cache.None(), c.newLogClientBuilder())
+	if err != nil {
+		return nil, err
+	}
+	if c.options.Join != nil {
+		records = logging.JoinRecords(records, *c.options.Join)
+	}
+	return records, nil
+}
+
+// Tail starts tailing the logs of the selected service instances. An error is returned if the service instances
+// cannot be looked up.
+//
+// Tailing continues until the context is cancelled, whereupon the records already received are delivered and both
+// channels are closed, so callers must receive from both until they are closed. Errors, such as a failed connection
+// to a logs endpoint, do not stop tailing.
+func (c *Client) Tail(ctx context.Context, selector Selector) (<-chan Record, <-chan error, error) {
+	cfSelector, err := selector.cfutilSelector()
+	if err != nil {
+		return nil, nil, err
+	}
+	return logging.TailLogRecords(ctx, c.connection(ctx), cfSelector, cache.None(), c.options, c.newLogClientBuilder())
+}
+
+// newLogClientBuilder returns a builder of log clients. A builder is not safe for concurrent use, so each request
+// uses its own.
+func (c *Client) newLogClientBuilder() logclient.LogClientBuilder {
+	return c.logClientBuilder().InsecureSkipVerify(c.skipSSLValidation)
+}
+
+func (s Selector) cfutilSelector() (cfutil.Selector, error) {
+	selected := 0
+	for _, field := range []string{s.Guid, s.LabelSelector, s.Name} {
+		if field != "" {
+			selected++
+		}
+	}
+	if selected != 1 {
+		return cfutil.Selector{}, errors.New("Select service instances by exactly one of GUID, label selector or name")
+	}
+	if s.Guid != "" && (s.Org != "" || s.Space != "") {
+		return cfutil.Selector{}, errors.New("A service instance selected by GUID cannot also be selected by org or space")
+	}
+	if s.AllSpaces && (s.LabelSelector == "" || s.Space != "") {
+		return cfutil.Selector{}, errors.New("All spaces can only be searched for instances matching a label selector in an org")
+	}
+	if s.Org != "" && s.Space == "" && !s.AllSpaces {
+		return cfutil.Selector{}, errors.New("A space is required when an org is given")
+	}
+	return cfutil.Selector{
+		Target:        cfutil.Target{Org: s.Org, Space: s.Space},
+		Name:          s.Name,
+		Guid:          s.Guid,
+		LabelSelector: s.LabelSelector,
+		AllSpaces:     s.AllSpaces,
+	}, nil
+}
@@ -0,0 +1,229 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package servicelogs_test
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"code.cloudfoundry.org/cli/plugin/models"
+	"code.cloudfoundry.org/cli/plugin/pluginfakes"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient/logclientfakes"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logging"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/servicelogs"
+)
+
+// fakeCloudController serves canned response bodies and records the paths requested.
+type fakeCloudController struct {
+	bodies    map[string]string
+	requested []string
+}
+
+func (f *fakeCloudController) Get(_ context.Context, path string) ([]byte, error) {
+	f.requested = append(f.requested, path)
+	body, ok := f.bodies[path]
+	if !ok {
+		return nil, errors.New("404 Not Found")
+	}
+	return []byte(body), nil
+}
+
+var _ = Describe("Client", func() {
+	var (
+		cc                   *fakeCloudController
+		fakeLogClientBuilder *logclientfakes.FakeLogClientBuilder
+		fakeLogClient        *logclientfakes.FakeLogClient
+		client               *servicelogs.Client
+		selector             servicelogs.Selector
+	)
+
+	BeforeEach(func() {
+		cc = &fakeCloudController{bodies: map[string]string{
+			"/v2/service_instances/guid-a":                        `{"entity": {"name": "db", "service_plan_guid": "plan"}}`,
+			"/v2/organizations?q=name%3Aorg":                      `{"resources": [{"metadata": {"guid": "org-guid"}, "entity": {}}]}`,
+			"/v2/organizations/org-guid/spaces?q=name%3Aspace":    `{"resources": [{"metadata": {"guid": "space-guid"}, "entity": {}}]}`,
+			"/v2/spaces/space-guid/service_instances?q=name%3Adb": `{"resources": [{"metadata": {"guid": "guid-a"}, "entity": {"service_plan_guid": "plan"}}]}`,
+			"/v2/service_instances/guid-a/shared_from":            ``,
+			"/v2/service_plans/plan":                              `{"entity": {"service_guid": "service"}}`,
+			"/v2/services/service":                                `{"entity": {"extra": "{\"serviceInstanceLogsEndpoint\":\"https://service-instance-logs/logs/\"}"}}`,
+		}}
+		fakeLogClientBuilder = &logclientfakes.FakeLogClientBuilder{}
+		fakeLogClient = &logclientfakes.FakeLogClient{}
+		fakeLogClientBuilder.EndpointReturns(fakeLogClientBuilder)
+		fakeLogClientBuilder.TokenSourceReturns(fakeLogClientBuilder)
+		fakeLogClientBuilder.InsecureSkipVerifyReturns(fakeLogClientBuilder)
+		fakeLogClientBuilder.BuildReturns(fakeLogClient)
+		fakeLogClient.StreamRecentLogsStub = streamRecords(logclient.LogRecord{Message: "hello"})
+
+		client = servicelogs.NewClient(cc, servicelogs.StaticToken("some-token"))
+		client.SetLogClientBuilder(fakeLogClientBuilder)
+		selector = servicelogs.Selector{Guid: "guid-a"}
+	})
+
+	Describe("recent logs", func() {
+		It("should look up the service instance and return its recent logs", func() {
+			records, err := client.Recent(context.Background(), selector)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(records).To(Equal([]servicelogs.Record{{Message: "hello"}}))
+			Expect(fakeLogClientBuilder.EndpointArgsForCall(0)).To(Equal("https://service-instance-logs/logs/"))
+			_, guid, token, _ := fakeLogClient.StreamRecentLogsArgsForCall(0)
+			Expect(guid).To(Equal("guid-a"))
+			Expect(token).To(Equal("some-token"))
+		})
+
+		It("should honour the SSL validation setting", func() {
+			_, err := client.SkipSSLValidation(true).Recent(context.Background(), selector)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeLogClientBuilder.InsecureSkipVerifyArgsForCall(0)).To(BeTrue())
+		})
+
+		It("should join multi-line log entries if required", func() {
+			fakeLogClient.StreamRecentLogsStub = streamRecords(
+				logclient.LogRecord{Timestamp: time.Unix(1, 0), Message: "java.lang.IllegalStateException: boom"},
+				logclient.LogRecord{Timestamp: time.Unix(1, 0), Message: "\tat com.example.Main.main(Main.java:3)"},
+			)
+			records, err := client.Options(logging.Options{Join: &logging.JoinOptions{}}).Recent(context.Background(), selector)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(records).To(HaveLen(1))
+			Expect(records[0].Message).To(Equal("java.lang.IllegalStateException: boom\n\tat com.example.Main.main(Main.java:3)"))
+		})
+
+		Context("when the service instance is named in an org and space", func() {
+			BeforeEach(func() {
+				selector = servicelogs.Selector{Org: "org", Space: "space", Name: "db"}
+			})
+
+			It("should look up the service instance in that space", func() {
+				_, err := client.Recent(context.Background(), selector)
+				Expect(err).NotTo(HaveOccurred())
+				_, guid, _, _ := fakeLogClient.StreamRecentLogsArgsForCall(0)
+				Expect(guid).To(Equal("guid-a"))
+			})
+		})
+
+		Context("when the service instance is named without an org and space", func() {
+			BeforeEach(func() {
+				selector = servicelogs.Selector{Name: "db"}
+			})
+
+			It("should return a suitable error", func() {
+				_, err := client.Recent(context.Background(), selector)
+				Expect(err).To(MatchError("No org or space is targeted: give the org and space of the service instance"))
+			})
+		})
+
+		Context("when the Cloud Controller fails", func() {
+			BeforeEach(func() {
+				delete(cc.bodies, "/v2/service_instances/guid-a")
+			})
+
+			It("should return the error", func() {
+				_, err := client.Recent(context.Background(), selector)
+				Expect(err).To(MatchError("/v2/service_instances/guid-a failed: 404 Not Found"))
+			})
+		})
+	})
+
+	DescribeTable("invalid selectors",
+		func(selector servicelogs.Selector, message string) {
+			_, err := client.Recent(context.Background(), selector)
+			Expect(err).To(MatchError(message))
+			Expect(cc.requested).To(BeEmpty())
+		},
+		Entry("nothing selected", servicelogs.Selector{}, "Select service instances by exactly one of GUID, label selector or name"),
+		Entry("several selected", servicelogs.Selector{Guid: "guid-a", Name: "db"}, "Select service instances by exactly one of GUID, label selector or name"),
+		Entry("GUID in a space", servicelogs.Selector{Guid: "guid-a", Org: "org", Space: "space"}, "A service instance selected by GUID cannot also be selected by org or space"),
+		Entry("org without a space", servicelogs.Selector{Name: "db", Org: "org"}, "A space is required when an org is given"),
+		Entry("all spaces without a label selector", servicelogs.Selector{Name: "db", AllSpaces: true}, "All spaces can only be searched for instances matching a label selector in an org"),
+		Entry("all spaces and a space", servicelogs.Selector{LabelSelector: "team=payments", Space: "space", AllSpaces: true}, "All spaces can only be searched for instances matching a label selector in an org"),
+	)
+
+	Describe("tailing logs", func() {
+		BeforeEach(func() {
+			fakeLogClient.TailingLogsStub = func(ctx context.Context, guid string, token string) (<-chan logclient.LogRecord, <-chan error) {
+				messageChan := make(chan logclient.LogRecord, 1)
+				errChan := make(chan error)
+				messageChan <- logclient.LogRecord{Message: "hello from " + guid}
+				go func() {
+					<-ctx.Done()
+					close(messageChan)
+					close(errChan)
+				}()
+				return messageChan, errChan
+			}
+		})
+
+		It("should stream records until the context is cancelled", func() {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			records, errs, err := client.Tail(ctx, selector)
+			Expect(err).NotTo(HaveOccurred())
+			Eventually(records).Should(Receive(Equal(servicelogs.Record{Message: "hello from guid-a"})))
+			cancel()
+			Eventually(records).Should(BeClosed())
+			Eventually(errs).Should(BeClosed())
+		})
+
+		It("should return an invalid selector error without tailing", func() {
+			_, _, err := client.Tail(context.Background(), servicelogs.Selector{})
+			Expect(err).To(HaveOccurred())
+			Expect(fakeLogClient.TailingLogsCallCount()).To(Equal(0))
+		})
+	})
+
+	Describe("using a cf CLI connection", func() {
+		var fakeCliConnection *pluginfakes.FakeCliConnection
+
+		BeforeEach(func() {
+			fakeCliConnection = &pluginfakes.FakeCliConnection{}
+			fakeCliConnection.AccessTokenReturns("bearer cli-token", nil)
+			fakeCliConnection.GetServiceReturns(plugin_models.GetService_Model{Guid: "guid-a", ServicePlan: plugin_models.GetService_ServicePlan{Guid: "plan"}}, nil)
+			fakeCliConnection.CliCommandWithoutTerminalOutputStub = func(args ...string) ([]string, error) {
+				body, err := cc.Get(context.Background(), args[1])
+				return []string{string(body)}, err
+			}
+			adapter := servicelogs.NewCliConnectionAdapter(fakeCliConnection)
+			client = servicelogs.NewClient(adapter, adapter)
+			client.SetLogClientBuilder(fakeLogClientBuilder)
+		})
+
+		It("should look up a named service instance in the targeted space", func() {
+			_, err := client.Recent(context.Background(), servicelogs.Selector{Name: "db"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(fakeCliConnection.GetServiceArgsForCall(0)).To(Equal("db"))
+			_, guid, token, _ := fakeLogClient.StreamRecentLogsArgsForCall(0)
+			Expect(guid).To(Equal("guid-a"))
+			Expect(token).To(Equal("cli-token"))
+		})
+	})
+})
+
+// streamRecords returns a StreamRecentLogs stub which passes the given records to its function.
+func streamRecords(records ...logclient.LogRecord) func(context.Context, string, string, func(logclient.LogRecord) error) error {
+	return func(_ context.Context, _ string, _ string, each func(logclient.LogRecord) error) error {
+		for _, record := range records {
+			if err := each(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
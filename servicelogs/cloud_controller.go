@@ -0,0 +1,108 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package servicelogs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"code.cloudfoundry.org/cli/plugin/models"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/cfutil"
+)
+
+// CloudController provides access to the Cloud Controller API.
+type CloudController interface {
+	// Get issues a GET request for the given path, such as "/v3/service_instances?names=db", and returns the
+	// response body. Error responses whose bodies describe the error in the Cloud Controller V2 format are returned
+	// as bodies, as by 'cf curl'.
+	Get(ctx context.Context, path string) ([]byte, error)
+}
+
+// TokenSource supplies the access tokens used to authenticate to the Cloud Controller and to logs endpoints.
+type TokenSource interface {
+	// AccessToken returns a UAA access token, without the "bearer " prefix.
+	AccessToken(ctx context.Context) (string, error)
+}
+
+// StaticToken returns a token source which always supplies the given access token.
+func StaticToken(token string) TokenSource {
+	return staticToken(token)
+}
+
+type staticToken string
+
+func (t staticToken) AccessToken(context.Context) (string, error) {
+	return string(t), nil
+}
+
+// errNoTarget is returned when a service instance would have to be looked up in the targeted org or space but
+// there is no cf CLI connection to provide the target.
+var errNoTarget = errors.New("No org or space is targeted: give the org and space of the service instance")
+
+// targeted is implemented by Cloud Controllers which provide the targeted org and space, so that service instances
+// may be looked up by name in the targeted space.
+type targeted interface {
+	cliConnection() cfutil.Connection
+}
+
+// connection adapts a Cloud Controller and token source, using the given context for requests, to the connection
+// used to look up service instances.
+func (c *Client) connection(ctx context.Context) cfutil.Connection {
+	if t, ok := c.cc.(targeted); ok && interface{}(c.tokens) == interface{}(c.cc) {
+		return t.cliConnection()
+	}
+	return &connection{ctx: ctx, cc: c.cc, tokens: c.tokens}
+}
+
+type connection struct {
+	ctx    context.Context
+	cc     CloudController
+	tokens TokenSource
+}
+
+func (c *connection) CliCommandWithoutTerminalOutput(args ...string) ([]string, error) {
+	if len(args) != 2 || args[0] != "curl" {
+		return nil, fmt.Errorf("Unsupported command: cf %s", strings.Join(args, " "))
+	}
+	body, err := c.cc.Get(c.ctx, args[1])
+	if err != nil {
+		return nil, err
+	}
+	return []string{string(body)}, nil
+}
+
+func (c *connection) AccessToken() (string, error) {
+	token, err := c.tokens.AccessToken(c.ctx)
+	if err != nil {
+		return "", err
+	}
+	return "bearer " + token, nil
+}
+
+func (c *connection) GetCurrentOrg() (plugin_models.Organization, error) {
+	return plugin_models.Organization{}, errNoTarget
+}
+
+func (c *connection) GetCurrentSpace() (plugin_models.Space, error) {
+	return plugin_models.Space{}, errNoTarget
+}
+
+func (c *connection) GetService(string) (plugin_models.GetService_Model, error) {
+	return plugin_models.GetService_Model{}, errNoTarget
+}
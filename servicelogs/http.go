@@ -0,0 +1,95 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package servicelogs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+type httpCloudController struct {
+	apiEndpoint string
+	httpClient  *http.Client
+	tokens      TokenSource
+}
+
+// NewHTTPCloudController returns a Cloud Controller which issues requests to the given API endpoint, such as
+// "https://api.example.com", with the given HTTP client, authenticated by tokens from the given token source. If
+// the HTTP client is nil, http.DefaultClient is used.
+func NewHTTPCloudController(apiEndpoint string, httpClient *http.Client, tokens TokenSource) CloudController {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &httpCloudController{apiEndpoint: strings.TrimSuffix(apiEndpoint, "/"), httpClient: httpClient, tokens: tokens}
+}
+
+func (h *httpCloudController) Get(ctx context.Context, path string) ([]byte, error) {
+	token, err := h.tokens.AccessToken(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Access token not available: %s", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, h.apiEndpoint+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "bearer "+token)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := h.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 300 || isV2Error(body) {
+		return body, nil
+	}
+	return nil, responseError(resp.Status, body)
+}
+
+// isV2Error determines whether the given response body describes an error in the Cloud Controller V2 format.
+func isV2Error(body []byte) bool {
+	var v2 struct {
+		ErrorCode string `json:"error_code"`
+	}
+	return json.Unmarshal(body, &v2) == nil && v2.ErrorCode != ""
+}
+
+// responseError returns the error described by the body of a Cloud Controller V3 error response, or an error giving
+// the response status if the body does not describe the error.
+func responseError(status string, body []byte) error {
+
+	var v3 struct {
+		Errors []struct {
+			Detail string `json:"detail"`
+			Title  string `json:"title"`
+		} `json:"errors"`
+	}
+	if json.Unmarshal(body, &v3) == nil && len(v3.Errors) > 0 {
+		return fmt.Errorf("%s (%s)", v3.Errors[0].Detail, v3.Errors[0].Title)
+	}
+	return errors.New(status)
+}
@@ -0,0 +1,116 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package servicelogs_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/servicelogs"
+)
+
+// failingToken is a token source which cannot supply a token.
+type failingToken struct{}
+
+func (failingToken) AccessToken(context.Context) (string, error) {
+	return "", errors.New("no dice")
+}
+
+var _ = Describe("HTTP Cloud Controller", func() {
+	var (
+		server  *httptest.Server
+		handler http.HandlerFunc
+		tokens  servicelogs.TokenSource
+		body    []byte
+		err     error
+	)
+
+	BeforeEach(func() {
+		tokens = servicelogs.StaticToken("some-token")
+		handler = func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.Header.Get("Authorization")).To(Equal("bearer some-token"))
+			w.Write([]byte(`{"path": "` + r.URL.RequestURI() + `"}`))
+		}
+	})
+
+	JustBeforeEach(func() {
+		server = httptest.NewServer(handler)
+		body, err = servicelogs.NewHTTPCloudController(server.URL+"/", nil, tokens).Get(context.Background(), "/v3/service_instances?names=db")
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should issue an authenticated request for the path", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(body)).To(Equal(`{"path": "/v3/service_instances?names=db"}`))
+	})
+
+	Context("when the response describes a V2 error", func() {
+		BeforeEach(func() {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"code": 10000, "description": "Unknown request", "error_code": "CF-NotFound"}`))
+			}
+		})
+
+		It("should return the body, as cf curl does", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(body)).To(ContainSubstring("CF-NotFound"))
+		})
+	})
+
+	Context("when the response describes a V3 error", func() {
+		BeforeEach(func() {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				w.Write([]byte(`{"errors": [{"detail": "Unknown field(s): 'colour'", "title": "CF-UnprocessableEntity", "code": 10008}]}`))
+			}
+		})
+
+		It("should return the described error", func() {
+			Expect(err).To(MatchError("Unknown field(s): 'colour' (CF-UnprocessableEntity)"))
+		})
+	})
+
+	Context("when the response has no error description", func() {
+		BeforeEach(func() {
+			handler = func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusBadGateway)
+			}
+		})
+
+		It("should return the status", func() {
+			Expect(err).To(MatchError("502 Bad Gateway"))
+		})
+	})
+
+	Context("when a token is not available", func() {
+		BeforeEach(func() {
+			tokens = failingToken{}
+		})
+
+		It("should return a suitable error", func() {
+			Expect(err).To(MatchError("Access token not available: no dice"))
+		})
+	})
+})
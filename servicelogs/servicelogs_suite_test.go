@@ -0,0 +1,13 @@
+package servicelogs_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestServicelogs(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Servicelogs Suite")
+}
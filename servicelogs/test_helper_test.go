@@ -0,0 +1,14 @@
+package servicelogs
+
+import "github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+
+// Allow the client's log client builder to be replaced, but only in tests (since the name of this file ends in "...test.go").
+func (c *Client) SetLogClientBuilder(builder logclient.LogClientBuilder) {
+	c.logClientBuilder = func() logclient.LogClientBuilder {
+		return builder
+	}
+}
+
+type LogClientBuilderSetter interface {
+	SetLogClientBuilder(builder logclient.LogClientBuilder)
+}
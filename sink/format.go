@@ -0,0 +1,41 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package sink
+
+import (
+	"encoding/json"
+
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+)
+
+// Format renders a log record as a line of output, without a trailing newline.
+type Format func(record logclient.LogRecord) (string, error)
+
+// TextFormat renders a log record in the plugin's standard format. Sinks given a nil format render text without
+// this intermediate string.
+func TextFormat(record logclient.LogRecord) (string, error) {
+	return record.String(), nil
+}
+
+// JSONFormat renders a log record as a JSON object.
+func JSONFormat(record logclient.LogRecord) (string, error) {
+	line, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+	return string(line), nil
+}
@@ -0,0 +1,271 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package sink
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+)
+
+const (
+	ndjsonContentType    = "application/x-ndjson"
+	defaultBatchSize     = 100
+	defaultFlushInterval = 2 * time.Second
+	defaultMaxAttempts   = 3
+	defaultRetryBackoff  = 500 * time.Millisecond
+	defaultTimeout       = 30 * time.Second
+	maxQueuedBatches     = 4
+)
+
+// delivery is a batch of records waiting to be sent. If done is not nil, it is closed once the batch has been
+// sent or abandoned.
+type delivery struct {
+	records []logclient.LogRecord
+	done    chan struct{}
+}
+
+type httpSink struct {
+	endpoint      string
+	client        *http.Client
+	batchSize     int
+	flushInterval time.Duration
+	maxAttempts   int
+	retryBackoff  time.Duration
+
+	mutex     sync.Mutex
+	batch     []logclient.LogRecord
+	sendErr   error
+	dropping  bool
+	errWriter io.Writer
+
+	queue     chan delivery
+	stop      chan struct{}
+	stopped   chan struct{}
+	startOnce sync.Once
+	closeOnce sync.Once
+}
+
+// NewHTTPSink returns a sink which POSTs batches of records, encoded as newline delimited JSON, to the given
+// URL. A batch is sent when it is full, when it has been pending for the flush interval, or when the sink is
+// flushed or closed. Failed requests are retried with exponential backoff. If the client is nil, a client with
+// a 30 second timeout is used.
+//
+// Batches are sent in the background, so a collector which is slow or down never holds up, or fails, writes.
+// Records which cannot be delivered, or which cannot be queued because earlier batches are still being sent, are
+// discarded. Each such loss is reported to errWriter, if it is not nil, as soon as it happens, and the first is
+// returned by the next Flush or Close.
+func NewHTTPSink(endpoint string, client *http.Client, errWriter io.Writer) Sink {
+	if client == nil {
+		client = &http.Client{Timeout: defaultTimeout}
+	}
+	if errWriter == nil {
+		errWriter = io.Discard
+	}
+	return &httpSink{
+		errWriter:     errWriter,
+		endpoint:      endpoint,
+		client:        client,
+		batchSize:     defaultBatchSize,
+		flushInterval: defaultFlushInterval,
+		maxAttempts:   defaultMaxAttempts,
+		retryBackoff:  defaultRetryBackoff,
+		queue:         make(chan delivery, maxQueuedBatches),
+		stop:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+}
+
+func (hs *httpSink) Write(record logclient.LogRecord) error {
+	hs.startOnce.Do(hs.startSending)
+
+	hs.mutex.Lock()
+	hs.batch = append(hs.batch, record)
+	var full []logclient.LogRecord
+	if len(hs.batch) >= hs.batchSize {
+		full = hs.batch
+		hs.batch = nil
+	}
+	hs.mutex.Unlock()
+
+	if full == nil {
+		return nil
+	}
+	select {
+	case hs.queue <- delivery{records: full}:
+	default:
+		// Rather than hold up the caller, drop the batch while earlier batches are still being sent.
+		hs.dropped(len(full))
+	}
+	return nil
+}
+
+// Flush sends the pending batch and waits until it, and any batches queued before it, have been sent.
+func (hs *httpSink) Flush() error {
+	hs.startOnce.Do(hs.startSending)
+
+	hs.mutex.Lock()
+	pending := delivery{records: hs.batch, done: make(chan struct{})}
+	hs.batch = nil
+	hs.mutex.Unlock()
+
+	select {
+	case hs.queue <- pending:
+		select {
+		case <-pending.done:
+		case <-hs.stopped:
+		}
+	case <-hs.stopped:
+	}
+
+	hs.mutex.Lock()
+	defer hs.mutex.Unlock()
+	err := hs.sendErr
+	hs.sendErr = nil
+	return err
+}
+
+func (hs *httpSink) Close() error {
+	var err error
+	hs.closeOnce.Do(func() {
+		err = hs.Flush()
+		close(hs.stop)
+		<-hs.stopped
+	})
+	return err
+}
+
+// startSending starts the goroutine which sends batches, in the order they were queued, and sends the pending
+// batch each flush interval.
+func (hs *httpSink) startSending() {
+	go func() {
+		defer close(hs.stopped)
+		ticker := time.NewTicker(hs.flushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case d := <-hs.queue:
+				hs.deliver(d.records)
+				if d.done != nil {
+					close(d.done)
+				}
+			case <-ticker.C:
+				hs.mutex.Lock()
+				records := hs.batch
+				hs.batch = nil
+				hs.mutex.Unlock()
+				hs.deliver(records)
+			case <-hs.stop:
+				return
+			}
+		}
+	}()
+}
+
+// deliver sends the given records, reporting any failure at once and remembering it for the next Flush or Close.
+func (hs *httpSink) deliver(records []logclient.LogRecord) {
+	err := hs.send(records)
+	if err == nil {
+		if len(records) > 0 {
+			hs.mutex.Lock()
+			hs.dropping = false
+			hs.mutex.Unlock()
+		}
+		return
+	}
+	hs.failed(err)
+	fmt.Fprintf(hs.errWriter, "Warning: %s\n", err)
+}
+
+// dropped records the loss of a batch of the given size which could not be queued. Only the first of a run of
+// losses is reported at once, so that a slow collector does not flood errWriter.
+func (hs *httpSink) dropped(count int) {
+	hs.failed(fmt.Errorf("Failed to send %d log records to sink %s: earlier log records are still being sent", count, hs.endpoint))
+
+	hs.mutex.Lock()
+	warn := !hs.dropping
+	hs.dropping = true
+	hs.mutex.Unlock()
+	if warn {
+		fmt.Fprintf(hs.errWriter, "Warning: Sink %s is not keeping up, so log records are being dropped\n", hs.endpoint)
+	}
+}
+
+// failed remembers the first failure to deliver records since the last Flush or Close.
+func (hs *httpSink) failed(err error) {
+	hs.mutex.Lock()
+	defer hs.mutex.Unlock()
+	if hs.sendErr == nil {
+		hs.sendErr = err
+	}
+}
+
+// send sends the given records. The records are discarded even if they could not be delivered, so that a
+// collector which is down does not cause records to accumulate without limit.
+func (hs *httpSink) send(records []logclient.LogRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	var body bytes.Buffer
+	encoder := json.NewEncoder(&body)
+	for _, record := range records {
+		if err := encoder.Encode(record); err != nil {
+			return err
+		}
+	}
+
+	backoff := hs.retryBackoff
+	var err error
+	for attempt := 1; attempt <= hs.maxAttempts; attempt++ {
+		var retry bool
+		retry, err = hs.post(body.Bytes())
+		if err == nil || !retry {
+			break
+		}
+		if attempt < hs.maxAttempts {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("Failed to send %d log records to sink %s: %s", len(records), hs.endpoint, err)
+	}
+	return nil
+}
+
+// post sends a single request and reports whether a failure is worth retrying.
+func (hs *httpSink) post(body []byte) (bool, error) {
+	resp, err := hs.client.Post(hs.endpoint, ndjsonContentType, bytes.NewReader(body))
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+	retry := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retry, fmt.Errorf("unexpected response status %s", resp.Status)
+}
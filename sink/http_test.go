@@ -0,0 +1,217 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package sink_test
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/sink"
+)
+
+var _ = Describe("HTTPSink", func() {
+	var (
+		server      *httptest.Server
+		mutex       sync.Mutex
+		batches     [][]logclient.LogRecord
+		contentType string
+		statuses    []int
+		requests    int
+		release     chan struct{}
+		errOutput   *gbytes.Buffer
+		s           sink.Sink
+	)
+
+	received := func() [][]logclient.LogRecord {
+		mutex.Lock()
+		defer mutex.Unlock()
+		return batches
+	}
+
+	BeforeEach(func() {
+		batches = nil
+		statuses = nil
+		requests = 0
+		release = nil
+		server = httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if release != nil {
+				<-release
+			}
+
+			mutex.Lock()
+			defer mutex.Unlock()
+
+			requests++
+			if len(statuses) > 0 {
+				status := statuses[0]
+				statuses = statuses[1:]
+				if status != http.StatusOK {
+					rw.WriteHeader(status)
+					return
+				}
+			}
+
+			contentType = r.Header.Get("Content-Type")
+			batch := []logclient.LogRecord{}
+			scanner := bufio.NewScanner(r.Body)
+			for scanner.Scan() {
+				var record logclient.LogRecord
+				Expect(json.Unmarshal(scanner.Bytes(), &record)).To(Succeed())
+				batch = append(batch, record)
+			}
+			batches = append(batches, batch)
+		}))
+
+		errOutput = gbytes.NewBuffer()
+		s = sink.NewHTTPSink(server.URL, nil, errOutput)
+		setter, ok := s.(sink.HTTPFieldSetter)
+		Expect(ok).To(BeTrue())
+		setter.SetBatchSize(2)
+		setter.SetFlushInterval(time.Hour)
+		setter.SetRetryBackoff(time.Millisecond)
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("should use a client with a timeout by default", func() {
+		Expect(s.(sink.HTTPFieldSetter).Client().Timeout).To(Equal(30 * time.Second))
+	})
+
+	It("should not send anything until a batch is full", func() {
+		Expect(s.Write(logclient.LogRecord{Message: "one"})).To(Succeed())
+		Expect(received()).To(BeEmpty())
+	})
+
+	It("should send a full batch as newline delimited JSON", func() {
+		Expect(s.Write(logclient.LogRecord{Message: "one"})).To(Succeed())
+		Expect(s.Write(logclient.LogRecord{Message: "two", MessageType: "ERR"})).To(Succeed())
+
+		Eventually(received).Should(HaveLen(1))
+		Expect(received()[0]).To(HaveLen(2))
+		Expect(received()[0][0].Message).To(Equal("one"))
+		Expect(received()[0][1].Message).To(Equal("two"))
+		Expect(received()[0][1].MessageType).To(Equal("ERR"))
+		Expect(contentType).To(Equal("application/x-ndjson"))
+	})
+
+	It("should send a partial batch when flushed", func() {
+		Expect(s.Write(logclient.LogRecord{Message: "one"})).To(Succeed())
+		Expect(s.Flush()).To(Succeed())
+		Expect(received()).To(HaveLen(1))
+	})
+
+	It("should send a partial batch when closed", func() {
+		Expect(s.Write(logclient.LogRecord{Message: "one"})).To(Succeed())
+		Expect(s.Close()).To(Succeed())
+		Expect(received()).To(HaveLen(1))
+	})
+
+	Context("when the flush interval elapses", func() {
+		BeforeEach(func() {
+			s.(sink.HTTPFieldSetter).SetFlushInterval(10 * time.Millisecond)
+		})
+
+		AfterEach(func() {
+			Expect(s.Close()).To(Succeed())
+		})
+
+		It("should send a partial batch", func() {
+			Expect(s.Write(logclient.LogRecord{Message: "one"})).To(Succeed())
+			Eventually(received).Should(HaveLen(1))
+		})
+	})
+
+	Context("when the collector fails temporarily", func() {
+		BeforeEach(func() {
+			statuses = []int{http.StatusServiceUnavailable, http.StatusTooManyRequests}
+		})
+
+		It("should retry the batch", func() {
+			Expect(s.Write(logclient.LogRecord{Message: "one"})).To(Succeed())
+			Expect(s.Flush()).To(Succeed())
+			Expect(received()).To(HaveLen(1))
+			Expect(requests).To(Equal(3))
+		})
+	})
+
+	Context("when the collector keeps failing", func() {
+		BeforeEach(func() {
+			statuses = []int{http.StatusBadGateway, http.StatusBadGateway, http.StatusBadGateway}
+		})
+
+		It("should give up and return a suitable error", func() {
+			Expect(s.Write(logclient.LogRecord{Message: "one"})).To(Succeed())
+			Expect(s.Flush()).To(MatchError(ContainSubstring("Failed to send 1 log records to sink")))
+			Expect(requests).To(Equal(3))
+		})
+
+		It("should not fail writes, but report the failure at once and when flushed", func() {
+			Expect(s.Write(logclient.LogRecord{Message: "one"})).To(Succeed())
+			Expect(s.Write(logclient.LogRecord{Message: "two"})).To(Succeed())
+			Eventually(errOutput).Should(gbytes.Say("Warning: Failed to send 2 log records to sink"))
+			Expect(s.Write(logclient.LogRecord{Message: "three"})).To(Succeed())
+			Expect(s.Flush()).To(MatchError(ContainSubstring("Failed to send 2 log records to sink")))
+			Expect(s.Flush()).To(Succeed())
+		})
+	})
+
+	Context("when the collector is slow", func() {
+		BeforeEach(func() {
+			release = make(chan struct{})
+		})
+
+		It("should report dropped records at once without holding up writes", func() {
+			done := make(chan struct{})
+			go func() {
+				defer GinkgoRecover()
+				defer close(done)
+				for i := 0; i < 20; i++ {
+					Expect(s.Write(logclient.LogRecord{Message: "one"})).To(Succeed())
+				}
+			}()
+			Eventually(done).Should(BeClosed())
+			Expect(errOutput).To(gbytes.Say("Warning: Sink " + server.URL + " is not keeping up, so log records are being dropped"))
+			Expect(errOutput).NotTo(gbytes.Say("not keeping up"))
+
+			close(release)
+			Expect(s.Flush()).To(MatchError(ContainSubstring("earlier log records are still being sent")))
+			Expect(len(received())).To(BeNumerically(">=", 1))
+		})
+	})
+
+	Context("when the collector rejects the batch", func() {
+		BeforeEach(func() {
+			statuses = []int{http.StatusBadRequest}
+		})
+
+		It("should not retry", func() {
+			Expect(s.Write(logclient.LogRecord{Message: "one"})).To(Succeed())
+			Expect(s.Flush()).To(MatchError(ContainSubstring("400 Bad Request")))
+			Expect(requests).To(Equal(1))
+		})
+	})
+})
@@ -0,0 +1,84 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package sink
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+)
+
+const (
+	stdoutSpec     = "stdout"
+	fileSpecPrefix = "file:"
+)
+
+// Sink is a destination for log records. Implementations may buffer records, in which case they are only
+// guaranteed to have been delivered after Flush or Close returns successfully.
+type Sink interface {
+	Write(record logclient.LogRecord) error
+	Flush() error
+	Close() error
+}
+
+// New returns a sink for each of the given specifications, teed together if there is more than one. A
+// specification is "stdout", "file:PATH" or an http(s) URL. With no specifications, records are written to stdout.
+// Records written to stdout or a file are rendered by the given format, or as text if the format is nil, and
+// buffered as by NewBufferedWriterSink. Records sent to an http(s) URL are always encoded as JSON, and failures to
+// send them are reported to stderr.
+func New(specs []string, stdout io.Writer, stderr io.Writer, httpClient *http.Client, format Format) (Sink, error) {
+	if len(specs) == 0 {
+		return NewBufferedWriterSink(stdout, format), nil
+	}
+
+	sinks := []Sink{}
+	for _, spec := range specs {
+		s, err := parse(spec, stdout, stderr, httpClient, format)
+		if err != nil {
+			for _, opened := range sinks {
+				opened.Close()
+			}
+			return nil, err
+		}
+		sinks = append(sinks, s)
+	}
+
+	if len(sinks) == 1 {
+		return sinks[0], nil
+	}
+	return NewTee(sinks...), nil
+}
+
+func parse(spec string, stdout io.Writer, stderr io.Writer, httpClient *http.Client, format Format) (Sink, error) {
+	switch {
+	case spec == stdoutSpec:
+		return NewBufferedWriterSink(stdout, format), nil
+	case strings.HasPrefix(spec, fileSpecPrefix):
+		path := strings.TrimPrefix(spec, fileSpecPrefix)
+		if path == "" {
+			return nil, fmt.Errorf("Invalid sink %q: file path not specified", spec)
+		}
+		return NewFormattedFileSink(path, format)
+	case strings.HasPrefix(spec, "http://") || strings.HasPrefix(spec, "https://"):
+		return NewHTTPSink(spec, httpClient, stderr), nil
+	default:
+		return nil, fmt.Errorf("Invalid sink %q: expected stdout, file:PATH or an http(s) URL", spec)
+	}
+}
@@ -0,0 +1,134 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package sink_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/sink"
+)
+
+var _ = Describe("New", func() {
+	var (
+		specs  []string
+		format sink.Format
+		stdout *gbytes.Buffer
+		s      sink.Sink
+		err    error
+	)
+
+	BeforeEach(func() {
+		stdout = gbytes.NewBuffer()
+		format = nil
+	})
+
+	JustBeforeEach(func() {
+		s, err = sink.New(specs, stdout, nil, nil, format)
+	})
+
+	Context("when no sinks are specified", func() {
+		BeforeEach(func() {
+			specs = nil
+		})
+
+		It("should write to stdout", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(s.Write(logclient.LogRecord{Message: "hello"})).To(Succeed())
+			Expect(s.Flush()).To(Succeed())
+			Expect(stdout).To(gbytes.Say("hello"))
+		})
+
+		It("should write buffered records periodically", func() {
+			Expect(err).NotTo(HaveOccurred())
+			defer s.Close()
+			Expect(s.Write(logclient.LogRecord{Message: "hello"})).To(Succeed())
+			Eventually(stdout).Should(gbytes.Say("hello"))
+		})
+	})
+
+	Context("when a format is specified", func() {
+		BeforeEach(func() {
+			specs = nil
+			format = sink.JSONFormat
+		})
+
+		It("should render records with the format", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(s.Write(logclient.LogRecord{Message: "hello"})).To(Succeed())
+			Expect(s.Flush()).To(Succeed())
+			Expect(stdout).To(gbytes.Say(`"message":"hello"`))
+		})
+	})
+
+	Context("when several sinks are specified", func() {
+		var (
+			path      string
+			collector *httptest.Server
+			collected *gbytes.Buffer
+		)
+
+		BeforeEach(func() {
+			collected = gbytes.NewBuffer()
+			collector = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				io.Copy(collected, r.Body)
+			}))
+			path = filepath.Join(GinkgoT().TempDir(), "logs.txt")
+			specs = []string{"stdout", "file:" + path, collector.URL}
+		})
+
+		AfterEach(func() {
+			collector.Close()
+		})
+
+		It("should tee records to them", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(s.Write(logclient.LogRecord{Message: "hello"})).To(Succeed())
+			Expect(s.Close()).To(Succeed())
+			Expect(stdout).To(gbytes.Say("hello"))
+			Expect(os.ReadFile(path)).To(ContainSubstring("hello"))
+			Expect(collected).To(gbytes.Say(`"message":"hello"`))
+		})
+	})
+
+	Context("when a file sink has no path", func() {
+		BeforeEach(func() {
+			specs = []string{"file:"}
+		})
+
+		It("should return a suitable error", func() {
+			Expect(err).To(MatchError(`Invalid sink "file:": file path not specified`))
+		})
+	})
+
+	Context("when a sink is not recognised", func() {
+		BeforeEach(func() {
+			specs = []string{"stdout", "syslog"}
+		})
+
+		It("should return a suitable error", func() {
+			Expect(err).To(MatchError(`Invalid sink "syslog": expected stdout, file:PATH or an http(s) URL`))
+		})
+	})
+})
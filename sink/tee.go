@@ -0,0 +1,53 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package sink
+
+import "github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+
+type tee struct {
+	sinks []Sink
+}
+
+// NewTee returns a sink which passes each operation to all the given sinks in turn. An operation which fails on
+// one sink is still attempted on the remaining sinks and the first error is returned.
+func NewTee(sinks ...Sink) Sink {
+	return &tee{sinks: sinks}
+}
+
+func (t *tee) Write(record logclient.LogRecord) error {
+	return t.each(func(s Sink) error {
+		return s.Write(record)
+	})
+}
+
+func (t *tee) Flush() error {
+	return t.each(Sink.Flush)
+}
+
+func (t *tee) Close() error {
+	return t.each(Sink.Close)
+}
+
+func (t *tee) each(op func(Sink) error) error {
+	var firstErr error
+	for _, s := range t.sinks {
+		if err := op(s); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
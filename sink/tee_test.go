@@ -0,0 +1,66 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package sink_test
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/sink"
+)
+
+type failingSink struct {
+	err error
+}
+
+func (f *failingSink) Write(logclient.LogRecord) error { return f.err }
+func (f *failingSink) Flush() error                    { return f.err }
+func (f *failingSink) Close() error                    { return f.err }
+
+var _ = Describe("Tee", func() {
+	var (
+		first, second *gbytes.Buffer
+		record        logclient.LogRecord
+	)
+
+	BeforeEach(func() {
+		first = gbytes.NewBuffer()
+		second = gbytes.NewBuffer()
+		record = logclient.LogRecord{Message: "hello"}
+	})
+
+	It("should write each record to every sink", func() {
+		t := sink.NewTee(sink.NewWriterSink(first), sink.NewWriterSink(second))
+		Expect(t.Write(record)).To(Succeed())
+		Expect(first).To(gbytes.Say("hello"))
+		Expect(second).To(gbytes.Say("hello"))
+	})
+
+	Context("when a sink fails", func() {
+		It("should still write to the remaining sinks and return the error", func() {
+			testError := errors.New("no dice")
+			t := sink.NewTee(&failingSink{err: testError}, sink.NewWriterSink(second))
+			Expect(t.Write(record)).To(MatchError(testError))
+			Expect(second).To(gbytes.Say("hello"))
+			Expect(t.Flush()).To(MatchError(testError))
+			Expect(t.Close()).To(MatchError(testError))
+		})
+	})
+})
@@ -0,0 +1,31 @@
+package sink
+
+import (
+	"net/http"
+	"time"
+)
+
+// Allow httpSink batching and retry parameters to be modified, but only in tests (since the name of this file ends in "...test.go").
+func (hs *httpSink) SetBatchSize(size int) {
+	hs.batchSize = size
+}
+
+func (hs *httpSink) SetFlushInterval(interval time.Duration) {
+	hs.flushInterval = interval
+}
+
+func (hs *httpSink) SetRetryBackoff(backoff time.Duration) {
+	hs.retryBackoff = backoff
+}
+
+// Expose the client of an httpSink, but only in tests.
+func (hs *httpSink) Client() *http.Client {
+	return hs.client
+}
+
+type HTTPFieldSetter interface {
+	SetBatchSize(size int)
+	SetFlushInterval(interval time.Duration)
+	SetRetryBackoff(backoff time.Duration)
+	Client() *http.Client
+}
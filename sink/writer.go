@@ -0,0 +1,212 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package sink
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+)
+
+const (
+	writerBufferSize           = 64 * 1024
+	defaultWriterFlushInterval = 200 * time.Millisecond
+)
+
+type writerSink struct {
+	mutex  sync.Mutex
+	writer io.Writer
+	closer io.Closer
+	// format is nil when records are rendered as text, which is appended directly to the output buffer.
+	format Format
+	line   []byte
+
+	// buffered is nil when each line is written as soon as it is rendered.
+	buffered      *bufio.Writer
+	flushInterval time.Duration
+	flushErr      error
+
+	stop      chan struct{}
+	stopped   chan struct{}
+	startOnce sync.Once
+	closeOnce sync.Once
+}
+
+// NewWriterSink returns a sink which writes each record, formatted as a line of text, to the given writer.
+// Closing the sink does not close the writer.
+func NewWriterSink(w io.Writer) Sink {
+	return NewFormattedWriterSink(w, nil)
+}
+
+// NewFormattedWriterSink returns a sink which writes each record, rendered as a line by the given format, or as
+// text if the format is nil, to the given writer. Closing the sink does not close the writer.
+func NewFormattedWriterSink(w io.Writer, format Format) Sink {
+	return &writerSink{writer: w, format: format}
+}
+
+// NewBufferedWriterSink returns a sink which writes each record, rendered as a line by the given format, or as text
+// if the format is nil, to the given writer through a buffer. Buffered lines are written when the buffer is full,
+// when they have been pending for the flush interval, or when the sink is flushed or closed. If the writer is a
+// terminal, each line is written as soon as it is rendered, so that it is seen at once. Closing the sink does not
+// close the writer.
+func NewBufferedWriterSink(w io.Writer, format Format) Sink {
+	return newBufferedWriterSink(w, nil, format)
+}
+
+// NewFileSink returns a sink which appends each record, formatted as a line of text, to the file at the given
+// path, creating the file if necessary.
+func NewFileSink(path string) (Sink, error) {
+	return NewFormattedFileSink(path, nil)
+}
+
+// NewFormattedFileSink returns a sink which appends each record, rendered as a line by the given format, or as text
+// if the format is nil, to the file at the given path, creating the file if necessary. Lines are buffered as by
+// NewBufferedWriterSink.
+func NewFormattedFileSink(path string, format Format) (Sink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("Cannot open sink file: %s", err)
+	}
+	return newBufferedWriterSink(f, f, format), nil
+}
+
+func newBufferedWriterSink(w io.Writer, closer io.Closer, format Format) *writerSink {
+	ws := &writerSink{writer: w, closer: closer, format: format}
+	if !isTerminal(w) {
+		ws.buffered = bufio.NewWriterSize(w, writerBufferSize)
+		ws.flushInterval = defaultWriterFlushInterval
+		ws.stop = make(chan struct{})
+		ws.stopped = make(chan struct{})
+	}
+	return ws
+}
+
+// isTerminal determines whether the given writer is a terminal, whose user expects to see each line at once.
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+func (ws *writerSink) Write(record logclient.LogRecord) error {
+	var line string
+	if ws.format != nil {
+		var err error
+		line, err = ws.format(record)
+		if err != nil {
+			return err
+		}
+	}
+	if ws.buffered != nil {
+		ws.startOnce.Do(ws.startPeriodicFlush)
+	}
+
+	ws.mutex.Lock()
+	defer ws.mutex.Unlock()
+
+	if ws.buffered == nil {
+		ws.line = ws.appendLine(ws.line[:0], record, line)
+		_, err := ws.writer.Write(ws.line)
+		return err
+	}
+
+	// Report a failure from a periodic flush at the first opportunity.
+	if err := ws.flushErr; err != nil {
+		ws.flushErr = nil
+		return err
+	}
+	// Render text into the free space of the buffer, so that it is only copied if it does not fit.
+	if ws.format == nil {
+		_, err := ws.buffered.Write(ws.appendLine(ws.buffered.AvailableBuffer(), record, line))
+		return err
+	}
+	ws.line = ws.appendLine(ws.line[:0], record, line)
+	_, err := ws.buffered.Write(ws.line)
+	return err
+}
+
+// appendLine appends the given record, or the given rendering of it if the sink has a format, and a newline.
+func (ws *writerSink) appendLine(dst []byte, record logclient.LogRecord, line string) []byte {
+	if ws.format == nil {
+		dst = record.AppendText(dst)
+	} else {
+		dst = append(dst, line...)
+	}
+	return append(dst, '\n')
+}
+
+func (ws *writerSink) Flush() error {
+	ws.mutex.Lock()
+	defer ws.mutex.Unlock()
+
+	if ws.buffered == nil {
+		return nil
+	}
+	err := ws.buffered.Flush()
+	if err == nil {
+		err = ws.flushErr
+	}
+	ws.flushErr = nil
+	return err
+}
+
+func (ws *writerSink) Close() error {
+	if ws.buffered != nil {
+		ws.closeOnce.Do(func() {
+			close(ws.stop)
+			ws.startOnce.Do(func() {
+				close(ws.stopped)
+			})
+			<-ws.stopped
+		})
+	}
+	err := ws.Flush()
+	if ws.closer != nil {
+		if closeErr := ws.closer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+func (ws *writerSink) startPeriodicFlush() {
+	go func() {
+		defer close(ws.stopped)
+		ticker := time.NewTicker(ws.flushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				ws.mutex.Lock()
+				if err := ws.buffered.Flush(); err != nil && ws.flushErr == nil {
+					ws.flushErr = err
+				}
+				ws.mutex.Unlock()
+			case <-ws.stop:
+				return
+			}
+		}
+	}()
+}
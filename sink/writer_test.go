@@ -0,0 +1,156 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package sink_test
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/onsi/gomega/gbytes"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/sink"
+)
+
+var _ = Describe("WriterSink", func() {
+	var record logclient.LogRecord
+
+	BeforeEach(func() {
+		record = logclient.LogRecord{
+			Timestamp:      time.Now(),
+			SourceType:     "ST",
+			SourceInstance: "SI",
+			MessageType:    "OUT",
+			Message:        "hello",
+		}
+	})
+
+	It("should write each record as a line", func() {
+		output := &bytes.Buffer{}
+		s := sink.NewWriterSink(output)
+		Expect(s.Write(record)).To(Succeed())
+		Expect(s.Flush()).To(Succeed())
+		Expect(output.String()).To(Equal(record.String() + "\n"))
+	})
+
+	It("should render records with a format", func() {
+		output := &bytes.Buffer{}
+		s := sink.NewFormattedWriterSink(output, func(record logclient.LogRecord) (string, error) {
+			return "<" + record.Message + ">", nil
+		})
+		Expect(s.Write(record)).To(Succeed())
+		Expect(output.String()).To(Equal("<hello>\n"))
+	})
+
+	It("should not close the underlying writer", func() {
+		output := gbytes.NewBuffer()
+		s := sink.NewWriterSink(output)
+		Expect(s.Close()).To(Succeed())
+		Expect(output.Closed()).To(BeFalse())
+	})
+
+	Describe("BufferedWriterSink", func() {
+		var (
+			output *gbytes.Buffer
+			s      sink.Sink
+		)
+
+		BeforeEach(func() {
+			output = gbytes.NewBuffer()
+			s = sink.NewBufferedWriterSink(output, nil)
+		})
+
+		AfterEach(func() {
+			s.Close()
+		})
+
+		It("should hold records until flushed", func() {
+			Expect(s.Write(record)).To(Succeed())
+			Expect(output.Contents()).To(BeEmpty())
+			Expect(s.Flush()).To(Succeed())
+			Expect(string(output.Contents())).To(Equal(record.String() + "\n"))
+		})
+
+		It("should write held records periodically", func() {
+			Expect(s.Write(record)).To(Succeed())
+			Eventually(output).Should(gbytes.Say("hello\n"))
+		})
+
+		It("should write held records when closed, without closing the underlying writer", func() {
+			Expect(s.Write(record)).To(Succeed())
+			Expect(s.Close()).To(Succeed())
+			Expect(string(output.Contents())).To(Equal(record.String() + "\n"))
+			Expect(output.Closed()).To(BeFalse())
+		})
+
+		It("should render lines longer than the buffer", func() {
+			record.Message = strings.Repeat("x", 100000)
+			Expect(s.Write(record)).To(Succeed())
+			Expect(s.Flush()).To(Succeed())
+			Expect(string(output.Contents())).To(Equal(record.String() + "\n"))
+		})
+
+		Context("when writing fails", func() {
+			BeforeEach(func() {
+				s = sink.NewBufferedWriterSink(failingWriter{}, nil)
+			})
+
+			It("should report the failure when flushed", func() {
+				Expect(s.Write(record)).To(Succeed())
+				Expect(s.Flush()).To(MatchError("disk full"))
+			})
+		})
+	})
+
+	Describe("FileSink", func() {
+		var path string
+
+		BeforeEach(func() {
+			path = filepath.Join(GinkgoT().TempDir(), "logs.txt")
+			Expect(os.WriteFile(path, []byte("existing\n"), 0644)).To(Succeed())
+		})
+
+		It("should append records to the file", func() {
+			s, err := sink.NewFileSink(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(s.Write(record)).To(Succeed())
+			Expect(s.Close()).To(Succeed())
+
+			contents, err := os.ReadFile(path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(contents)).To(Equal("existing\n" + record.String() + "\n"))
+		})
+
+		Context("when the file cannot be opened", func() {
+			It("should return a suitable error", func() {
+				_, err := sink.NewFileSink(filepath.Join(path, "not-a-directory"))
+				Expect(err).To(MatchError(HavePrefix("Cannot open sink file: ")))
+			})
+		})
+	})
+})
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
@@ -0,0 +1,118 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package main
+
+import (
+	"context"
+	"os"
+	"strings"
+
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/failure"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/standalone"
+)
+
+const (
+	apiFlag               = "--api"
+	clientIDFlag          = "--client-id"
+	clientSecretFlag      = "--client-secret"
+	skipSslValidationFlag = "--skip-ssl-validation"
+	versionFlag           = "--version"
+)
+
+// standaloneCommand returns the name of the plugin command with the given name or alias, if there is one.
+func standaloneCommand(name string) (string, bool) {
+	switch name {
+	case serivceLogsCommand, serviceLogsAlias:
+		return serivceLogsCommand, true
+	case serviceLogsStatsCommand, serviceLogsDoctorCommand, serviceLogsInstancesCommand:
+		return name, true
+	}
+	return "", false
+}
+
+// runStandalone runs a plugin command without the cf CLI, accessing the Cloud Controller and UAA directly. The
+// target and tokens are taken from the cf CLI's configuration file, unless --api or CF_API gives another API
+// endpoint, in which case the user is logged in with CF_USERNAME and CF_PASSWORD. A UAA client ID and secret given
+// by --client-id and --client-secret, or CF_CLIENT_ID and CF_CLIENT_SECRET, authenticate as that client instead.
+func (c *Plugin) runStandalone(command string, args []string) {
+	args, flags := extractStandaloneFlags(args)
+	if command == serivceLogsCommand && containsArg(args, versionFlag) {
+		printVersion()
+		return
+	}
+	failed := func(err error) {
+		fail(failure.Classify(err), os.Stderr, outputJSON(args))
+	}
+
+	path, err := standalone.CFConfigPath()
+	if err != nil {
+		failed(err)
+	}
+	cfConfig, err := standalone.LoadCFConfig(path)
+	if err != nil {
+		failed(err)
+	}
+	settings, err := standalone.NewSettings(cfConfig, flags, os.Getenv)
+	if err != nil {
+		failed(err)
+	}
+	if containsArg(args, skipSslValidationFlag) {
+		settings.SkipSSLValidation = true
+	}
+
+	conn, err := standalone.NewConnection(context.Background(), settings)
+	if err != nil {
+		failed(err)
+	}
+	c.Run(conn, append([]string{command}, args...))
+}
+
+// extractStandaloneFlags removes the flags which are only valid in standalone mode from the given arguments and
+// returns their values.
+func extractStandaloneFlags(args []string) ([]string, standalone.Flags) {
+	var flags standalone.Flags
+	values := map[string]*string{
+		apiFlag:          &flags.API,
+		clientIDFlag:     &flags.ClientID,
+		clientSecretFlag: &flags.ClientSecret,
+	}
+
+	remaining := []string{}
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		target, ok := values[name]
+		switch {
+		case ok && hasValue:
+			*target = value
+		case ok && i+1 < len(args):
+			*target = args[i+1]
+			i++
+		default:
+			remaining = append(remaining, args[i])
+		}
+	}
+	return remaining, flags
+}
+
+func containsArg(args []string, arg string) bool {
+	for _, a := range args {
+		if a == arg {
+			return true
+		}
+	}
+	return false
+}
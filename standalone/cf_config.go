@@ -0,0 +1,79 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+// Package standalone runs the plugin's commands without the cf CLI, using the cf CLI's configuration file or
+// settings given by flags and environment variables to access the Cloud Controller and UAA directly.
+package standalone
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// CFConfig holds the parts of the cf CLI's configuration file used to access the targeted Cloud Controller.
+type CFConfig struct {
+	Target               string
+	UaaEndpoint          string
+	AccessToken          string
+	RefreshToken         string
+	UAAOAuthClient       string
+	UAAOAuthClientSecret string
+	SSLDisabled          bool
+	OrganizationFields   TargetFields
+	SpaceFields          TargetFields
+}
+
+// TargetFields identifies the targeted org or space.
+type TargetFields struct {
+	GUID string
+	Name string
+}
+
+// CFConfigPath returns the path of the cf CLI's configuration file, which is in the .cf directory of $CF_HOME or,
+// by default, of the user's home directory.
+func CFConfigPath() (string, error) {
+	home := os.Getenv("CF_HOME")
+	if home == "" {
+		var err error
+		home, err = os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+	}
+	return filepath.Join(home, ".cf", "config.json"), nil
+}
+
+// LoadCFConfig reads the cf CLI's configuration file at the given path. A missing file denotes an empty
+// configuration.
+func LoadCFConfig(path string) (CFConfig, error) {
+	contents, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return CFConfig{}, nil
+	}
+	if err != nil {
+		return CFConfig{}, fmt.Errorf("Cannot read cf configuration file: %s", err)
+	}
+
+	var c CFConfig
+	if err := json.Unmarshal(contents, &c); err != nil {
+		return CFConfig{}, fmt.Errorf("cf configuration file %s contained invalid JSON: %s", path, err)
+	}
+	return c, nil
+}
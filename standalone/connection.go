@@ -0,0 +1,271 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package standalone
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"code.cloudfoundry.org/cli/plugin"
+	"code.cloudfoundry.org/cli/plugin/models"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/cfutil"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/servicelogs"
+)
+
+// errUnsupported is returned by the parts of the cf CLI plugin connection which the plugin does not use.
+var errUnsupported = errors.New("Not supported in standalone mode")
+
+// Connection provides the cf CLI plugin connection, backed by direct requests to the Cloud Controller and UAA, so
+// that the plugin's commands can run without the cf CLI. Only the operations used by the plugin are supported.
+type Connection struct {
+	settings Settings
+	cc       servicelogs.CloudController
+	tokens   servicelogs.TokenSource
+}
+
+// NewConnection returns a connection using the given settings. The UAA endpoint is discovered from the Cloud
+// Controller if the settings do not give it.
+func NewConnection(ctx context.Context, settings Settings) (*Connection, error) {
+	httpClient := &http.Client{Transport: &http.Transport{
+		Proxy:           http.ProxyFromEnvironment,
+		TLSClientConfig: &tls.Config{InsecureSkipVerify: settings.SkipSSLValidation},
+	}}
+
+	if settings.UAAEndpoint == "" {
+		var err error
+		settings.UAAEndpoint, err = discoverUAA(ctx, httpClient, settings.API)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tokens := NewUAA(settings.UAAEndpoint, settings.UAAClient, settings.UAAClientSecret, httpClient).
+		Tokens(settings.AccessToken, settings.RefreshToken).
+		Password(settings.Username, settings.Password)
+	if settings.ClientCredentials {
+		tokens.ClientCredentials()
+	}
+	return &Connection{
+		settings: settings,
+		cc:       servicelogs.NewHTTPCloudController(settings.API, httpClient, tokens),
+		tokens:   tokens,
+	}, nil
+}
+
+// discoverUAA returns the UAA endpoint advertised by the root of the Cloud Controller API.
+func discoverUAA(ctx context.Context, httpClient *http.Client, api string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, api+"/", nil)
+	if err != nil {
+		return "", fmt.Errorf("Invalid API endpoint %s: %s", api, err)
+	}
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("Cannot reach API endpoint %s: %s", api, err)
+	}
+	defer resp.Body.Close()
+
+	var root struct {
+		Links struct {
+			UAA *struct {
+				Href string `json:"href"`
+			} `json:"uaa"`
+		} `json:"links"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&root); err != nil {
+		return "", fmt.Errorf("API endpoint %s returned invalid JSON: %s", api, err)
+	}
+	if root.Links.UAA == nil || root.Links.UAA.Href == "" {
+		return "", fmt.Errorf("API endpoint %s did not advertise a UAA endpoint", api)
+	}
+	return root.Links.UAA.Href, nil
+}
+
+// CliCommandWithoutTerminalOutput supports only 'cf curl' for GET requests.
+func (c *Connection) CliCommandWithoutTerminalOutput(args ...string) ([]string, error) {
+	if len(args) != 2 || args[0] != "curl" {
+		return nil, fmt.Errorf("cf %s: %s", strings.Join(args, " "), errUnsupported)
+	}
+	body, err := c.cc.Get(context.Background(), args[1])
+	if err != nil {
+		return nil, err
+	}
+	return []string{string(body)}, nil
+}
+
+func (c *Connection) CliCommand(args ...string) ([]string, error) {
+	return nil, fmt.Errorf("cf %s: %s", strings.Join(args, " "), errUnsupported)
+}
+
+func (c *Connection) GetCurrentOrg() (plugin_models.Organization, error) {
+	return plugin_models.Organization{OrganizationFields: plugin_models.OrganizationFields{Guid: c.settings.Org.GUID, Name: c.settings.Org.Name}}, nil
+}
+
+func (c *Connection) GetCurrentSpace() (plugin_models.Space, error) {
+	return plugin_models.Space{SpaceFields: plugin_models.SpaceFields{Guid: c.settings.Space.GUID, Name: c.settings.Space.Name}}, nil
+}
+
+// Username returns the name of the user or, when authenticated as a UAA client, the client ID.
+func (c *Connection) Username() (string, error) {
+	if c.settings.ClientCredentials {
+		return c.claim("client_id")
+	}
+	return c.claim("user_name")
+}
+
+func (c *Connection) UserGuid() (string, error) {
+	return c.claim("user_id")
+}
+
+func (c *Connection) UserEmail() (string, error) {
+	return c.claim("email")
+}
+
+// claim returns the given claim of the current access token.
+func (c *Connection) claim(name string) (string, error) {
+	token, err := c.tokens.AccessToken(context.Background())
+	if err != nil {
+		return "", err
+	}
+	return tokenClaim(token, name), nil
+}
+
+func (c *Connection) IsLoggedIn() (bool, error) {
+	_, err := c.tokens.AccessToken(context.Background())
+	return err == nil, nil
+}
+
+func (c *Connection) IsSSLDisabled() (bool, error) {
+	return c.settings.SkipSSLValidation, nil
+}
+
+func (c *Connection) HasOrganization() (bool, error) {
+	return c.settings.Org.GUID != "", nil
+}
+
+func (c *Connection) HasSpace() (bool, error) {
+	return c.settings.Space.GUID != "", nil
+}
+
+func (c *Connection) ApiEndpoint() (string, error) {
+	return c.settings.API, nil
+}
+
+func (c *Connection) ApiVersion() (string, error) {
+	return "", errUnsupported
+}
+
+func (c *Connection) HasAPIEndpoint() (bool, error) {
+	return c.settings.API != "", nil
+}
+
+func (c *Connection) LoggregatorEndpoint() (string, error) {
+	return "", errUnsupported
+}
+
+func (c *Connection) DopplerEndpoint() (string, error) {
+	return "", errUnsupported
+}
+
+// AccessToken returns the current access token with a "bearer " prefix, as the cf CLI does.
+func (c *Connection) AccessToken() (string, error) {
+	token, err := c.tokens.AccessToken(context.Background())
+	if err != nil {
+		return "", err
+	}
+	return "bearer " + token, nil
+}
+
+// GetService looks up the named service instance in the targeted space. Only the fields used by the plugin are
+// set.
+func (c *Connection) GetService(name string) (plugin_models.GetService_Model, error) {
+	if c.settings.Space.GUID == "" {
+		return plugin_models.GetService_Model{}, errors.New("No space targeted. Use -o ORG -s SPACE or 'cf target -o ORG -s SPACE'.")
+	}
+
+	query := url.Values{}
+	query.Set("names", name)
+	query.Set("space_guids", c.settings.Space.GUID)
+	var page struct {
+		Resources []struct {
+			Guid          string `json:"guid"`
+			Relationships struct {
+				ServicePlan struct {
+					Data struct {
+						Guid string `json:"guid"`
+					} `json:"data"`
+				} `json:"service_plan"`
+			} `json:"relationships"`
+		} `json:"resources"`
+	}
+	if err := cfutil.Curl(c, "/v3/service_instances?"+query.Encode(), &page); err != nil {
+		return plugin_models.GetService_Model{}, err
+	}
+	if len(page.Resources) == 0 {
+		return plugin_models.GetService_Model{}, &cfutil.NotFoundError{Message: fmt.Sprintf("Service instance %s not found", name)}
+	}
+
+	resource := page.Resources[0]
+	return plugin_models.GetService_Model{
+		Guid:        resource.Guid,
+		Name:        name,
+		ServicePlan: plugin_models.GetService_ServicePlan{Guid: resource.Relationships.ServicePlan.Data.Guid},
+	}, nil
+}
+
+func (c *Connection) GetApp(string) (plugin_models.GetAppModel, error) {
+	return plugin_models.GetAppModel{}, errUnsupported
+}
+
+func (c *Connection) GetApps() ([]plugin_models.GetAppsModel, error) {
+	return nil, errUnsupported
+}
+
+func (c *Connection) GetOrgs() ([]plugin_models.GetOrgs_Model, error) {
+	return nil, errUnsupported
+}
+
+func (c *Connection) GetSpaces() ([]plugin_models.GetSpaces_Model, error) {
+	return nil, errUnsupported
+}
+
+func (c *Connection) GetOrgUsers(string, ...string) ([]plugin_models.GetOrgUsers_Model, error) {
+	return nil, errUnsupported
+}
+
+func (c *Connection) GetSpaceUsers(string, string) ([]plugin_models.GetSpaceUsers_Model, error) {
+	return nil, errUnsupported
+}
+
+func (c *Connection) GetServices() ([]plugin_models.GetServices_Model, error) {
+	return nil, errUnsupported
+}
+
+func (c *Connection) GetOrg(string) (plugin_models.GetOrg_Model, error) {
+	return plugin_models.GetOrg_Model{}, errUnsupported
+}
+
+func (c *Connection) GetSpace(string) (plugin_models.GetSpace_Model, error) {
+	return plugin_models.GetSpace_Model{}, errUnsupported
+}
+
+var _ plugin.CliConnection = &Connection{}
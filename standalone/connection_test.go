@@ -0,0 +1,121 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package standalone_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/standalone"
+)
+
+var _ = Describe("Connection", func() {
+	var (
+		server   *httptest.Server
+		token    string
+		settings standalone.Settings
+		conn     *standalone.Connection
+		err      error
+	)
+
+	BeforeEach(func() {
+		token = jwt(`{"user_name": "admin", "user_id": "user-guid", "exp": 4000000000}`)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/":
+				w.Write([]byte(`{"links": {"uaa": {"href": "` + server.URL + `/uaa"}}}`))
+			case "/uaa/oauth/token":
+				w.Write([]byte(`{"access_token": "` + token + `"}`))
+			case "/v3/service_instances":
+				Expect(r.Header.Get("Authorization")).To(Equal("bearer " + token))
+				Expect(r.URL.Query().Get("names")).To(Equal("db"))
+				Expect(r.URL.Query().Get("space_guids")).To(Equal("space-guid"))
+				w.Write([]byte(`{"resources": [{"guid": "db-guid", "relationships": {"service_plan": {"data": {"guid": "plan-guid"}}}}]}`))
+			default:
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"code": 10000, "description": "Unknown request", "error_code": "CF-NotFound"}`))
+			}
+		}))
+		settings = standalone.Settings{
+			API:      server.URL,
+			Username: "admin",
+			Password: "secret",
+			Space:    standalone.TargetFields{GUID: "space-guid", Name: "space"},
+		}
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	JustBeforeEach(func() {
+		conn, err = standalone.NewConnection(context.Background(), settings)
+	})
+
+	It("should log in through the UAA advertised by the Cloud Controller", func() {
+		Expect(err).NotTo(HaveOccurred())
+		accessToken, err := conn.AccessToken()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(accessToken).To(Equal("bearer " + token))
+		Expect(conn.Username()).To(Equal("admin"))
+	})
+
+	It("should look up a service instance in the targeted space", func() {
+		Expect(err).NotTo(HaveOccurred())
+		model, err := conn.GetService("db")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(model.Guid).To(Equal("db-guid"))
+		Expect(model.ServicePlan.Guid).To(Equal("plan-guid"))
+	})
+
+	It("should issue Cloud Controller requests for cf curl", func() {
+		Expect(err).NotTo(HaveOccurred())
+		output, err := conn.CliCommandWithoutTerminalOutput("curl", "/v2/unknown")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(output).To(ConsistOf(ContainSubstring("CF-NotFound")))
+	})
+
+	It("should not support other cf commands", func() {
+		Expect(err).NotTo(HaveOccurred())
+		_, err := conn.CliCommandWithoutTerminalOutput("target", "-s", "space")
+		Expect(err).To(MatchError("cf target -s space: Not supported in standalone mode"))
+	})
+
+	Context("when no space is targeted", func() {
+		BeforeEach(func() {
+			settings.Space = standalone.TargetFields{}
+		})
+
+		It("should not look up service instances by name", func() {
+			_, err := conn.GetService("db")
+			Expect(err).To(MatchError("No space targeted. Use -o ORG -s SPACE or 'cf target -o ORG -s SPACE'."))
+		})
+	})
+
+	Context("when the Cloud Controller does not advertise a UAA", func() {
+		BeforeEach(func() {
+			settings.API = server.URL + "/v2/unknown"
+		})
+
+		It("should return a suitable error", func() {
+			Expect(err).To(MatchError("API endpoint " + server.URL + "/v2/unknown did not advertise a UAA endpoint"))
+		})
+	})
+})
@@ -0,0 +1,115 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package standalone
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Environment variables which configure standalone mode. They are those understood by the cf CLI, where there is
+// an equivalent.
+const (
+	APIEnv               = "CF_API"
+	UsernameEnv          = "CF_USERNAME"
+	PasswordEnv          = "CF_PASSWORD"
+	SkipSSLValidationEnv = "CF_SKIP_SSL_VALIDATION"
+	ClientIDEnv          = "CF_CLIENT_ID"
+	ClientSecretEnv      = "CF_CLIENT_SECRET"
+)
+
+// Flags are the settings given on the command line in standalone mode. They take precedence over the corresponding
+// environment variables.
+type Flags struct {
+	API          string
+	ClientID     string
+	ClientSecret string
+}
+
+// Settings determine how the Cloud Controller and UAA are accessed without the cf CLI.
+type Settings struct {
+	API               string
+	SkipSSLValidation bool
+	Org               TargetFields
+	Space             TargetFields
+
+	// UAAEndpoint is discovered from the Cloud Controller if it is empty.
+	UAAEndpoint     string
+	UAAClient       string
+	UAAClientSecret string
+	AccessToken     string
+	RefreshToken    string
+	Username        string
+	Password        string
+
+	// ClientCredentials authenticates as the UAA client rather than as a user.
+	ClientCredentials bool
+}
+
+// NewSettings combines the given cf CLI configuration with the given flags and with environment variables, as
+// obtained by getenv. An API endpoint given by a flag or environment variable overrides the configuration's target.
+// The configuration's tokens and targeted org and space are only used if the API endpoint is the configuration's
+// target. The configuration's tokens are not used if a UAA client ID is given, in which case the client's
+// credentials are used instead.
+func NewSettings(cfConfig CFConfig, flags Flags, getenv func(string) string) (Settings, error) {
+	api := firstNonEmpty(flags.API, getenv(APIEnv), cfConfig.Target)
+	if api == "" {
+		return Settings{}, errors.New("No API endpoint set. Use 'cf api', --api or " + APIEnv + ".")
+	}
+
+	settings := Settings{
+		API:      strings.TrimSuffix(api, "/"),
+		Username: getenv(UsernameEnv),
+		Password: getenv(PasswordEnv),
+	}
+	if settings.API == strings.TrimSuffix(cfConfig.Target, "/") {
+		settings.SkipSSLValidation = cfConfig.SSLDisabled
+		settings.Org = cfConfig.OrganizationFields
+		settings.Space = cfConfig.SpaceFields
+		settings.UAAEndpoint = cfConfig.UaaEndpoint
+		settings.UAAClient = cfConfig.UAAOAuthClient
+		settings.UAAClientSecret = cfConfig.UAAOAuthClientSecret
+		settings.AccessToken = cfConfig.AccessToken
+		settings.RefreshToken = cfConfig.RefreshToken
+	}
+	if skip, err := strconv.ParseBool(getenv(SkipSSLValidationEnv)); err == nil && skip {
+		settings.SkipSSLValidation = true
+	}
+
+	if clientID := firstNonEmpty(flags.ClientID, getenv(ClientIDEnv)); clientID != "" {
+		settings.ClientCredentials = true
+		settings.UAAClient = clientID
+		settings.UAAClientSecret = firstNonEmpty(flags.ClientSecret, getenv(ClientSecretEnv))
+		settings.AccessToken = ""
+		settings.RefreshToken = ""
+		settings.Username = ""
+		settings.Password = ""
+	} else if flags.ClientSecret != "" || getenv(ClientSecretEnv) != "" {
+		return Settings{}, errors.New("A UAA client secret was given without a client ID. Use --client-id or " + ClientIDEnv + ".")
+	}
+	return settings, nil
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if value != "" {
+			return value
+		}
+	}
+	return ""
+}
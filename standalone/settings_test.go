@@ -0,0 +1,171 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package standalone_test
+
+import (
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/standalone"
+)
+
+var _ = Describe("Settings", func() {
+	var (
+		cfConfig standalone.CFConfig
+		flags    standalone.Flags
+		env      map[string]string
+		settings standalone.Settings
+		err      error
+	)
+
+	BeforeEach(func() {
+		cfConfig = standalone.CFConfig{
+			Target:             "https://api.example.com",
+			UaaEndpoint:        "https://uaa.example.com",
+			AccessToken:        "bearer access-token",
+			RefreshToken:       "refresh-token",
+			OrganizationFields: standalone.TargetFields{GUID: "org-guid", Name: "org"},
+			SpaceFields:        standalone.TargetFields{GUID: "space-guid", Name: "space"},
+		}
+		flags = standalone.Flags{}
+		env = map[string]string{}
+	})
+
+	JustBeforeEach(func() {
+		settings, err = standalone.NewSettings(cfConfig, flags, func(name string) string {
+			return env[name]
+		})
+	})
+
+	It("should use the cf CLI's target and tokens", func() {
+		Expect(err).NotTo(HaveOccurred())
+		Expect(settings.API).To(Equal("https://api.example.com"))
+		Expect(settings.UAAEndpoint).To(Equal("https://uaa.example.com"))
+		Expect(settings.AccessToken).To(Equal("bearer access-token"))
+		Expect(settings.RefreshToken).To(Equal("refresh-token"))
+		Expect(settings.Space).To(Equal(standalone.TargetFields{GUID: "space-guid", Name: "space"}))
+	})
+
+	Context("when another API endpoint is given", func() {
+		BeforeEach(func() {
+			env[standalone.APIEnv] = "https://api.other.example.com/"
+			env[standalone.UsernameEnv] = "admin"
+			env[standalone.PasswordEnv] = "secret"
+		})
+
+		It("should not use the cf CLI's tokens or target", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(settings).To(Equal(standalone.Settings{API: "https://api.other.example.com", Username: "admin", Password: "secret"}))
+		})
+
+		Context("when the --api flag is also given", func() {
+			BeforeEach(func() {
+				flags.API = "https://api.example.com"
+			})
+
+			It("should prefer the flag", func() {
+				Expect(settings.API).To(Equal("https://api.example.com"))
+				Expect(settings.AccessToken).To(Equal("bearer access-token"))
+			})
+		})
+	})
+
+	Context("when a UAA client ID and secret are given", func() {
+		BeforeEach(func() {
+			env[standalone.ClientIDEnv] = "ci-client"
+			env[standalone.ClientSecretEnv] = "env-secret"
+			env[standalone.UsernameEnv] = "admin"
+			flags.ClientSecret = "flag-secret"
+		})
+
+		It("should authenticate as the client rather than with the cf CLI's tokens", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(settings.ClientCredentials).To(BeTrue())
+			Expect(settings.UAAClient).To(Equal("ci-client"))
+			Expect(settings.UAAClientSecret).To(Equal("flag-secret"))
+			Expect(settings.AccessToken).To(BeEmpty())
+			Expect(settings.RefreshToken).To(BeEmpty())
+			Expect(settings.Username).To(BeEmpty())
+			Expect(settings.Space).To(Equal(standalone.TargetFields{GUID: "space-guid", Name: "space"}))
+		})
+	})
+
+	Context("when a UAA client secret is given without a client ID", func() {
+		BeforeEach(func() {
+			env[standalone.ClientSecretEnv] = "secret"
+		})
+
+		It("should return a suitable error", func() {
+			Expect(err).To(MatchError("A UAA client secret was given without a client ID. Use --client-id or CF_CLIENT_ID."))
+		})
+	})
+
+	Context("when SSL validation is disabled by the environment", func() {
+		BeforeEach(func() {
+			env[standalone.SkipSSLValidationEnv] = "true"
+		})
+
+		It("should skip SSL validation", func() {
+			Expect(settings.SkipSSLValidation).To(BeTrue())
+		})
+	})
+
+	Context("when there is no API endpoint", func() {
+		BeforeEach(func() {
+			cfConfig = standalone.CFConfig{}
+		})
+
+		It("should return a suitable error", func() {
+			Expect(err).To(MatchError("No API endpoint set. Use 'cf api', --api or CF_API."))
+		})
+	})
+})
+
+var _ = Describe("CFConfig", func() {
+	var home string
+
+	BeforeEach(func() {
+		home = GinkgoT().TempDir()
+		GinkgoT().Setenv("CF_HOME", home)
+	})
+
+	It("should read the cf CLI's configuration file in CF_HOME", func() {
+		Expect(os.MkdirAll(filepath.Join(home, ".cf"), 0755)).To(Succeed())
+		Expect(os.WriteFile(filepath.Join(home, ".cf", "config.json"), []byte(`{"Target": "https://api.example.com", "SpaceFields": {"GUID": "space-guid", "Name": "space"}}`), 0600)).To(Succeed())
+		path, err := standalone.CFConfigPath()
+		Expect(err).NotTo(HaveOccurred())
+		cfConfig, err := standalone.LoadCFConfig(path)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfConfig.Target).To(Equal("https://api.example.com"))
+		Expect(cfConfig.SpaceFields.GUID).To(Equal("space-guid"))
+	})
+
+	It("should treat a missing file as an empty configuration", func() {
+		cfConfig, err := standalone.LoadCFConfig(filepath.Join(home, "missing.json"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cfConfig).To(Equal(standalone.CFConfig{}))
+	})
+
+	It("should reject invalid JSON", func() {
+		path := filepath.Join(home, "config.json")
+		Expect(os.WriteFile(path, []byte(`{`), 0600)).To(Succeed())
+		_, err := standalone.LoadCFConfig(path)
+		Expect(err).To(MatchError(HavePrefix("cf configuration file " + path + " contained invalid JSON: ")))
+	})
+})
@@ -0,0 +1,12 @@
+package standalone
+
+import "time"
+
+// Allow the UAA token source's clock to be replaced, but only in tests (since the name of this file ends in "...test.go").
+func (u *UAA) SetClock(now func() time.Time) {
+	u.now = now
+}
+
+type ClockSetter interface {
+	SetClock(now func() time.Time)
+}
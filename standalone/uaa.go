@@ -0,0 +1,220 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package standalone
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultUAAClient is the UAA client used by the cf CLI, which has an empty secret.
+	DefaultUAAClient = "cf"
+
+	// expiryMargin is how long before its expiry an access token is refreshed, so that it does not expire in use.
+	expiryMargin = time.Minute
+)
+
+// errNotLoggedIn is returned when there is neither a usable access token nor a way of obtaining one.
+var errNotLoggedIn = errors.New("Not logged in. Use 'cf login', set CF_USERNAME and CF_PASSWORD, or set CF_CLIENT_ID and CF_CLIENT_SECRET.")
+
+// UAA supplies access tokens obtained from a UAA server. An access token is refreshed with the refresh token, if
+// there is one, before it expires. Otherwise a new access token is obtained with the client's credentials or the
+// user's credentials, if they are known.
+type UAA struct {
+	tokenEndpoint     string
+	client            string
+	clientSecret      string
+	httpClient        *http.Client
+	clientCredentials bool
+	username          string
+	password          string
+	now               func() time.Time
+
+	mutex        sync.Mutex
+	accessToken  string
+	refreshToken string
+	expiry       time.Time
+}
+
+// NewUAA returns a token source for the UAA server at the given URL which authenticates as the given UAA client,
+// or as the cf CLI's client if none is given, using the given HTTP client. If the HTTP client is nil,
+// http.DefaultClient is used.
+func NewUAA(uaaEndpoint string, client string, clientSecret string, httpClient *http.Client) *UAA {
+	if client == "" {
+		client = DefaultUAAClient
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &UAA{
+		tokenEndpoint: strings.TrimSuffix(uaaEndpoint, "/") + "/oauth/token",
+		client:        client,
+		clientSecret:  clientSecret,
+		httpClient:    httpClient,
+		now:           time.Now,
+	}
+}
+
+// Tokens sets the access and refresh tokens obtained when the user logged in. Either may be empty and the access
+// token may have a "bearer " prefix, as in the cf CLI's configuration file.
+func (u *UAA) Tokens(accessToken string, refreshToken string) *UAA {
+	u.accessToken = strings.TrimPrefix(strings.TrimPrefix(accessToken, "bearer "), "Bearer ")
+	u.refreshToken = refreshToken
+	u.expiry = tokenExpiry(u.accessToken)
+	return u
+}
+
+// Password sets the user's credentials, which are used to obtain an access token if there is no usable one.
+func (u *UAA) Password(username string, password string) *UAA {
+	u.username = username
+	u.password = password
+	return u
+}
+
+// ClientCredentials obtains access tokens with the client credentials grant, which authenticates as the UAA client
+// itself rather than as a user, as CI service accounts do. Such tokens cannot be refreshed, so a new token is
+// obtained whenever the current one is about to expire.
+func (u *UAA) ClientCredentials() *UAA {
+	u.clientCredentials = true
+	return u
+}
+
+// AccessToken returns a current access token, obtaining a new one if necessary.
+func (u *UAA) AccessToken(ctx context.Context) (string, error) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	if u.accessToken != "" && (u.expiry.IsZero() || u.now().Add(expiryMargin).Before(u.expiry)) {
+		return u.accessToken, nil
+	}
+
+	var err error
+	if u.refreshToken != "" {
+		if err = u.requestToken(ctx, url.Values{"grant_type": {"refresh_token"}, "refresh_token": {u.refreshToken}}); err == nil {
+			return u.accessToken, nil
+		}
+		err = fmt.Errorf("Cannot refresh access token: %s", err)
+	}
+	if u.clientCredentials {
+		if err = u.requestToken(ctx, url.Values{"grant_type": {"client_credentials"}}); err == nil {
+			return u.accessToken, nil
+		}
+		err = fmt.Errorf("Cannot authenticate as UAA client %s: %s", u.client, err)
+	}
+	if u.username != "" {
+		if err = u.requestToken(ctx, url.Values{"grant_type": {"password"}, "username": {u.username}, "password": {u.password}}); err == nil {
+			return u.accessToken, nil
+		}
+		err = fmt.Errorf("Cannot log in as %s: %s", u.username, err)
+	}
+	if err == nil && u.accessToken != "" {
+		err = errors.New("Access token has expired. Use 'cf login' to log in again.")
+	}
+	if err == nil {
+		err = errNotLoggedIn
+	}
+	return "", err
+}
+
+// tokenResponse is the body of a UAA token response, which describes either the tokens granted or an error.
+type tokenResponse struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	ExpiresIn        int    `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// requestToken requests tokens with the given grant and records them.
+func (u *UAA) requestToken(ctx context.Context, grant url.Values) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.tokenEndpoint, strings.NewReader(grant.Encode()))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(url.QueryEscape(u.client), url.QueryEscape(u.clientSecret))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := u.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var body tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return fmt.Errorf("UAA returned %s with invalid JSON: %s", resp.Status, err)
+	}
+	if body.Error != "" {
+		return fmt.Errorf("%s (%s)", body.ErrorDescription, body.Error)
+	}
+	if resp.StatusCode >= 300 || body.AccessToken == "" {
+		return fmt.Errorf("UAA returned %s without an access token", resp.Status)
+	}
+
+	u.accessToken = body.AccessToken
+	if body.RefreshToken != "" {
+		u.refreshToken = body.RefreshToken
+	}
+	u.expiry = tokenExpiry(body.AccessToken)
+	if u.expiry.IsZero() && body.ExpiresIn > 0 {
+		u.expiry = u.now().Add(time.Duration(body.ExpiresIn) * time.Second)
+	}
+	return nil
+}
+
+// tokenClaims returns the claims of the given JWT access token, or nil if the token is not a JWT.
+func tokenClaims(token string) map[string]interface{} {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil
+	}
+	var claims map[string]interface{}
+	if json.Unmarshal(payload, &claims) != nil {
+		return nil
+	}
+	return claims
+}
+
+// tokenClaim returns the given string claim of the given JWT access token, or an empty string if there is no such
+// claim.
+func tokenClaim(token string, name string) string {
+	value, _ := tokenClaims(token)[name].(string)
+	return value
+}
+
+// tokenExpiry returns the expiry time of the given JWT access token, or the zero time if it is unknown.
+func tokenExpiry(token string) time.Time {
+	exp, ok := tokenClaims(token)["exp"].(float64)
+	if !ok {
+		return time.Time{}
+	}
+	return time.Unix(int64(exp), 0)
+}
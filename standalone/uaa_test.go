@@ -0,0 +1,191 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package standalone_test
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/standalone"
+)
+
+// jwt returns an unsigned JWT with the given claims.
+func jwt(claims string) string {
+	return "eyJhbGciOiJub25lIn0." + base64.RawURLEncoding.EncodeToString([]byte(claims)) + "."
+}
+
+var _ = Describe("UAA", func() {
+	var (
+		server   *httptest.Server
+		requests []*http.Request
+		response string
+		status   int
+		now      time.Time
+		uaa      *standalone.UAA
+		token    string
+		err      error
+	)
+
+	BeforeEach(func() {
+		requests = nil
+		response = fmt.Sprintf(`{"access_token": %q, "refresh_token": "new-refresh-token"}`, jwt(`{"exp": 2000000000}`))
+		status = http.StatusOK
+		now = time.Unix(1000000000, 0)
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			Expect(r.URL.Path).To(Equal("/oauth/token"))
+			Expect(r.ParseForm()).To(Succeed())
+			requests = append(requests, r)
+			w.WriteHeader(status)
+			w.Write([]byte(response))
+		}))
+		uaa = standalone.NewUAA(server.URL+"/", "", "", server.Client())
+		uaa.SetClock(func() time.Time { return now })
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	JustBeforeEach(func() {
+		token, err = uaa.AccessToken(context.Background())
+	})
+
+	Context("when the access token is current", func() {
+		BeforeEach(func() {
+			uaa.Tokens("bearer "+jwt(`{"exp": 1000003600}`), "refresh-token")
+		})
+
+		It("should return it without a request", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(token).To(Equal(jwt(`{"exp": 1000003600}`)))
+			Expect(requests).To(BeEmpty())
+		})
+	})
+
+	Context("when the access token is about to expire", func() {
+		BeforeEach(func() {
+			uaa.Tokens("bearer "+jwt(`{"exp": 1000000030}`), "refresh-token")
+		})
+
+		It("should refresh it as the cf client", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(token).To(Equal(jwt(`{"exp": 2000000000}`)))
+			Expect(requests).To(HaveLen(1))
+			Expect(requests[0].PostForm.Get("grant_type")).To(Equal("refresh_token"))
+			Expect(requests[0].PostForm.Get("refresh_token")).To(Equal("refresh-token"))
+			client, secret, ok := requests[0].BasicAuth()
+			Expect(ok).To(BeTrue())
+			Expect(client).To(Equal("cf"))
+			Expect(secret).To(BeEmpty())
+		})
+
+		It("should use the new token until it expires", func() {
+			_, err := uaa.AccessToken(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(requests).To(HaveLen(1))
+		})
+	})
+
+	Context("when the refresh token is rejected", func() {
+		BeforeEach(func() {
+			status = http.StatusUnauthorized
+			response = `{"error": "invalid_token", "error_description": "Invalid refresh token (expired)"}`
+			uaa.Tokens("", "refresh-token")
+		})
+
+		It("should return a suitable error", func() {
+			Expect(err).To(MatchError("Cannot refresh access token: Invalid refresh token (expired) (invalid_token)"))
+		})
+	})
+
+	Context("when the user's credentials are known", func() {
+		BeforeEach(func() {
+			uaa.Password("admin", "secret")
+		})
+
+		It("should log in with them", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(token).To(Equal(jwt(`{"exp": 2000000000}`)))
+			Expect(requests[0].PostForm.Get("grant_type")).To(Equal("password"))
+			Expect(requests[0].PostForm.Get("username")).To(Equal("admin"))
+			Expect(requests[0].PostForm.Get("password")).To(Equal("secret"))
+		})
+	})
+
+	Context("when authenticating as a UAA client", func() {
+		BeforeEach(func() {
+			response = fmt.Sprintf(`{"access_token": %q, "expires_in": 43199}`, jwt(`{"client_id": "ci-client"}`))
+			uaa = standalone.NewUAA(server.URL, "ci-client", "ci secret", server.Client()).ClientCredentials()
+			uaa.SetClock(func() time.Time { return now })
+		})
+
+		It("should use the client credentials grant", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(token).To(Equal(jwt(`{"client_id": "ci-client"}`)))
+			Expect(requests[0].PostForm.Get("grant_type")).To(Equal("client_credentials"))
+			client, secret, ok := requests[0].BasicAuth()
+			Expect(ok).To(BeTrue())
+			Expect(client).To(Equal("ci-client"))
+			Expect(secret).To(Equal("ci+secret"))
+		})
+
+		It("should obtain a new token when the current one is about to expire", func() {
+			_, err := uaa.AccessToken(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(requests).To(HaveLen(1))
+
+			now = now.Add(12 * time.Hour)
+			_, err = uaa.AccessToken(context.Background())
+			Expect(err).NotTo(HaveOccurred())
+			Expect(requests).To(HaveLen(2))
+			Expect(requests[1].PostForm.Get("grant_type")).To(Equal("client_credentials"))
+		})
+
+		Context("when the client's credentials are rejected", func() {
+			BeforeEach(func() {
+				status = http.StatusUnauthorized
+				response = `{"error": "unauthorized", "error_description": "Bad credentials"}`
+			})
+
+			It("should return a suitable error", func() {
+				Expect(err).To(MatchError("Cannot authenticate as UAA client ci-client: Bad credentials (unauthorized)"))
+			})
+		})
+	})
+
+	Context("when the access token has expired and cannot be refreshed", func() {
+		BeforeEach(func() {
+			uaa.Tokens(jwt(`{"exp": 999999999}`), "")
+		})
+
+		It("should return a suitable error", func() {
+			Expect(err).To(MatchError("Access token has expired. Use 'cf login' to log in again."))
+		})
+	})
+
+	Context("when there is no way of obtaining a token", func() {
+		It("should return a suitable error", func() {
+			Expect(err).To(MatchError("Not logged in. Use 'cf login', set CF_USERNAME and CF_PASSWORD, or set CF_CLIENT_ID and CF_CLIENT_SECRET."))
+		})
+	})
+})
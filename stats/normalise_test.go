@@ -0,0 +1,39 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package stats_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/stats"
+)
+
+var _ = Describe("Normalise", func() {
+	DescribeTable("should replace variable parts of a message",
+		func(message string, normalised string) {
+			Expect(stats.Normalise(message)).To(Equal(normalised))
+		},
+		Entry("uuid", "instance 870cdf18-7e15-435a-8459-6c38a8452d79 created", "instance <uuid> created"),
+		Entry("numbers", "took 35 ms for 2.5 MB", "took <num> ms for <num> MB"),
+		Entry("address", "connected to 10.0.16.4:5432", "connected to <ip>"),
+		Entry("hex", "commit 3fa9c2e0 at 0x7ffe", "commit <hex> at <hex>"),
+		Entry("timestamp", "started at 2017-06-12T08:11:02Z ok", "started at <time> ok"),
+		Entry("quoted string", `user "bob" logged in`, "user <str> logged in"),
+		Entry("whitespace", "  too   much space  ", "too much space"),
+		Entry("plain words", "replication is healthy", "replication is healthy"),
+	)
+})
@@ -0,0 +1,101 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package stats
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Severities, most severe first, as reported by DetectSeverity.
+const (
+	SeverityFatal   = "FATAL"
+	SeverityError   = "ERROR"
+	SeverityWarn    = "WARN"
+	SeverityInfo    = "INFO"
+	SeverityDebug   = "DEBUG"
+	SeverityTrace   = "TRACE"
+	SeverityUnknown = "UNKNOWN"
+)
+
+// levelWords maps the words which name a level, in lower case, to their severities.
+var levelWords = map[string]string{
+	"fatal": SeverityFatal, "panic": SeverityFatal, "critical": SeverityFatal, "crit": SeverityFatal, "emerg": SeverityFatal, "emergency": SeverityFatal,
+	"error": SeverityError, "err": SeverityError, "severe": SeverityError,
+	"warn": SeverityWarn, "warning": SeverityWarn,
+	"info": SeverityInfo, "notice": SeverityInfo,
+	"debug": SeverityDebug, "fine": SeverityDebug,
+	"trace": SeverityTrace, "finer": SeverityTrace, "finest": SeverityTrace,
+}
+
+// levelField matches a field giving the level, as in level=error or "level":"error".
+var levelField = regexp.MustCompile(`(?i)\b(?:level|lvl|severity)"?\s*[=:]\s*"?([a-z]+)`)
+
+// leadingTokens is the number of tokens, such as a timestamp and a thread name, which may precede the level at the
+// start of a message.
+const leadingTokens = 4
+
+// keywordPatterns match keywords which suggest a severity wherever they appear in a message, most severe first.
+// Abbreviations such as err and crit, and words such as alert and fine, are too often part of other text.
+var keywordPatterns = []struct {
+	severity string
+	pattern  *regexp.Regexp
+}{
+	{SeverityFatal, regexp.MustCompile(`(?i)\b(fatal|panic|critical|emergency)\b`)},
+	{SeverityError, regexp.MustCompile(`(?i)\b(error|severe)\b`)},
+	{SeverityWarn, regexp.MustCompile(`(?i)\b(warn|warning)\b`)},
+	{SeverityInfo, regexp.MustCompile(`(?i)\b(info|notice)\b`)},
+	{SeverityDebug, regexp.MustCompile(`(?i)\bdebug\b`)},
+	{SeverityTrace, regexp.MustCompile(`(?i)\b(trace|finer|finest)\b`)},
+}
+
+// DetectSeverity guesses the severity of a log message. A level field, such as level=error, or a level among the
+// first few tokens of the message, such as ERROR or [warn], determines the severity. Otherwise the most severe keyword in the
+// message wins, ignoring keywords used as field names, as in error=nil. Messages without a recognised level or
+// keyword have severity UNKNOWN.
+func DetectSeverity(message string) string {
+	if match := levelField.FindStringSubmatch(message); match != nil {
+		if severity, ok := levelWords[strings.ToLower(match[1])]; ok {
+			return severity
+		}
+	}
+
+	for i, token := range strings.Fields(message) {
+		if i == leadingTokens {
+			break
+		}
+		// A level is written in upper case or set off by punctuation, as in ERROR, [warn] or panic:, unlike an
+		// ordinary word such as "fine".
+		word := strings.Trim(token, "[]()<>:,|-")
+		if word == token && word != strings.ToUpper(word) {
+			continue
+		}
+		if severity, ok := levelWords[strings.ToLower(word)]; ok {
+			return severity
+		}
+	}
+
+	for _, kp := range keywordPatterns {
+		for _, loc := range kp.pattern.FindAllStringIndex(message, -1) {
+			if loc[1] < len(message) && message[loc[1]] == '=' {
+				continue
+			}
+			return kp.severity
+		}
+	}
+	return SeverityUnknown
+}
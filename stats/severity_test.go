@@ -0,0 +1,50 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package stats_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/stats"
+)
+
+var _ = Describe("DetectSeverity", func() {
+	DescribeTable("should detect the severity of a message",
+		func(message string, severity string) {
+			Expect(stats.DetectSeverity(message)).To(Equal(severity))
+		},
+		Entry("fatal", "FATAL: could not start", stats.SeverityFatal),
+		Entry("panic", "panic: runtime error", stats.SeverityFatal),
+		Entry("error", "2017-06-12 ERROR [main] connection lost", stats.SeverityError),
+		Entry("leading level in preference to keywords", "INFO retrying after error", stats.SeverityInfo),
+		Entry("level after a timestamp and thread", "2017-06-12 10:00:00.123 [main] WARN c.e.Pool - fatal flaw avoided", stats.SeverityWarn),
+		Entry("level field in preference to keywords", "msg=\"disk full error\" level=warn", stats.SeverityWarn),
+		Entry("JSON level field", `{"level":"debug","msg":"critical section entered"}`, stats.SeverityDebug),
+		Entry("most severe keyword without a level", "request failed with error after warning", stats.SeverityError),
+		Entry("abbreviated leading level", "[crit] disk failure", stats.SeverityFatal),
+		Entry("abbreviation elsewhere", "lookup done, err=nil", stats.SeverityUnknown),
+		Entry("keyword used as a field name", "request completed error=none", stats.SeverityUnknown),
+		Entry("alert", "sending alert to the on-call engineer", stats.SeverityUnknown),
+		Entry("leading word which is not a level", "all fine after restart", stats.SeverityUnknown),
+		Entry("warning", "[warning] disk 80% full", stats.SeverityWarn),
+		Entry("info", "level=info msg=started", stats.SeverityInfo),
+		Entry("debug", "DEBUG polling", stats.SeverityDebug),
+		Entry("trace", "TRACE entering method", stats.SeverityTrace),
+		Entry("keyword embedded in a word", "terrors and informants", stats.SeverityUnknown),
+		Entry("no keyword", "hello world", stats.SeverityUnknown),
+	)
+})
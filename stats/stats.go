@@ -0,0 +1,160 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package stats
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/format"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+)
+
+// Summary is an overview of a collection of log records.
+type Summary struct {
+	Total             int            `json:"total"`
+	From              *time.Time     `json:"from,omitempty"`
+	To                *time.Time     `json:"to,omitempty"`
+	SpanSeconds       float64        `json:"span_seconds"`
+	MessagesPerMinute float64        `json:"messages_per_minute"`
+	SourceTypes       map[string]int `json:"source_types"`
+	SourceInstances   map[string]int `json:"source_instances"`
+	Streams           map[string]int `json:"streams"`
+	Severities        map[string]int `json:"severities"`
+	TopMessages       []MessageCount `json:"top_messages"`
+}
+
+// MessageCount is the number of occurrences of a normalised message.
+type MessageCount struct {
+	Message string `json:"message"`
+	Count   int    `json:"count"`
+}
+
+// Summarise computes a summary of the given records including the top most frequent normalised messages.
+func Summarise(records []logclient.LogRecord, top int) Summary {
+	summary := Summary{
+		Total:           len(records),
+		SourceTypes:     map[string]int{},
+		SourceInstances: map[string]int{},
+		Streams:         map[string]int{},
+		Severities:      map[string]int{},
+		TopMessages:     []MessageCount{},
+	}
+
+	messages := map[string]int{}
+	for i, r := range records {
+		if i == 0 || r.Timestamp.Before(*summary.From) {
+			from := r.Timestamp
+			summary.From = &from
+		}
+		if i == 0 || r.Timestamp.After(*summary.To) {
+			to := r.Timestamp
+			summary.To = &to
+		}
+		summary.SourceTypes[r.SourceType]++
+		summary.SourceInstances[r.SourceType+"/"+r.SourceInstance]++
+		summary.Streams[r.MessageType]++
+		summary.Severities[DetectSeverity(r.Message)]++
+		messages[Normalise(r.Message)]++
+	}
+
+	if summary.Total > 0 {
+		span := summary.To.Sub(*summary.From)
+		summary.SpanSeconds = span.Seconds()
+		if span > 0 {
+			summary.MessagesPerMinute = float64(summary.Total) / span.Minutes()
+		}
+	}
+
+	for _, c := range sortCounts(messages) {
+		if len(summary.TopMessages) == top {
+			break
+		}
+		summary.TopMessages = append(summary.TopMessages, MessageCount{Message: c.key, Count: c.count})
+	}
+
+	return summary
+}
+
+// WriteJSON writes the summary to the given writer as JSON.
+func (s Summary) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(s)
+}
+
+// WriteText writes the summary to the given writer in a human readable form.
+func (s Summary) WriteText(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	fmt.Fprintf(tw, "Total messages:\t%d\n", s.Total)
+	if s.Total > 0 {
+		fmt.Fprintf(tw, "Time span:\t%s to %s (%s)\n",
+			s.From.In(logclient.CurrentTimezoneLocation).Format(logclient.LogTimestampFormat),
+			s.To.In(logclient.CurrentTimezoneLocation).Format(logclient.LogTimestampFormat),
+			time.Duration(s.SpanSeconds*float64(time.Second)).Round(time.Millisecond))
+		fmt.Fprintf(tw, "Messages per minute:\t%.1f\n", s.MessagesPerMinute)
+	}
+
+	writeCounts(tw, "By source type", s.SourceTypes)
+	writeCounts(tw, "By source instance", s.SourceInstances)
+	writeCounts(tw, "By stream", s.Streams)
+	writeCounts(tw, "By severity", s.Severities)
+
+	if len(s.TopMessages) > 0 {
+		fmt.Fprintf(tw, "\n%s\n", format.Bold("Most frequent messages"))
+		for _, m := range s.TopMessages {
+			fmt.Fprintf(tw, "  %d\t%s\n", m.Count, m.Message)
+		}
+	}
+
+	return tw.Flush()
+}
+
+func writeCounts(w io.Writer, title string, counts map[string]int) {
+	if len(counts) == 0 {
+		return
+	}
+	fmt.Fprintf(w, "\n%s\n", format.Bold(title))
+	for _, c := range sortCounts(counts) {
+		fmt.Fprintf(w, "  %s\t%d\n", c.key, c.count)
+	}
+}
+
+type keyCount struct {
+	key   string
+	count int
+}
+
+// sortCounts orders counts by descending count and then by key.
+func sortCounts(counts map[string]int) []keyCount {
+	sorted := []keyCount{}
+	for k, c := range counts {
+		sorted = append(sorted, keyCount{key: k, count: c})
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].count != sorted[j].count {
+			return sorted[i].count > sorted[j].count
+		}
+		return sorted[i].key < sorted[j].key
+	})
+	return sorted
+}
@@ -0,0 +1,115 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package stats_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/stats"
+)
+
+var _ = Describe("Summarise", func() {
+	var (
+		start   time.Time
+		records []logclient.LogRecord
+		top     int
+		summary stats.Summary
+	)
+
+	BeforeEach(func() {
+		start = time.Date(2017, 6, 12, 8, 0, 0, 0, time.UTC)
+		top = 2
+		records = []logclient.LogRecord{
+			{Timestamp: start.Add(time.Minute), SourceType: "SVC", SourceInstance: "0", MessageType: "OUT", Message: "INFO request 1 served"},
+			{Timestamp: start, SourceType: "SVC", SourceInstance: "1", MessageType: "OUT", Message: "INFO request 2 served"},
+			{Timestamp: start.Add(2 * time.Minute), SourceType: "SVC", SourceInstance: "0", MessageType: "ERR", Message: "ERROR disk full"},
+			{Timestamp: start.Add(4 * time.Minute), SourceType: "BROKER", SourceInstance: "0", MessageType: "OUT", Message: "INFO request 3 served"},
+			{Timestamp: start.Add(3 * time.Minute), SourceType: "SVC", SourceInstance: "1", MessageType: "OUT", Message: "heartbeat"},
+		}
+	})
+
+	JustBeforeEach(func() {
+		summary = stats.Summarise(records, top)
+	})
+
+	It("should count the records", func() {
+		Expect(summary.Total).To(Equal(5))
+	})
+
+	It("should compute the time span and rate regardless of record order", func() {
+		Expect(*summary.From).To(Equal(start))
+		Expect(*summary.To).To(Equal(start.Add(4 * time.Minute)))
+		Expect(summary.SpanSeconds).To(Equal(240.0))
+		Expect(summary.MessagesPerMinute).To(Equal(1.25))
+	})
+
+	It("should count by source type, source instance, stream and severity", func() {
+		Expect(summary.SourceTypes).To(Equal(map[string]int{"SVC": 4, "BROKER": 1}))
+		Expect(summary.SourceInstances).To(Equal(map[string]int{"SVC/0": 2, "SVC/1": 2, "BROKER/0": 1}))
+		Expect(summary.Streams).To(Equal(map[string]int{"OUT": 4, "ERR": 1}))
+		Expect(summary.Severities).To(Equal(map[string]int{"INFO": 3, "ERROR": 1, "UNKNOWN": 1}))
+	})
+
+	It("should list the most frequent normalised messages", func() {
+		Expect(summary.TopMessages).To(Equal([]stats.MessageCount{
+			{Message: "INFO request <num> served", Count: 3},
+			{Message: "ERROR disk full", Count: 1},
+		}))
+	})
+
+	It("should write the summary as JSON", func() {
+		var buffer bytes.Buffer
+		Expect(summary.WriteJSON(&buffer)).To(Succeed())
+
+		var decoded map[string]interface{}
+		Expect(json.Unmarshal(buffer.Bytes(), &decoded)).To(Succeed())
+		Expect(decoded["total"]).To(BeNumerically("==", 5))
+		Expect(decoded["messages_per_minute"]).To(BeNumerically("==", 1.25))
+		Expect(decoded["streams"]).To(HaveKeyWithValue("ERR", BeNumerically("==", 1)))
+	})
+
+	It("should write the summary as text", func() {
+		var buffer bytes.Buffer
+		Expect(summary.WriteText(&buffer)).To(Succeed())
+		Expect(buffer.String()).To(ContainSubstring("Total messages:       5"))
+		Expect(buffer.String()).To(ContainSubstring("Messages per minute:  1.2"))
+		Expect(buffer.String()).To(MatchRegexp(`SVC/0\s+2`))
+		Expect(buffer.String()).To(MatchRegexp(`3\s+INFO request <num> served`))
+	})
+
+	Context("when there are no records", func() {
+		BeforeEach(func() {
+			records = nil
+		})
+
+		It("should produce an empty summary", func() {
+			Expect(summary.Total).To(BeZero())
+			Expect(summary.From).To(BeNil())
+			Expect(summary.MessagesPerMinute).To(BeZero())
+			Expect(summary.TopMessages).To(BeEmpty())
+
+			var buffer bytes.Buffer
+			Expect(summary.WriteText(&buffer)).To(Succeed())
+			Expect(buffer.String()).To(Equal("Total messages:  0\n"))
+		})
+	})
+})
@@ -0,0 +1,84 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package structured
+
+import (
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/sink"
+)
+
+// missingColumn is displayed in a column whose field is missing.
+const missingColumn = "-"
+
+// Options determines how log messages which are JSON objects are presented and which log records are written.
+type Options struct {
+	// Pretty indents JSON messages in text output.
+	Pretty bool
+	// Columns names fields whose values are displayed before the message.
+	Columns []string
+	// Filters must all match a JSON message for its record to be written. Records whose messages are not JSON
+	// objects are not written if there are any filters.
+	Filters []Filter
+}
+
+type structuredSink struct {
+	next    sink.Sink
+	options Options
+}
+
+// NewSink returns a sink which parses log messages which are JSON objects into the records' fields, applies the
+// given options and writes the records which pass the filters to the given sink.
+func NewSink(next sink.Sink, options Options) sink.Sink {
+	return &structuredSink{next: next, options: options}
+}
+
+func (ss *structuredSink) Write(record logclient.LogRecord) error {
+	fields, ok := Parse(record.Message)
+	if len(ss.options.Filters) > 0 && !ok {
+		return nil
+	}
+	for _, filter := range ss.options.Filters {
+		if !filter.Match(fields) {
+			return nil
+		}
+	}
+
+	if ok {
+		record.Fields = fields
+		if ss.options.Pretty {
+			record.Message = Indent(record.Message)
+		}
+	}
+	if len(ss.options.Columns) > 0 {
+		record.Columns = make([]string, len(ss.options.Columns))
+		for i, name := range ss.options.Columns {
+			record.Columns[i] = missingColumn
+			if value, found := Lookup(fields, name); found {
+				record.Columns[i] = Render(value)
+			}
+		}
+	}
+	return ss.next.Write(record)
+}
+
+func (ss *structuredSink) Flush() error {
+	return ss.next.Flush()
+}
+
+func (ss *structuredSink) Close() error {
+	return ss.next.Close()
+}
@@ -0,0 +1,139 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package structured_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/sink"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/structured"
+)
+
+// recordingSink remembers the records written to it.
+type recordingSink struct {
+	records []logclient.LogRecord
+	flushed bool
+	closed  bool
+}
+
+func (rs *recordingSink) Write(record logclient.LogRecord) error {
+	rs.records = append(rs.records, record)
+	return nil
+}
+
+func (rs *recordingSink) Flush() error {
+	rs.flushed = true
+	return nil
+}
+
+func (rs *recordingSink) Close() error {
+	rs.closed = true
+	return nil
+}
+
+var _ = Describe("Sink", func() {
+	var (
+		next    *recordingSink
+		options structured.Options
+		s       sink.Sink
+	)
+
+	const jsonMessage = `{"level": "error", "component": "replication"}`
+
+	BeforeEach(func() {
+		next = &recordingSink{}
+		options = structured.Options{}
+	})
+
+	JustBeforeEach(func() {
+		s = structured.NewSink(next, options)
+	})
+
+	It("should parse JSON messages into fields", func() {
+		Expect(s.Write(logclient.LogRecord{Message: jsonMessage})).To(Succeed())
+		Expect(next.records).To(HaveLen(1))
+		Expect(next.records[0].Fields).To(HaveKeyWithValue("level", "error"))
+		Expect(next.records[0].Message).To(Equal(jsonMessage))
+	})
+
+	It("should nest parsed messages in JSON output", func() {
+		Expect(s.Write(logclient.LogRecord{Message: jsonMessage})).To(Succeed())
+		line, err := sink.JSONFormat(next.records[0])
+		Expect(err).NotTo(HaveOccurred())
+		var decoded map[string]interface{}
+		Expect(json.Unmarshal([]byte(line), &decoded)).To(Succeed())
+		Expect(decoded["message"]).To(HaveKeyWithValue("component", "replication"))
+	})
+
+	It("should pass on other messages unchanged", func() {
+		Expect(s.Write(logclient.LogRecord{Message: "plain"})).To(Succeed())
+		Expect(next.records).To(Equal([]logclient.LogRecord{{Message: "plain"}}))
+	})
+
+	It("should pass on flush and close", func() {
+		Expect(s.Flush()).To(Succeed())
+		Expect(s.Close()).To(Succeed())
+		Expect(next.flushed).To(BeTrue())
+		Expect(next.closed).To(BeTrue())
+	})
+
+	Context("when pretty-printing", func() {
+		BeforeEach(func() {
+			options.Pretty = true
+		})
+
+		It("should indent JSON messages", func() {
+			Expect(s.Write(logclient.LogRecord{Message: jsonMessage})).To(Succeed())
+			Expect(next.records[0].Message).To(Equal("{\n  \"level\": \"error\",\n  \"component\": \"replication\"\n}"))
+		})
+	})
+
+	Context("when columns are requested", func() {
+		BeforeEach(func() {
+			options.Columns = []string{"level", "pid"}
+		})
+
+		It("should extract the fields, marking missing fields", func() {
+			Expect(s.Write(logclient.LogRecord{Message: jsonMessage})).To(Succeed())
+			Expect(s.Write(logclient.LogRecord{Message: "plain"})).To(Succeed())
+			Expect(next.records[0].Columns).To(Equal([]string{"error", "-"}))
+			Expect(next.records[1].Columns).To(Equal([]string{"-", "-"}))
+		})
+	})
+
+	Context("when filters are given", func() {
+		BeforeEach(func() {
+			level, err := structured.ParseFilter("level=error")
+			Expect(err).NotTo(HaveOccurred())
+			component, err := structured.ParseFilter("component~=^repl")
+			Expect(err).NotTo(HaveOccurred())
+			options.Filters = []structured.Filter{level, component}
+		})
+
+		It("should only write JSON messages which match every filter", func() {
+			Expect(s.Write(logclient.LogRecord{Message: jsonMessage})).To(Succeed())
+			Expect(s.Write(logclient.LogRecord{Message: `{"level": "error", "component": "checkpointer"}`})).To(Succeed())
+			Expect(s.Write(logclient.LogRecord{Message: `{"level": "info", "component": "replication"}`})).To(Succeed())
+			Expect(s.Write(logclient.LogRecord{Message: "plain"})).To(Succeed())
+			Expect(next.records).To(HaveLen(1))
+			Expect(next.records[0].Message).To(Equal(jsonMessage))
+		})
+	})
+})
@@ -0,0 +1,141 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package structured
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Parse returns the fields of the given log message if it is a JSON object. Numbers are returned as json.Number
+// so that they are rendered as they appear in the message.
+func Parse(message string) (map[string]interface{}, bool) {
+	trimmed := strings.TrimSpace(message)
+	if !strings.HasPrefix(trimmed, "{") {
+		return nil, false
+	}
+
+	decoder := json.NewDecoder(strings.NewReader(trimmed))
+	decoder.UseNumber()
+	var fields map[string]interface{}
+	if err := decoder.Decode(&fields); err != nil {
+		return nil, false
+	}
+	// Reject messages with anything after the object.
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, false
+	}
+	return fields, true
+}
+
+// Lookup returns the value of the field with the given name. A name containing dots, such as "error.code", denotes
+// a nested field unless the fields contain the whole name.
+func Lookup(fields map[string]interface{}, name string) (interface{}, bool) {
+	if value, ok := fields[name]; ok {
+		return value, true
+	}
+	head, rest, nested := strings.Cut(name, ".")
+	if !nested {
+		return nil, false
+	}
+	child, ok := fields[head].(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	return Lookup(child, rest)
+}
+
+// Render returns the textual form of a field value: strings and numbers as they are, other values as JSON.
+func Render(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	default:
+		rendered, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(rendered)
+	}
+}
+
+// Indent returns the given JSON message indented for display.
+func Indent(message string) string {
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, []byte(strings.TrimSpace(message)), "", "  "); err != nil {
+		return message
+	}
+	return indented.String()
+}
+
+// Filter selects log messages by the value of one of their fields.
+type Filter struct {
+	field   string
+	op      string
+	value   string
+	pattern *regexp.Regexp
+}
+
+// ParseFilter parses a filter of the form NAME=VALUE, which matches messages whose field has the value, NAME!=VALUE,
+// which matches messages whose field does not have the value, or NAME~=REGEX, which matches messages whose field
+// matches the regular expression.
+func ParseFilter(spec string) (Filter, error) {
+	i := strings.Index(spec, "=")
+	if i <= 0 {
+		return Filter{}, fmt.Errorf("Invalid field filter %q: expected NAME=VALUE, NAME!=VALUE or NAME~=REGEX", spec)
+	}
+
+	filter := Filter{field: spec[:i], op: "=", value: spec[i+1:]}
+	if strings.HasSuffix(filter.field, "~") || strings.HasSuffix(filter.field, "!") {
+		filter.op = filter.field[len(filter.field)-1:] + "="
+		filter.field = filter.field[:len(filter.field)-1]
+	}
+	if filter.field == "" {
+		return Filter{}, fmt.Errorf("Invalid field filter %q: field name not specified", spec)
+	}
+	if filter.op == "~=" {
+		pattern, err := regexp.Compile(filter.value)
+		if err != nil {
+			return Filter{}, fmt.Errorf("Invalid field filter %q: %s", spec, err)
+		}
+		filter.pattern = pattern
+	}
+	return filter, nil
+}
+
+// Match returns whether the given fields satisfy the filter. A missing field only satisfies a != filter.
+func (f Filter) Match(fields map[string]interface{}) bool {
+	value, ok := Lookup(fields, f.field)
+	switch f.op {
+	case "!=":
+		return !ok || Render(value) != f.value
+	case "~=":
+		return ok && f.pattern.MatchString(Render(value))
+	default:
+		return ok && Render(value) == f.value
+	}
+}
+
+func (f Filter) String() string {
+	return f.field + f.op + f.value
+}
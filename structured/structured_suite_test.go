@@ -0,0 +1,13 @@
+package structured_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestStructured(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Structured Suite")
+}
@@ -0,0 +1,138 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package structured_test
+
+import (
+	"encoding/json"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/structured"
+)
+
+var _ = Describe("Structured log messages", func() {
+	Describe("Parse", func() {
+		It("should parse a JSON object", func() {
+			fields, ok := structured.Parse(` {"level": "error", "count": 3} `)
+			Expect(ok).To(BeTrue())
+			Expect(fields).To(Equal(map[string]interface{}{"level": "error", "count": json.Number("3")}))
+		})
+
+		It("should reject plain text", func() {
+			_, ok := structured.Parse("level=error")
+			Expect(ok).To(BeFalse())
+		})
+
+		It("should reject a JSON object followed by text", func() {
+			_, ok := structured.Parse(`{"level": "error"} and more`)
+			Expect(ok).To(BeFalse())
+		})
+
+		It("should reject JSON which is not an object", func() {
+			_, ok := structured.Parse(`["error"]`)
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Describe("Lookup", func() {
+		var fields map[string]interface{}
+
+		BeforeEach(func() {
+			fields, _ = structured.Parse(`{"error": {"code": 42}, "a.b": "dotted"}`)
+		})
+
+		It("should look up nested fields", func() {
+			value, ok := structured.Lookup(fields, "error.code")
+			Expect(ok).To(BeTrue())
+			Expect(structured.Render(value)).To(Equal("42"))
+		})
+
+		It("should prefer a field whose name contains dots", func() {
+			value, ok := structured.Lookup(fields, "a.b")
+			Expect(ok).To(BeTrue())
+			Expect(value).To(Equal("dotted"))
+		})
+
+		It("should report a missing field", func() {
+			_, ok := structured.Lookup(fields, "error.reason")
+			Expect(ok).To(BeFalse())
+		})
+	})
+
+	Describe("Render", func() {
+		It("should render objects as JSON", func() {
+			Expect(structured.Render(map[string]interface{}{"code": json.Number("42")})).To(Equal(`{"code":42}`))
+		})
+
+		It("should render booleans and null", func() {
+			Expect(structured.Render(true)).To(Equal("true"))
+			Expect(structured.Render(nil)).To(Equal("null"))
+		})
+	})
+
+	Describe("Indent", func() {
+		It("should indent JSON, preserving the order of fields", func() {
+			Expect(structured.Indent(`{"b": 1, "a": 2}`)).To(Equal("{\n  \"b\": 1,\n  \"a\": 2\n}"))
+		})
+	})
+
+	Describe("ParseFilter", func() {
+		var fields map[string]interface{}
+
+		BeforeEach(func() {
+			fields, _ = structured.Parse(`{"level": "error", "component": "wal-replication"}`)
+		})
+
+		It("should match equal values", func() {
+			filter, err := structured.ParseFilter("level=error")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(filter.Match(fields)).To(BeTrue())
+			Expect(filter.Match(map[string]interface{}{"level": "info"})).To(BeFalse())
+			Expect(filter.Match(map[string]interface{}{})).To(BeFalse())
+		})
+
+		It("should match unequal values", func() {
+			filter, err := structured.ParseFilter("level!=info")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(filter.Match(fields)).To(BeTrue())
+			Expect(filter.Match(map[string]interface{}{})).To(BeTrue())
+			Expect(filter.Match(map[string]interface{}{"level": "info"})).To(BeFalse())
+		})
+
+		It("should match regular expressions", func() {
+			filter, err := structured.ParseFilter("component~=replication")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(filter.Match(fields)).To(BeTrue())
+			Expect(filter.Match(map[string]interface{}{"component": "checkpointer"})).To(BeFalse())
+		})
+
+		It("should reject a filter without a value", func() {
+			_, err := structured.ParseFilter("level")
+			Expect(err).To(MatchError(`Invalid field filter "level": expected NAME=VALUE, NAME!=VALUE or NAME~=REGEX`))
+		})
+
+		It("should reject a filter without a field name", func() {
+			_, err := structured.ParseFilter("~=error")
+			Expect(err).To(MatchError(`Invalid field filter "~=error": field name not specified`))
+		})
+
+		It("should reject an invalid regular expression", func() {
+			_, err := structured.ParseFilter("level~=(")
+			Expect(err).To(MatchError(HavePrefix(`Invalid field filter "level~=(": `)))
+		})
+	})
+})
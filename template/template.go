@@ -0,0 +1,119 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package template
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/sink"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/structured"
+)
+
+// templateFuncs are the helper functions available to log line templates. Functions which take a value to be
+// transformed take it last, so that it may be piped, as in {{.SourceType | pad 8}}.
+var templateFuncs = template.FuncMap{
+	"utc":     func(t time.Time) time.Time { return t.UTC() },
+	"local":   func(t time.Time) time.Time { return t.In(logclient.CurrentTimezoneLocation) },
+	"timefmt": func(layout string, t time.Time) string { return t.Format(layout) },
+	"pad":     pad,
+	"padleft": padLeft,
+	"trunc":   trunc,
+	"upper":   func(value interface{}) string { return strings.ToUpper(fmt.Sprint(value)) },
+	"lower":   func(value interface{}) string { return strings.ToLower(fmt.Sprint(value)) },
+	"bold":    colorFunc(color.Bold),
+	"red":     colorFunc(color.FgRed),
+	"green":   colorFunc(color.FgGreen),
+	"yellow":  colorFunc(color.FgYellow),
+	"blue":    colorFunc(color.FgBlue),
+	"cyan":    colorFunc(color.FgHiCyan),
+}
+
+// templateRecord is the data passed to a log line template: the log record together with a Time field, which is
+// an alias of Timestamp, and a Field method, which returns the value of a field of a JSON message.
+type templateRecord struct {
+	logclient.LogRecord
+	Time time.Time
+}
+
+// Field returns the value of the given field of a JSON message, or an empty string if there is no such field.
+// Fields are only available when JSON messages are parsed, for example with --field or --column.
+func (r templateRecord) Field(name string) string {
+	value, ok := structured.Lookup(r.Fields, name)
+	if !ok {
+		return ""
+	}
+	return structured.Render(value)
+}
+
+// Parse returns a format which renders log records using the given Go text/template, for example:
+//
+//	{{.Time | utc | timefmt "15:04:05.000"}} {{.SourceInstance | pad 4}} {{.Message | trunc 120}}
+//
+// The template may refer to the fields of logclient.LogRecord, Time, and the helper functions utc, local,
+// timefmt, pad, padleft, trunc, upper, lower, bold, red, green, yellow, blue and cyan.
+func Parse(text string) (sink.Format, error) {
+	tmpl, err := template.New("format").Funcs(templateFuncs).Option("missingkey=zero").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid format: %s", err)
+	}
+	return func(record logclient.LogRecord) (string, error) {
+		var line strings.Builder
+		if err := tmpl.Execute(&line, templateRecord{LogRecord: record, Time: record.Timestamp}); err != nil {
+			return "", fmt.Errorf("Cannot format log record: %s", err)
+		}
+		return line.String(), nil
+	}, nil
+}
+
+// pad left-aligns the given value in a field of the given width.
+func pad(width int, value interface{}) string {
+	s := fmt.Sprint(value)
+	if n := width - len([]rune(s)); n > 0 {
+		return s + strings.Repeat(" ", n)
+	}
+	return s
+}
+
+// padLeft right-aligns the given value in a field of the given width.
+func padLeft(width int, value interface{}) string {
+	s := fmt.Sprint(value)
+	if n := width - len([]rune(s)); n > 0 {
+		return strings.Repeat(" ", n) + s
+	}
+	return s
+}
+
+// trunc truncates the given value to the given number of characters.
+func trunc(length int, value interface{}) string {
+	runes := []rune(fmt.Sprint(value))
+	if length >= 0 && len(runes) > length {
+		return string(runes[:length])
+	}
+	return string(runes)
+}
+
+func colorFunc(attribute color.Attribute) func(value interface{}) string {
+	c := color.New(attribute)
+	return func(value interface{}) string {
+		return c.Sprint(value)
+	}
+}
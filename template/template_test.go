@@ -0,0 +1,129 @@
+/*
+ * Copyright (C) 2017-Present Pivotal Software, Inc. All rights reserved.
+ *
+ * This program and the accompanying materials are made available under
+ * the terms of the under the Apache License, Version 2.0 (the "License”);
+ * you may not use this file except in compliance with the License.
+ * You may obtain a copy of the License at
+ *
+ *     http://www.apache.org/licenses/LICENSE-2.0
+ *
+ * Unless required by applicable law or agreed to in writing, software
+ * distributed under the License is distributed on an "AS IS" BASIS,
+ * WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+ * See the License for the specific language governing permissions and
+ * limitations under the License.
+ */
+package template_test
+
+import (
+	"time"
+
+	"github.com/fatih/color"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/logclient"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/sink"
+	"github.com/pivotal-cf/service-instance-logs-cli-plugin/template"
+)
+
+var _ = Describe("Parse", func() {
+	var (
+		text   string
+		record logclient.LogRecord
+		line   string
+		err    error
+	)
+
+	BeforeEach(func() {
+		record = logclient.LogRecord{
+			Timestamp:      time.Date(2017, 7, 14, 2, 40, 0, 0, time.FixedZone("EST", -5*60*60)),
+			SourceType:     "SVC",
+			SourceInstance: "2",
+			MessageType:    "OUT",
+			Message:        "checkpoint complete",
+		}
+	})
+
+	JustBeforeEach(func() {
+		var f sink.Format
+		f, err = template.Parse(text)
+		Expect(err).NotTo(HaveOccurred())
+		line, err = f(record)
+	})
+
+	Context("when the template uses the record's fields", func() {
+		BeforeEach(func() {
+			text = `{{.Time | utc | timefmt "15:04:05"}} {{.SourceType}}/{{.SourceInstance}} {{.Message}}`
+		})
+
+		It("should render the record", func() {
+			Expect(err).NotTo(HaveOccurred())
+			Expect(line).To(Equal("07:40:00 SVC/2 checkpoint complete"))
+		})
+	})
+
+	Context("when the template pads and truncates", func() {
+		BeforeEach(func() {
+			text = `[{{.SourceType | pad 5}}][{{.SourceInstance | padleft 3}}] {{.Message | trunc 10}}`
+		})
+
+		It("should render the record", func() {
+			Expect(line).To(Equal("[SVC  ][  2] checkpoint"))
+		})
+	})
+
+	Context("when the template changes case", func() {
+		BeforeEach(func() {
+			text = `{{.MessageType | lower}} {{.Message | upper}}`
+		})
+
+		It("should render the record", func() {
+			Expect(line).To(Equal("out CHECKPOINT COMPLETE"))
+		})
+	})
+
+	Context("when the template refers to fields of a JSON message", func() {
+		BeforeEach(func() {
+			record.Fields = map[string]interface{}{"level": "info"}
+			text = `{{.Field "level"}}|{{.Field "missing"}}`
+		})
+
+		It("should render the fields' values", func() {
+			Expect(line).To(Equal("info|"))
+		})
+	})
+
+	Context("when the template colors its output", func() {
+		var noColor bool
+
+		BeforeEach(func() {
+			noColor = color.NoColor
+			color.NoColor = false
+			text = `{{.MessageType | red}}`
+		})
+
+		AfterEach(func() {
+			color.NoColor = noColor
+		})
+
+		It("should render the record in color", func() {
+			Expect(line).To(Equal("\x1b[31mOUT\x1b[0m"))
+		})
+	})
+
+	Context("when the template cannot be applied to the record", func() {
+		BeforeEach(func() {
+			text = `{{.Message | pad "wide"}}`
+		})
+
+		It("should return a suitable error", func() {
+			Expect(err).To(MatchError(HavePrefix("Cannot format log record: ")))
+		})
+	})
+
+	It("should reject an invalid template", func() {
+		_, err := template.Parse("{{.Message")
+		Expect(err).To(MatchError(HavePrefix("Invalid format: ")))
+	})
+})